@@ -0,0 +1,506 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package canonical
+
+//
+// The file define canonicalization of knowledge statements, blank nodes
+// are relabelled following RDF Dataset Canonicalization algorithm.
+// See https://www.w3.org/TR/rdf-canon/
+//
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/encoding/ntriples"
+	"github.com/kshard/xsd"
+)
+
+// ErrTooComplex is returned when canonicalization exceeds the limit of
+// computations, it protects from poison graphs.
+var ErrTooComplex = errors.New("canonical: graph is too complex")
+
+// maximum number of N-degree hash computations
+const maxDegreeCalls = 1 << 16
+
+// Option configures canonicalization
+type Option func(*config)
+
+type config struct {
+	prefixes curie.Prefixes
+}
+
+// WithPrefixes expands CURIEs of statements to absolute IRIs, canonical
+// N-Quads and hashes are computed over absolute IRIs. Without prefixes,
+// IRIs are written as they are, the hash of CURIEs differs from the hash
+// of same graph with absolute IRIs.
+func WithPrefixes(prefixes curie.Prefixes) Option {
+	return func(c *config) { c.prefixes = prefixes }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Canonize relabels blank nodes of the bag using RDFC-1.0 algorithm. Blank
+// nodes are labelled as _:c14n0, _:c14n1, ... Statements are sorted in the
+// order of canonical N-Quads, duplicate statements are removed.
+func Canonize(bag spock.Bag, opts ...Option) (spock.Bag, error) {
+	seq, _, err := canonize(bag, newConfig(opts))
+	return seq, err
+}
+
+// Document returns canonical N-Quads document of the bag
+func Document(bag spock.Bag, opts ...Option) ([]byte, error) {
+	_, lines, err := canonize(bag, newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// Hash returns SHA-256 of canonical N-Quads document of the bag, the hash
+// is equal for isomorphic graphs.
+func Hash(bag spock.Bag, opts ...Option) ([]byte, error) {
+	doc, err := Document(bag, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(doc)
+	return h[:], nil
+}
+
+// Isomorphic checks if two bags are same graph regardless labels of blank
+// nodes.
+func Isomorphic(a, b spock.Bag, opts ...Option) (bool, error) {
+	ha, err := Hash(a, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	hb, err := Hash(b, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(ha, hb), nil
+}
+
+//------------------------------------------------------------------------------
+
+func canonize(bag spock.Bag, cfg *config) (spock.Bag, []string, error) {
+	st := newState(bag, cfg)
+	if err := st.issueCanonical(); err != nil {
+		return nil, nil, err
+	}
+
+	type line struct {
+		text  string
+		spock spock.SPOCK
+	}
+
+	seq := make([]line, 0, len(bag))
+	for _, x := range bag {
+		x = relabel(x, func(id string) string { return st.canonical.ids[id] })
+		text, err := st.nquad(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		seq = append(seq, line{text: text, spock: x})
+	}
+
+	sort.SliceStable(seq, func(i, j int) bool { return seq[i].text < seq[j].text })
+
+	out := make(spock.Bag, 0, len(seq))
+	lines := make([]string, 0, len(seq))
+	for i, x := range seq {
+		if i > 0 && x.text == seq[i-1].text {
+			continue
+		}
+		out = append(out, x.spock)
+		lines = append(lines, x.text)
+	}
+
+	return out, lines, nil
+}
+
+func isBlank(iri string) bool { return strings.HasPrefix(iri, "_:") }
+
+// relabels blank nodes of the statement
+func relabel(x spock.SPOCK, f func(string) string) spock.SPOCK {
+	if s := x.S.String(); isBlank(s) {
+		x.S = xsd.ToAnyURI(curie.IRI(f(s)))
+	}
+
+	if o, ok := x.O.(xsd.AnyURI); ok {
+		if s := o.String(); isBlank(s) {
+			x.O = xsd.ToAnyURI(curie.IRI(f(s)))
+		}
+	}
+
+	return x
+}
+
+// serializes statement as canonical N-Quad
+func (st *state) nquad(x spock.SPOCK) (string, error) {
+	var sb strings.Builder
+	enc := ntriples.NewEncoder(&sb,
+		ntriples.WithCanonical(),
+		ntriples.WithPrefixes(st.prefixes),
+	)
+	if err := enc.Put(x); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// absolute IRI of the predicate
+func (st *state) iri(x xsd.AnyURI) string {
+	iri := curie.IRI(x.String())
+	if st.prefixes != nil {
+		if prefix, has := st.prefixes.Lookup(curie.Prefix(iri)); has {
+			return prefix + curie.Reference(iri)
+		}
+	}
+	return string(iri)
+}
+
+func sha(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+//------------------------------------------------------------------------------
+
+// identifier issuer
+type issuer struct {
+	prefix string
+	seq    []string
+	ids    map[string]string
+}
+
+func newIssuer(prefix string) *issuer {
+	return &issuer{prefix: prefix, ids: map[string]string{}}
+}
+
+func (iss *issuer) issue(id string) string {
+	if label, has := iss.ids[id]; has {
+		return label
+	}
+
+	label := iss.prefix + strconv.Itoa(len(iss.seq))
+	iss.ids[id] = label
+	iss.seq = append(iss.seq, id)
+	return label
+}
+
+func (iss *issuer) clone() *issuer {
+	c := &issuer{
+		prefix: iss.prefix,
+		seq:    append([]string(nil), iss.seq...),
+		ids:    make(map[string]string, len(iss.ids)),
+	}
+	for k, v := range iss.ids {
+		c.ids[k] = v
+	}
+	return c
+}
+
+// canonicalization state
+type state struct {
+	prefixes  curie.Prefixes
+	bag       spock.Bag
+	quads     map[string][]int // blank node to statements
+	blanks    []string
+	hashes    map[string]string // first degree hashes
+	canonical *issuer
+	calls     int
+}
+
+func newState(bag spock.Bag, cfg *config) *state {
+	st := &state{
+		prefixes:  cfg.prefixes,
+		bag:       bag,
+		quads:     map[string][]int{},
+		hashes:    map[string]string{},
+		canonical: newIssuer("_:c14n"),
+	}
+
+	add := func(id string, i int) {
+		seq, has := st.quads[id]
+		if !has {
+			st.blanks = append(st.blanks, id)
+		}
+		if len(seq) == 0 || seq[len(seq)-1] != i {
+			st.quads[id] = append(seq, i)
+		}
+	}
+
+	for i, x := range bag {
+		if s := x.S.String(); isBlank(s) {
+			add(s, i)
+		}
+		if o, ok := x.O.(xsd.AnyURI); ok {
+			if s := o.String(); isBlank(s) {
+				add(s, i)
+			}
+		}
+	}
+
+	return st
+}
+
+func (st *state) issueCanonical() error {
+	groups := map[string][]string{}
+	for _, id := range st.blanks {
+		h, err := st.firstDegree(id)
+		if err != nil {
+			return err
+		}
+		groups[h] = append(groups[h], id)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for h := range groups {
+		keys = append(keys, h)
+	}
+	sort.Strings(keys)
+
+	for _, h := range keys {
+		if len(groups[h]) == 1 {
+			st.canonical.issue(groups[h][0])
+		}
+	}
+
+	type result struct {
+		hash   string
+		issuer *issuer
+	}
+
+	for _, h := range keys {
+		if len(groups[h]) == 1 {
+			continue
+		}
+
+		results := []result{}
+		for _, id := range groups[h] {
+			if _, has := st.canonical.ids[id]; has {
+				continue
+			}
+
+			tmp := newIssuer("_:b")
+			tmp.issue(id)
+			hash, iss, err := st.nDegree(id, tmp)
+			if err != nil {
+				return err
+			}
+			results = append(results, result{hash: hash, issuer: iss})
+		}
+
+		sort.SliceStable(results, func(i, j int) bool { return results[i].hash < results[j].hash })
+		for _, r := range results {
+			for _, id := range r.issuer.seq {
+				st.canonical.issue(id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// first degree hash of blank node
+func (st *state) firstDegree(id string) (string, error) {
+	if h, has := st.hashes[id]; has {
+		return h, nil
+	}
+
+	lines := make([]string, 0, len(st.quads[id]))
+	for _, i := range st.quads[id] {
+		x := relabel(st.bag[i], func(label string) string {
+			if label == id {
+				return "_:a"
+			}
+			return "_:z"
+		})
+
+		line, err := st.nquad(x)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+
+	h := sha(strings.Join(lines, ""))
+	st.hashes[id] = h
+	return h, nil
+}
+
+// hash of blank node related to the statement
+func (st *state) related(id string, x spock.SPOCK, iss *issuer, position string) (string, error) {
+	label, has := st.canonical.ids[id]
+	if !has {
+		label, has = iss.ids[id]
+	}
+	if !has {
+		h, err := st.firstDegree(id)
+		if err != nil {
+			return "", err
+		}
+		label = h
+	}
+
+	return sha(position + "<" + st.iri(x.P) + ">" + label), nil
+}
+
+// N-degree hash of blank node
+func (st *state) nDegree(id string, iss *issuer) (string, *issuer, error) {
+	st.calls++
+	if st.calls > maxDegreeCalls {
+		return "", nil, ErrTooComplex
+	}
+
+	hn := map[string][]string{}
+	for _, i := range st.quads[id] {
+		x := st.bag[i]
+		if s := x.S.String(); isBlank(s) && s != id {
+			h, err := st.related(s, x, iss, "s")
+			if err != nil {
+				return "", nil, err
+			}
+			hn[h] = append(hn[h], s)
+		}
+		if o, ok := x.O.(xsd.AnyURI); ok {
+			if s := o.String(); isBlank(s) && s != id {
+				h, err := st.related(s, x, iss, "o")
+				if err != nil {
+					return "", nil, err
+				}
+				hn[h] = append(hn[h], s)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(hn))
+	for h := range hn {
+		keys = append(keys, h)
+	}
+	sort.Strings(keys)
+
+	var data strings.Builder
+	for _, h := range keys {
+		data.WriteString(h)
+
+		chosenPath := ""
+		var chosenIssuer *issuer
+
+		err := permutations(hn[h], func(perm []string) error {
+			copied := iss.clone()
+			path := ""
+			recursion := []string{}
+
+			for _, related := range perm {
+				if label, has := st.canonical.ids[related]; has {
+					path += label
+				} else {
+					if _, has := copied.ids[related]; !has {
+						recursion = append(recursion, related)
+					}
+					path += copied.issue(related)
+				}
+
+				if isWorse(path, chosenPath) {
+					return nil
+				}
+			}
+
+			for _, related := range recursion {
+				hash, result, err := st.nDegree(related, copied)
+				if err != nil {
+					return err
+				}
+
+				path += copied.issue(related)
+				path += "<" + hash + ">"
+				copied = result
+
+				if isWorse(path, chosenPath) {
+					return nil
+				}
+			}
+
+			if chosenPath == "" || path < chosenPath {
+				chosenPath = path
+				chosenIssuer = copied
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		data.WriteString(chosenPath)
+		iss = chosenIssuer
+	}
+
+	return sha(data.String()), iss, nil
+}
+
+// path is worse than chosen one
+func isWorse(path, chosen string) bool {
+	return chosen != "" && len(path) >= len(chosen) && path > chosen
+}
+
+// iterates over all permutations of the sequence
+func permutations(seq []string, f func([]string) error) error {
+	perm := append([]string(nil), seq...)
+
+	var permute func(k int) error
+	permute = func(k int) error {
+		if k == len(perm) {
+			return f(perm)
+		}
+
+		for i := k; i < len(perm); i++ {
+			perm[k], perm[i] = perm[i], perm[k]
+			if err := permute(k + 1); err != nil {
+				return err
+			}
+			perm[k], perm[i] = perm[i], perm[k]
+		}
+		return nil
+	}
+
+	return permute(0)
+}
@@ -0,0 +1,190 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package canonical_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/canonical"
+	"github.com/kshard/spock/encoding/ntriples"
+)
+
+func TestCanonical(t *testing.T) {
+	decode := func(t *testing.T, doc string) spock.Bag {
+		t.Helper()
+		bag := ntriples.Bag{}
+		it.Then(t).Should(it.Nil(bag.UnmarshalText([]byte(doc))))
+		return spock.Bag(bag)
+	}
+
+	Document := func(t *testing.T, doc string) string {
+		t.Helper()
+		b, err := canonical.Document(decode(t, doc))
+		it.Then(t).Should(it.Nil(err))
+		return string(b)
+	}
+
+	t.Run("UniqueHashes", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, strings.Join([]string{
+					`<http://example.com/#p> <http://example.com/#q> _:e0 .`,
+					`<http://example.com/#p> <http://example.com/#r> _:e1 .`,
+					`_:e0 <http://example.com/#s> <http://example.com/#u> .`,
+					`_:e1 <http://example.com/#t> <http://example.com/#u> .`,
+				}, "\n")),
+				strings.Join([]string{
+					`<http://example.com/#p> <http://example.com/#q> _:c14n0 .`,
+					`<http://example.com/#p> <http://example.com/#r> _:c14n1 .`,
+					`_:c14n0 <http://example.com/#s> <http://example.com/#u> .`,
+					`_:c14n1 <http://example.com/#t> <http://example.com/#u> .`,
+					``,
+				}, "\n"),
+			),
+		)
+	})
+
+	t.Run("SharedHashes", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, strings.Join([]string{
+					`<http://example.com/#p> <http://example.com/#q> _:e0 .`,
+					`<http://example.com/#p> <http://example.com/#q> _:e1 .`,
+					`_:e0 <http://example.com/#p> _:e2 .`,
+					`_:e1 <http://example.com/#p> _:e3 .`,
+					`_:e2 <http://example.com/#r> _:e3 .`,
+				}, "\n")),
+				strings.Join([]string{
+					`<http://example.com/#p> <http://example.com/#q> _:c14n2 .`,
+					`<http://example.com/#p> <http://example.com/#q> _:c14n3 .`,
+					`_:c14n0 <http://example.com/#r> _:c14n1 .`,
+					`_:c14n2 <http://example.com/#p> _:c14n1 .`,
+					`_:c14n3 <http://example.com/#p> _:c14n0 .`,
+					``,
+				}, "\n"),
+			),
+		)
+	})
+
+	// W3C rdf-canon test suite, rdfc10/test021 (blank node - circle of 2)
+	t.Run("CircleOfTwo", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, strings.Join([]string{
+					`_:b1 <http://example.org/vocab#next> _:b0 .`,
+					`_:b0 <http://example.org/vocab#next> _:b1 .`,
+				}, "\n")),
+				strings.Join([]string{
+					`_:c14n0 <http://example.org/vocab#next> _:c14n1 .`,
+					`_:c14n1 <http://example.org/vocab#next> _:c14n0 .`,
+					``,
+				}, "\n"),
+			),
+		)
+	})
+
+	t.Run("Prefixes", func(t *testing.T) {
+		prefixes := curie.Namespaces{"ex": "http://example.com/#"}
+		bag := spock.Bag{
+			spock.From("ex:p", "ex:q", curie.IRI("_:e0")),
+			spock.From("ex:p", "ex:r", curie.IRI("_:e1")),
+			spock.From("_:e0", "ex:s", curie.IRI("ex:u")),
+			spock.From("_:e1", "ex:t", curie.IRI("ex:u")),
+		}
+
+		doc, err := canonical.Document(bag, canonical.WithPrefixes(prefixes))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(string(doc),
+				strings.Join([]string{
+					`<http://example.com/#p> <http://example.com/#q> _:c14n0 .`,
+					`<http://example.com/#p> <http://example.com/#r> _:c14n1 .`,
+					`_:c14n0 <http://example.com/#s> <http://example.com/#u> .`,
+					`_:c14n1 <http://example.com/#t> <http://example.com/#u> .`,
+					``,
+				}, "\n"),
+			),
+		)
+
+		absolute := decode(t, string(doc))
+		ok, err := canonical.Isomorphic(bag, absolute, canonical.WithPrefixes(prefixes))
+		it.Then(t).Should(it.Nil(err), it.True(ok))
+	})
+
+	t.Run("LiteralEscapes", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, `_:e0 <http://example.com/#p> "\t\b\f\n\r\"\\ caf\u00E9" .`),
+				"_:c14n0 <http://example.com/#p> \"\t\b\f\\n\\r\\\"\\\\ café\" .\n",
+			),
+		)
+	})
+
+	t.Run("LiteralControls", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, `<http://example.com/#s> <http://example.com/#p> "\u0000\u001F\u007F" .`),
+				"<http://example.com/#s> <http://example.com/#p> \"\x00\x1f\x7f\" .\n",
+			),
+		)
+	})
+
+	t.Run("IRI", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Document(t, `<http://example.com/#a\u005Eb> <http://example.com/#caf\u00E9> _:e0 .`),
+				"<http://example.com/#a^b> <http://example.com/#café> _:c14n0 .\n",
+			),
+		)
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		bag, err := canonical.Canonize(decode(t, "_:x <p> \"a\" .\n_:x <p> \"a\" .\n"))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(spock.From("_:c14n0", "p", "a")),
+		)
+	})
+
+	t.Run("Isomorphic", func(t *testing.T) {
+		a := decode(t, "_:a <p> _:b .\n_:b <p> _:a .\n_:a <q> \"x\" .\n")
+		b := decode(t, "_:y <p> _:x .\n_:x <q> \"x\" .\n_:x <p> _:y .\n")
+		c := decode(t, "_:y <p> _:x .\n_:y <q> \"x\" .\n_:x <p> _:x .\n")
+
+		ab, err := canonical.Isomorphic(a, b)
+		it.Then(t).Should(it.Nil(err), it.True(ab))
+
+		ac, err := canonical.Isomorphic(a, c)
+		it.Then(t).Should(it.Nil(err)).ShouldNot(it.True(ac))
+	})
+
+	t.Run("Hash", func(t *testing.T) {
+		ha, err := canonical.Hash(decode(t, "_:a <p> _:b .\n_:b <p> _:c .\n"))
+		it.Then(t).Should(it.Nil(err))
+
+		hb, err := canonical.Hash(decode(t, "_:b <p> _:c .\n_:a <p> _:b .\n"))
+		it.Then(t).Should(it.Nil(err), it.Equal(string(ha), string(hb)), it.Equal(len(ha), 32))
+	})
+}
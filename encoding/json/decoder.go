@@ -0,0 +1,274 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package json
+
+//
+// The file define streaming decoder of JSON documents. The decoder reads
+// top-level array element by element, the memory is bounded by the size
+// of single element rather than the whole document.
+//
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+// Decoder reads knowledge statements from JSON document incrementally.
+// Decoder implements spock.Stream.
+type Decoder struct {
+	dec   *json.Decoder
+	cfg   *config
+	step  func() error
+	queue Bag
+	head  spock.SPOCK
+	err   error
+}
+
+var _ spock.Stream = (*Decoder)(nil)
+
+// Option configures decoder
+type Option func(*config)
+
+type config struct {
+	blank    BlankNode
+	datetime bool
+	base     string
+	vocab    string
+	ids      []string
+	keys     func(string) (curie.IRI, bool)
+	null     *string
+	strict   bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		blank: ClockBlankNode,
+		ids:   []string{"@id", "id"},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithBlankNode configures strategy of minting blank nodes
+func WithBlankNode(blank BlankNode) Option {
+	return func(c *config) { c.blank = blank }
+}
+
+// WithDateTime enables detection of ISO-8601 date and time strings, they
+// are decoded as spock.DateTime so that order of values is chronological.
+func WithDateTime() Option {
+	return func(c *config) { c.datetime = true }
+}
+
+// WithBase configures base IRI, relative identifiers of objects (those
+// without scheme or prefix) are resolved against it.
+func WithBase(base string) Option {
+	return func(c *config) { c.base = base }
+}
+
+// WithVocab configures namespace of predicates, keys of objects (those
+// without scheme or prefix) are resolved against it.
+func WithVocab(vocab string) Option {
+	return func(c *config) { c.vocab = vocab }
+}
+
+// WithIdentity configures keys of object identifier, the first key found in
+// the object is used. Default keys are `@id` and `id`.
+func WithIdentity(keys ...string) Option {
+	return func(c *config) { c.ids = keys }
+}
+
+// WithKeyMapper configures mapping of object keys to predicates. The mapping
+// takes precedence over vocab, the key is skipped if mapper returns false.
+func WithKeyMapper(f func(key string) (curie.IRI, bool)) Option {
+	return func(c *config) { c.keys = f }
+}
+
+// WithNull decodes JSON null as the literal value. Null values are skipped
+// by default.
+func WithNull(lit string) Option {
+	return func(c *config) { c.null = &lit }
+}
+
+// WithStrict enables strict mode, decoder fails on values that are skipped
+// otherwise: null (unless WithNull is used), identifiers that are not
+// strings and scalars of top-level array.
+func WithStrict() Option {
+	return func(c *config) { c.strict = true }
+}
+
+// isIdentity returns true if key is identifier of object
+func (cfg *config) isIdentity(key string) bool {
+	for _, id := range cfg.ids {
+		if key == id {
+			return true
+		}
+	}
+	return false
+}
+
+// identity of object, the flag is false if object has no identity
+func (cfg *config) identity(obj map[string]any) (curie.IRI, bool, error) {
+	for _, key := range cfg.ids {
+		raw, has := obj[key]
+		if !has {
+			continue
+		}
+
+		id, ok := raw.(string)
+		if !ok {
+			if cfg.strict {
+				return "", false, fmt.Errorf("json identity codec do not support %T (%v)", raw, raw)
+			}
+			return "", false, nil
+		}
+
+		return curie.IRI(resolve(cfg.base, id)), true, nil
+	}
+
+	return "", false, nil
+}
+
+// predicate of object key, the flag is false if key is skipped
+func (cfg *config) predicate(key string) (curie.IRI, bool) {
+	if cfg.keys != nil {
+		return cfg.keys(key)
+	}
+
+	return curie.IRI(resolve(cfg.vocab, key)), true
+}
+
+// resolve relative term against namespace, terms with scheme or prefix are
+// absolute.
+func resolve(ns, term string) string {
+	if ns == "" || strings.ContainsRune(term, ':') {
+		return term
+	}
+	return ns + term
+}
+
+// Create new decoder that reads from r
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	cfg := newConfig(opts)
+
+	dec := &Decoder{dec: json.NewDecoder(r), cfg: cfg}
+	dec.dec.UseNumber()
+	dec.step = dec.document
+	return dec
+}
+
+// Head returns current statement
+func (dec *Decoder) Head() spock.SPOCK { return dec.head }
+
+// Err returns the error (if any) that terminated the stream
+func (dec *Decoder) Err() error { return dec.err }
+
+// Next reads next statement from input
+func (dec *Decoder) Next() bool {
+	for len(dec.queue) == 0 {
+		if dec.err != nil || dec.step == nil {
+			return false
+		}
+
+		if err := dec.step(); err != nil {
+			dec.err = err
+			return false
+		}
+	}
+
+	dec.head = dec.queue[0]
+	dec.queue = dec.queue[1:]
+	return true
+}
+
+// FMap applies f to each statement, it returns the first error either from
+// f or decoder.
+func (dec *Decoder) FMap(f func(spock.SPOCK) error) error {
+	for dec.Next() {
+		if err := f(dec.Head()); err != nil {
+			return err
+		}
+	}
+	return dec.err
+}
+
+// document is either array of objects or single object
+func (dec *Decoder) document() error {
+	tok, err := dec.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		dec.step = dec.element
+		return nil
+	case json.Delim('{'):
+		// object is decoded as a whole, the opening token is consumed
+		// already so that the object is assembled from its properties.
+		obj := map[string]any{}
+		for dec.dec.More() {
+			key, err := dec.dec.Token()
+			if err != nil {
+				return err
+			}
+
+			var val any
+			if err := dec.dec.Decode(&val); err != nil {
+				return err
+			}
+			obj[key.(string)] = val
+		}
+
+		if _, err := dec.dec.Token(); err != nil {
+			return err
+		}
+
+		dec.step = nil
+		return decodeObject(&dec.queue, dec.cfg, nil, nil, obj)
+	default:
+		dec.step = nil
+		return fmt.Errorf("json codec do not support %T (%v)", tok, tok)
+	}
+}
+
+// element of top-level array
+func (dec *Decoder) element() error {
+	if !dec.dec.More() {
+		dec.step = nil
+		_, err := dec.dec.Token()
+		return err
+	}
+
+	var val any
+	if err := dec.dec.Decode(&val); err != nil {
+		return err
+	}
+
+	return decodeArray(&dec.queue, dec.cfg, nil, nil, []any{val})
+}
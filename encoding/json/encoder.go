@@ -0,0 +1,318 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package json
+
+//
+// The file define encoder of knowledge statements into JSON objects.
+// Statements are grouped by subject, each subject becomes an object with `id`
+// property. Blank nodes (`_:` subjects) are nested inline into the object
+// that refers them.
+//
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// MarshalJSON encodes bag as JSON object(s), one object per subject.
+// Blank nodes referenced exactly once are embedded into the referring object.
+// Only xsd:string and xsd:anyURI objects are supported.
+func (bag Bag) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := encode(&buf, spock.Bag(bag)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Encoder writes stream of knowledge statements as JSON objects to output.
+type Encoder struct {
+	w io.Writer
+}
+
+// Create new encoder that writes to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode consumes stream and writes JSON object(s) to output, one object per
+// subject. Encoder buffers statements of the current subject only, the object
+// is written as soon as the next subject starts. The very first object is
+// held until the second one is known, a single object is written as is and
+// multiple objects are written as JSON array. The stream is expected
+// to be ordered by subject, otherwise the subject is split into multiple
+// objects. Unlike MarshalJSON, blank nodes are not embedded, references to
+// them are unknown until the end of stream. They are written as objects with
+// `id` property.
+//
+// Only xsd:string and xsd:anyURI objects are supported, encoder fails on
+// other data types.
+func (enc *Encoder) Encode(stream spock.Stream) error {
+	seq := &seqWriter{w: enc.w}
+	bag := spock.Bag{}
+
+	flush := func() error {
+		if len(bag) == 0 {
+			return nil
+		}
+
+		g := newGraph(bag)
+		g.flat = true
+		obj, err := g.encodeNode(g.seq[0], false)
+		if err != nil {
+			return err
+		}
+
+		bag = bag[:0]
+		return seq.Write(obj)
+	}
+
+	err := stream.FMap(func(x spock.SPOCK) error {
+		if len(bag) > 0 && bag[0].S != x.S {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		bag = append(bag, x)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return seq.Close()
+}
+
+// seqWriter writes sequence of JSON objects, single object is written as is,
+// multiple objects are written as array.
+type seqWriter struct {
+	w    io.Writer
+	n    int
+	head any
+}
+
+func (seq *seqWriter) Write(obj any) error {
+	seq.n++
+	switch seq.n {
+	case 1:
+		// the first object is held until the second one is known
+		seq.head = obj
+		return nil
+	case 2:
+		if _, err := io.WriteString(seq.w, "["); err != nil {
+			return err
+		}
+		if err := writeJSON(seq.w, seq.head); err != nil {
+			return err
+		}
+		seq.head = nil
+	}
+
+	if _, err := io.WriteString(seq.w, ","); err != nil {
+		return err
+	}
+
+	return writeJSON(seq.w, obj)
+}
+
+func (seq *seqWriter) Close() error {
+	switch seq.n {
+	case 0:
+		_, err := io.WriteString(seq.w, "[]")
+		return err
+	case 1:
+		return writeJSON(seq.w, seq.head)
+	default:
+		_, err := io.WriteString(seq.w, "]")
+		return err
+	}
+}
+
+// node is statements of the subject grouped by predicate
+type node struct {
+	id   xsd.AnyURI
+	keys []xsd.AnyURI
+	vals map[xsd.AnyURI][]xsd.Value
+}
+
+// graph of nodes, the order of subjects is preserved
+type graph struct {
+	seq     []*node
+	nodes   map[xsd.AnyURI]*node
+	refs    map[xsd.AnyURI]int
+	emitted map[xsd.AnyURI]bool
+	// flat graph do not know references to its blank nodes,
+	// their identity is always kept
+	flat bool
+}
+
+func newGraph(bag spock.Bag) *graph {
+	g := &graph{
+		seq:     make([]*node, 0),
+		nodes:   make(map[xsd.AnyURI]*node),
+		refs:    make(map[xsd.AnyURI]int),
+		emitted: make(map[xsd.AnyURI]bool),
+	}
+
+	for _, spock := range bag {
+		n, has := g.nodes[spock.S]
+		if !has {
+			n = &node{id: spock.S, vals: make(map[xsd.AnyURI][]xsd.Value)}
+			g.nodes[spock.S] = n
+			g.seq = append(g.seq, n)
+		}
+
+		if _, has := n.vals[spock.P]; !has {
+			n.keys = append(n.keys, spock.P)
+		}
+		n.vals[spock.P] = append(n.vals[spock.P], spock.O)
+
+		if o, ok := spock.O.(xsd.AnyURI); ok {
+			g.refs[o]++
+		}
+	}
+
+	return g
+}
+
+func isBlank(iri xsd.AnyURI) bool {
+	return strings.HasPrefix(iri.String(), "_:")
+}
+
+// blank node is embedded if it is referenced exactly once
+func (g *graph) isEmbedded(iri xsd.AnyURI) bool {
+	_, has := g.nodes[iri]
+	return has && isBlank(iri) && g.refs[iri] == 1
+}
+
+func encode(w io.Writer, bag spock.Bag) error {
+	g := newGraph(bag)
+	seq := &seqWriter{w: w}
+
+	for _, n := range g.seq {
+		if !g.isEmbedded(n.id) {
+			obj, err := g.encodeNode(n, false)
+			if err != nil {
+				return err
+			}
+			if err := seq.Write(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// blank nodes referencing each other in cycle are not reachable from roots
+	for _, n := range g.seq {
+		if !g.emitted[n.id] {
+			obj, err := g.encodeNode(n, false)
+			if err != nil {
+				return err
+			}
+			if err := seq.Write(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	return seq.Close()
+}
+
+func writeJSON(w io.Writer, obj any) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func (g *graph) encodeNode(n *node, inline bool) (map[string]any, error) {
+	g.emitted[n.id] = true
+
+	obj := make(map[string]any, len(n.keys)+1)
+	// unreferenced blank node does not need an identity
+	if !inline && (g.flat || !(isBlank(n.id) && g.refs[n.id] == 0)) {
+		obj["id"] = n.id.String()
+	}
+
+	for _, p := range n.keys {
+		vals := n.vals[p]
+		seq := make([]any, len(vals))
+		for i, o := range vals {
+			v, err := g.encodeValue(o)
+			if err != nil {
+				return nil, err
+			}
+			seq[i] = v
+		}
+
+		if len(seq) == 1 {
+			obj[p.String()] = seq[0]
+		} else {
+			obj[p.String()] = seq
+		}
+	}
+
+	return obj, nil
+}
+
+// encodes object of the statement. Integers, doubles and booleans are JSON
+// scalars, date and time is ISO-8601 string (see WithDateTime). Doubles
+// that are not finite are not representable by JSON.
+func (g *graph) encodeValue(o xsd.Value) (any, error) {
+	switch v := o.(type) {
+	case xsd.String:
+		return string(v), nil
+	case spock.Integer:
+		return int64(v), nil
+	case spock.Double:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return nil, fmt.Errorf("json codec do not support %v", v)
+		}
+		return float64(v), nil
+	case spock.Boolean:
+		return bool(v), nil
+	case spock.DateTime:
+		return v.String(), nil
+	case xsd.AnyURI:
+		if g.isEmbedded(v) && !g.emitted[v] {
+			return g.encodeNode(g.nodes[v], true)
+		}
+		return map[string]any{"id": v.String()}, nil
+	default:
+		return nil, fmt.Errorf("json codec do not support %T (%v)", o, o)
+	}
+}
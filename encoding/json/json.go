@@ -21,50 +21,76 @@
 package json
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"sort"
 
 	"github.com/fogfish/curie"
-	"github.com/fogfish/guid/v2"
 	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
 )
 
 type Bag spock.Bag
 
+const (
+	rdfFirst = curie.IRI("rdf:first")
+	rdfRest  = curie.IRI("rdf:rest")
+	rdfNil   = curie.IRI("rdf:nil")
+)
+
 func (bag *Bag) UnmarshalJSON(b []byte) error {
-	var raw any
+	dec := NewDecoder(bytes.NewReader(b))
 
-	if err := json.Unmarshal(b, &raw); err != nil {
-		return err
+	for dec.Next() {
+		*bag = append(*bag, dec.Head())
 	}
 
-	switch val := raw.(type) {
-	case []any:
-		return decodeArray(bag, nil, nil, val)
-	case map[string]any:
-		return decodeObject(bag, nil, nil, val)
-	default:
-		return fmt.Errorf("json codec do not support %T (%v)", val, val)
+	return dec.Err()
+}
+
+// xsd value of scalar, the flag is false if value is not scalar (see
+// codec.Literal). The null is scalar if decoder is configured by WithNull.
+func (cfg *config) literal(val any) (xsd.Value, bool) {
+	if val == nil && cfg.null != nil {
+		return xsd.String(*cfg.null), true
 	}
+
+	return codec.Literal(val, cfg.datetime)
 }
 
-func decodeArray(bag *Bag, s, p *curie.IRI, seq []any) error {
+func decodeArray(bag *Bag, cfg *config, s, p *curie.IRI, seq []any) error {
 	for _, val := range seq {
+		if lit, ok := cfg.literal(val); ok {
+			switch {
+			case s != nil && p != nil:
+				*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(*s), P: xsd.ToAnyURI(*p), O: lit})
+			case cfg.strict:
+				return fmt.Errorf("json array codec do not support top-level %T (%v)", val, val)
+			}
+			continue
+		}
+
 		switch o := val.(type) {
-		case float64:
-			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
+		case nil:
+			if cfg.strict {
+				return fmt.Errorf("json array codec do not support null")
 			}
-		case string:
-			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
+		case map[string]any:
+			if err := decodeObject(bag, cfg, s, p, o); err != nil {
+				return err
 			}
-		case bool:
-			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
+		case []any:
+			// nested arrays are lists, top-level array of arrays is flatten
+			if s == nil || p == nil {
+				if err := decodeArray(bag, cfg, nil, nil, o); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := decodeList(bag, cfg, *s, *p, o); err != nil {
+				return err
 			}
-		case map[string]any:
-			decodeObject(bag, s, p, o)
 		default:
 			return fmt.Errorf("json array codec do not support %T (%v)", val, val)
 		}
@@ -72,56 +98,94 @@ func decodeArray(bag *Bag, s, p *curie.IRI, seq []any) error {
 	return nil
 }
 
-func decodeObject(bag *Bag, s, p *curie.IRI, obj map[string]any) error {
-	id, has := decodeObjectID(obj)
-	if !has {
-		id = curie.New("_:%s", guid.L(guid.Clock))
+// decodes nested array as rdf:List
+func decodeList(bag *Bag, cfg *config, s, p curie.IRI, seq []any) error {
+	if len(seq) == 0 {
+		*bag = append(*bag, spock.From(s, p, rdfNil))
+		return nil
 	}
 
-	if s != nil && p != nil {
-		*bag = append(*bag, spock.From(*s, *p, id))
+	head := cfg.blank(s, p, seq)
+	*bag = append(*bag, spock.From(s, p, head))
+
+	for i, val := range seq {
+		first := rdfFirst
+		if err := decodeArray(bag, cfg, &head, &first, []any{val}); err != nil {
+			return err
+		}
+
+		if i == len(seq)-1 {
+			*bag = append(*bag, spock.From(head, rdfRest, rdfNil))
+			break
+		}
+
+		next := cfg.blank(head, rdfRest, seq[i+1:])
+		*bag = append(*bag, spock.From(head, rdfRest, next))
+		head = next
 	}
 
-	return decodeObjectProperties(bag, id, obj)
+	return nil
 }
 
-func decodeObjectID(obj map[string]any) (curie.IRI, bool) {
-	raw, has := obj["@id"]
+func decodeObject(bag *Bag, cfg *config, s, p *curie.IRI, obj map[string]any) error {
+	id, has, err := cfg.identity(obj)
+	if err != nil {
+		return err
+	}
+
 	if !has {
-		raw, has = obj["id"]
-		if !has {
-			return "", false
+		if s != nil && p != nil {
+			id = cfg.blank(*s, *p, obj)
+		} else {
+			id = cfg.blank("", "", obj)
 		}
 	}
 
-	id, ok := raw.(string)
-	if !ok {
-		return "", false
+	if s != nil && p != nil {
+		*bag = append(*bag, spock.From(*s, *p, id))
 	}
 
-	return curie.IRI(id), true
+	return decodeObjectProperties(bag, cfg, id, obj)
 }
 
-func decodeObjectProperties(bag *Bag, s curie.IRI, obj map[string]any) error {
-	for key, val := range obj {
-		if key == "@id" || key == "id" {
+// keys of object in deterministic order
+func sortedKeys(obj map[string]any) []string {
+	seq := make([]string, 0, len(obj))
+	for key := range obj {
+		seq = append(seq, key)
+	}
+	sort.Strings(seq)
+	return seq
+}
+
+func decodeObjectProperties(bag *Bag, cfg *config, s curie.IRI, obj map[string]any) error {
+	for _, key := range sortedKeys(obj) {
+		val := obj[key]
+		if cfg.isIdentity(key) {
+			continue
+		}
+
+		p, ok := cfg.predicate(key)
+		if !ok {
+			continue
+		}
+
+		if lit, ok := cfg.literal(val); ok {
+			*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: lit})
 			continue
 		}
-		p := curie.IRI(key)
 
 		switch o := val.(type) {
-		case float64:
-			*bag = append(*bag, spock.From(s, p, o))
-		case string:
-			*bag = append(*bag, spock.From(s, p, o))
-		case bool:
-			*bag = append(*bag, spock.From(s, p, o))
+		case nil:
+			if cfg.strict {
+				return fmt.Errorf("json object codec do not support null (%s)", key)
+			}
 		case map[string]any:
-			if err := decodeObject(bag, &s, &p, o); err != nil {
+			if err := decodeObject(bag, cfg, &s, &p, o); err != nil {
 				return err
 			}
 		case []any:
-			if err := decodeArray(bag, &s, &p, o); err != nil {
+			if err := decodeArray(bag, cfg, &s, &p, o); err != nil {
 				return err
 			}
 		default:
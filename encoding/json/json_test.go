@@ -21,8 +21,12 @@
 package json_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fogfish/curie"
 	"github.com/fogfish/guid/v2"
@@ -65,35 +69,35 @@ func TestJsonUnmarshal(t *testing.T) {
 		)
 	})
 
-	// t.Run("PropertyInt", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": 10
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 10),
-	// 		),
-	// 	)
-	// })
-
-	// t.Run("PropertyFloat", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": 10.0
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 10.0),
-	// 		),
-	// 	)
-	// })
-
-	// t.Run("PropertyBool", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": true
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", true),
-	// 		),
-	// 	)
-	// })
+	t.Run("PropertyInt", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": 10
+			}`).Equal(
+				spock.From(luid, "prop", 10),
+			),
+		)
+	})
+
+	t.Run("PropertyFloat", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": 10.0
+			}`).Equal(
+				spock.From(luid, "prop", 10.0),
+			),
+		)
+	})
+
+	t.Run("PropertyBool", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": true
+			}`).Equal(
+				spock.From(luid, "prop", true),
+			),
+		)
+	})
 
 	t.Run("PropertyArray", func(t *testing.T) {
 		it.Then(t).Should(
@@ -107,17 +111,17 @@ func TestJsonUnmarshal(t *testing.T) {
 		)
 	})
 
-	// t.Run("PropertyArrayHeterogenous", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": [1, "b", true]
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 1),
-	// 			spock.From(luid, "prop", "b"),
-	// 			spock.From(luid, "prop", true),
-	// 		),
-	// 	)
-	// })
+	t.Run("PropertyArrayHeterogenous", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": [1, "b", true]
+			}`).Equal(
+				spock.From(luid, "prop", 1),
+				spock.From(luid, "prop", "b"),
+				spock.From(luid, "prop", true),
+			),
+		)
+	})
 
 	t.Run("ArrayOfObjects", func(t *testing.T) {
 		it.Then(t).Should(
@@ -146,3 +150,394 @@ func TestJsonUnmarshal(t *testing.T) {
 		)
 	})
 }
+
+func TestJsonMarshal(t *testing.T) {
+	Codec := func(t *testing.T, bag spock.Bag) string {
+		t.Helper()
+		b, err := json.Marshal(proto.Bag(bag))
+		it.Then(t).Should(it.Nil(err))
+
+		return string(b)
+	}
+
+	t.Run("Property", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("id", "prop", "title"),
+				}),
+				`{"id":"id","prop":"title"}`,
+			),
+		)
+	})
+
+	t.Run("PropertyTyped", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("id", "n", 10),
+					spock.From("id", "pi", 1.5),
+					spock.From("id", "ok", true),
+					spock.From("id", "at", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+				}),
+				`{"at":"2023-01-02T03:04:05Z","id":"id","n":10,"ok":true,"pi":1.5}`,
+			),
+		)
+	})
+
+	t.Run("PropertyArray", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("id", "prop", "a"),
+					spock.From("id", "prop", "b"),
+				}),
+				`{"id":"id","prop":["a","b"]}`,
+			),
+		)
+	})
+
+	t.Run("ArrayOfObjects", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a", "prop", "a"),
+					spock.From("b", "prop", "b"),
+				}),
+				`[{"id":"a","prop":"a"},{"id":"b","prop":"b"}]`,
+			),
+		)
+	})
+
+	t.Run("ObjectOfObjects", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a", "prop", curie.IRI("b")),
+					spock.From("b", "prop", "title"),
+				}),
+				`[{"id":"a","prop":{"id":"b"}},{"id":"b","prop":"title"}]`,
+			),
+		)
+	})
+
+	t.Run("BlankNode", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a", "prop", curie.IRI("_:b")),
+					spock.From("_:b", "prop", "title"),
+				}),
+				`{"id":"a","prop":{"prop":"title"}}`,
+			),
+		)
+	})
+
+	t.Run("Encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		bag := proto.Bag{}
+
+		err := proto.NewEncoder(&buf).Encode(
+			spock.NewFilter(
+				func(spock.SPOCK) bool { return true },
+				spock.Bag{
+					spock.From("a", "prop", curie.IRI("b")),
+					spock.From("b", "prop", "title"),
+				}.Stream(),
+			),
+		)
+		it.Then(t).Should(it.Nil(err))
+
+		err = json.Unmarshal(buf.Bytes(), &bag)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "prop", curie.IRI("b")),
+				spock.From("b", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("EncoderStream", func(t *testing.T) {
+		var buf bytes.Buffer
+		written := 0
+
+		err := proto.NewEncoder(&buf).Encode(
+			spock.NewFilter(
+				func(x spock.SPOCK) bool {
+					if x.S.String() == "d" {
+						written = buf.Len()
+					}
+					return true
+				},
+				spock.Bag{
+					spock.From("a", "prop", curie.IRI("_:b")),
+					spock.From("_:b", "prop", "title"),
+					spock.From("c", "prop", "c"),
+					spock.From("d", "prop", "d"),
+				}.Stream(),
+			),
+		)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Greater(written, 0),
+			it.Equal(buf.String(),
+				`[{"id":"a","prop":{"id":"_:b"}},{"id":"_:b","prop":"title"},{"id":"c","prop":"c"},{"id":"d","prop":"d"}]`,
+			),
+		)
+	})
+
+	t.Run("EncoderEmpty", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := proto.NewEncoder(&buf).Encode(spock.Bag{}.Stream())
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(buf.String(), `[]`),
+		)
+	})
+}
+
+func TestJsonDecoder(t *testing.T) {
+	t.Run("Array", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`[
+			{"id": "a", "prop": "title"},
+			{"id": "b", "prop": "title"}
+		]`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "prop", "title"),
+				spock.From("b", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Object", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"id": "a", "prop": "title"}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Incremental", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`[
+			{"id": "a", "prop": "title"},
+			{"id": "b", "prop": ???
+		]`))
+
+		it.Then(t).Should(
+			it.True(dec.Next()),
+			it.Equal(dec.Head(), spock.From("a", "prop", "title")),
+		).ShouldNot(
+			it.True(dec.Next()),
+			it.Nil(dec.Err()),
+		)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`"title"`))
+
+		it.Then(t).ShouldNot(
+			it.True(dec.Next()),
+			it.Nil(dec.Err()),
+		)
+	})
+
+	t.Run("HashBlankNode", func(t *testing.T) {
+		decode := func() spock.Bag {
+			dec := proto.NewDecoder(strings.NewReader(`{"prop": "title", "nested": {"prop": "title"}}`),
+				proto.WithBlankNode(proto.HashBlankNode),
+			)
+			bag := spock.Bag{}
+			it.Then(t).Should(it.Nil(dec.FMap(bag.Join)))
+			return bag
+		}
+
+		a, b := decode(), decode()
+		it.Then(t).Should(
+			it.Equal(len(a), 3),
+			it.Seq(a).Equal(b...),
+			it.Equal(a[0].S, a[2].S),
+		).ShouldNot(
+			it.Equal(a[0].S, a[1].S),
+		)
+	})
+
+	t.Run("SeqBlankNode", func(t *testing.T) {
+		seq := 0
+		dec := proto.NewDecoder(strings.NewReader(`{"prop": "title", "nested": {"prop": "title"}}`),
+			proto.WithBlankNode(proto.SeqBlankNode(func() string {
+				seq++
+				return fmt.Sprintf("b%d", seq)
+			})),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("_:b1", "nested", curie.IRI("_:b2")),
+				spock.From("_:b2", "prop", "title"),
+				spock.From("_:b1", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Number", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"id": "a", "big": 12345678901234567890, "pi": 3.14159265358979323846, "ok": true}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "big", "12345678901234567890"),
+				spock.From("a", "ok", true),
+				spock.From("a", "pi", 3.141592653589793),
+			),
+		)
+	})
+
+	t.Run("DateTime", func(t *testing.T) {
+		input := `{"id": "a", "at": "2023-01-02T03:04:05+02:00", "on": "2023-01-02", "name": "title"}`
+		bag := spock.Bag{}
+		dec := proto.NewDecoder(strings.NewReader(input), proto.WithDateTime())
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "at", time.Date(2023, 1, 2, 1, 4, 5, 0, time.UTC)),
+				spock.From("a", "name", "title"),
+				spock.From("a", "on", time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+			),
+		)
+
+		bag = spock.Bag{}
+		dec = proto.NewDecoder(strings.NewReader(input))
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "at", "2023-01-02T03:04:05+02:00"),
+				spock.From("a", "name", "title"),
+				spock.From("a", "on", "2023-01-02"),
+			),
+		)
+	})
+
+	t.Run("BaseAndVocab", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"id": "a", "prop": {"id": "https://example.com/b"}, "schema:name": "title"}`),
+			proto.WithBase("https://example.com/"),
+			proto.WithVocab("https://schema.org/"),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("https://example.com/a", "https://schema.org/prop", curie.IRI("https://example.com/b")),
+				spock.From("https://example.com/a", "schema:name", "title"),
+			),
+		)
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"uid": "a", "id": "title"}`),
+			proto.WithIdentity("uid"),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "id", "title"),
+			),
+		)
+	})
+
+	t.Run("KeyMapper", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"id": "a", "name": "title", "etag": "xxx"}`),
+			proto.WithKeyMapper(func(key string) (curie.IRI, bool) {
+				if key == "etag" {
+					return "", false
+				}
+				return curie.IRI("schema:" + key), true
+			}),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "schema:name", "title"),
+			),
+		)
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		input := `{"id": "a", "prop": null, "seq": ["b", null]}`
+
+		bag := spock.Bag{}
+		dec := proto.NewDecoder(strings.NewReader(input))
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "seq", "b"),
+			),
+		)
+
+		bag = spock.Bag{}
+		dec = proto.NewDecoder(strings.NewReader(input), proto.WithNull("null"))
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "prop", "null"),
+				spock.From("a", "seq", "b"),
+				spock.From("a", "seq", "null"),
+			),
+		)
+	})
+
+	t.Run("NestedArray", func(t *testing.T) {
+		dec := proto.NewDecoder(strings.NewReader(`{"id": "a", "seq": [["b", "c"], []]}`),
+			proto.WithBlankNode(proto.SeqBlankNode(func() string { return "x" })),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "seq", curie.IRI("_:x")),
+				spock.From("_:x", "rdf:first", "b"),
+				spock.From("_:x", "rdf:rest", curie.IRI("_:x")),
+				spock.From("_:x", "rdf:first", "c"),
+				spock.From("_:x", "rdf:rest", curie.IRI("rdf:nil")),
+				spock.From("a", "seq", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		for _, input := range []string{
+			`{"id": "a", "prop": null}`,
+			`{"id": 1, "prop": "title"}`,
+			`[{"id": "a", "prop": "title"}, "title"]`,
+		} {
+			lenient := proto.NewDecoder(strings.NewReader(input))
+			it.Then(t).Should(
+				it.Nil(lenient.FMap(func(spock.SPOCK) error { return nil })),
+			)
+
+			strict := proto.NewDecoder(strings.NewReader(input), proto.WithStrict())
+			it.Then(t).ShouldNot(
+				it.Nil(strict.FMap(func(spock.SPOCK) error { return nil })),
+			)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package jsonld
+
+//
+// The file define strategies of minting blank node identifiers.
+//
+
+import (
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock/internal/codec"
+)
+
+// BlankNode mints identifier of blank node. It is called with subject and
+// predicate that refer the node (empty for top-level nodes) and the JSON
+// value of the node.
+type BlankNode = codec.BlankNode
+
+// ClockBlankNode mints unique identifier using guid.Clock, identifiers are
+// different each time the document is decoded. It is the default strategy.
+func ClockBlankNode(s, p curie.IRI, node any) curie.IRI {
+	return codec.ClockBlankNode(s, p, node)
+}
+
+// HashBlankNode mints identifier from the content of the node, the subject
+// and predicate that refer it. Identifiers are stable across runs, so that
+// re-imports of the same document are idempotent. Structurally identical
+// nodes of the same subject and predicate are merged.
+func HashBlankNode(s, p curie.IRI, node any) curie.IRI {
+	return codec.HashBlankNode(s, p, node)
+}
+
+// SeqBlankNode mints identifiers from caller-supplied generator, e.g. a
+// sequence of labels b0, b1, ...
+func SeqBlankNode(f func() string) BlankNode {
+	return codec.SeqBlankNode(f)
+}
@@ -0,0 +1,424 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package jsonld
+
+//
+// The file define JSON-LD active context, the term definitions used for
+// expansion and compaction of IRIs.
+// See https://www.w3.org/TR/json-ld11/#the-context
+//
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/fogfish/curie"
+)
+
+// term definition
+type term struct {
+	id        string // IRI (absolute or compact) mapped by the term
+	coerce    string // type coercion: @id, @vocab or datatype IRI
+	container string // container mapping: @set, @list
+	reverse   bool   // reverse property
+}
+
+// active context
+type context struct {
+	base  string
+	vocab string
+	terms map[string]*term
+
+	// terms eligible for compaction, sorted by key. The index is built on
+	// the first use, definition of terms resets it.
+	index []indexed
+
+	// decoder options
+	blank    BlankNode
+	datetime bool
+	strict   bool
+
+	// loader of remote contexts and depth of loaded contexts
+	loader ContextLoader
+	remote int
+}
+
+// term of compaction index
+type indexed struct {
+	key    string
+	iri    string // expanded IRI of the term
+	prefix bool   // term is eligible to be used as prefix of compact IRI
+}
+
+func newContext() *context {
+	return &context{terms: make(map[string]*term), blank: ClockBlankNode}
+}
+
+// scoped context of the node object, the active context is cloned if
+// node object defines own local context.
+func (ctx *context) scoped(obj map[string]any) (*context, error) {
+	local, has := obj["@context"]
+	if !has {
+		return ctx, nil
+	}
+
+	scope := &context{
+		base:     ctx.base,
+		vocab:    ctx.vocab,
+		terms:    make(map[string]*term, len(ctx.terms)),
+		blank:    ctx.blank,
+		datetime: ctx.datetime,
+		strict:   ctx.strict,
+		loader:   ctx.loader,
+		remote:   ctx.remote,
+	}
+	for key, t := range ctx.terms {
+		scope.terms[key] = t
+	}
+
+	if err := scope.parse(local); err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+// keyword returns keyword if the key is keyword or alias to keyword
+func (ctx *context) keyword(key string) string {
+	if strings.HasPrefix(key, "@") {
+		return key
+	}
+
+	if t, has := ctx.terms[key]; has && strings.HasPrefix(t.id, "@") {
+		return t.id
+	}
+
+	return ""
+}
+
+// term definition of the key, nil if key is not defined by context
+func (ctx *context) term(key string) *term {
+	if t, has := ctx.terms[key]; has {
+		return t
+	}
+	return nil
+}
+
+// datatype expands IRI of data type, compact xsd: IRIs are expanded even
+// if context does not define the prefix.
+func (ctx *context) datatype(value string) string {
+	iri := ctx.expandIRI(value, true)
+	if strings.HasPrefix(iri, "xsd:") {
+		return xsdNS + iri[len("xsd:"):]
+	}
+	return iri
+}
+
+// expands value to IRI, which is compacted to CURIE if context defines
+// matching prefix.
+func (ctx *context) curie(value string, vocab bool) curie.IRI {
+	return curie.IRI(ctx.compactPrefix(ctx.expandIRI(value, vocab)))
+}
+
+// parse local context definition(s) into the active context
+func (ctx *context) parse(raw any) error {
+	ctx.index = nil
+
+	switch def := raw.(type) {
+	case nil:
+		blank, datetime, strict, loader, remote := ctx.blank, ctx.datetime, ctx.strict, ctx.loader, ctx.remote
+		*ctx = *newContext()
+		ctx.blank, ctx.datetime, ctx.strict, ctx.loader, ctx.remote = blank, datetime, strict, loader, remote
+		return nil
+	case string:
+		return ctx.parseRemote(def)
+	case []any:
+		for _, x := range def {
+			if err := ctx.parse(x); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		return ctx.parseDefinition(def)
+	default:
+		return fmt.Errorf("json-ld context do not support %T (%v)", raw, raw)
+	}
+}
+
+// parse remote context, it is ignored without loader. Terms of ignored
+// context are expanded against @vocab and @base.
+func (ctx *context) parseRemote(iri string) error {
+	if ctx.loader == nil {
+		return nil
+	}
+
+	if ctx.remote >= maxTermDepth {
+		return fmt.Errorf("json-ld remote context %s exceeds depth %d", iri, maxTermDepth)
+	}
+
+	doc, err := ctx.loader(iri)
+	if err != nil {
+		return fmt.Errorf("json-ld remote context %s: %w", iri, err)
+	}
+
+	if obj, ok := doc.(map[string]any); ok {
+		if def, has := obj["@context"]; has {
+			doc = def
+		}
+	}
+
+	ctx.remote++
+	defer func() { ctx.remote-- }()
+	return ctx.parse(doc)
+}
+
+func (ctx *context) parseDefinition(def map[string]any) error {
+	for key, val := range def {
+		switch key {
+		case "@base":
+			switch v := val.(type) {
+			case nil:
+				ctx.base = ""
+			case string:
+				ctx.base = v
+			default:
+				return fmt.Errorf("json-ld @base do not support %T (%v)", val, val)
+			}
+		case "@vocab":
+			switch v := val.(type) {
+			case nil:
+				ctx.vocab = ""
+			case string:
+				// defer expansion of vocabulary mapping until terms are defined
+			default:
+				return fmt.Errorf("json-ld @vocab do not support %T (%v)", v, v)
+			}
+		case "@version", "@language", "@direction", "@protected", "@propagate":
+			continue
+		default:
+			if err := ctx.parseTerm(key, val); err != nil {
+				return err
+			}
+		}
+	}
+
+	if vocab, ok := def["@vocab"].(string); ok {
+		ctx.vocab = ctx.expandIRI(vocab, true)
+	}
+
+	return nil
+}
+
+func (ctx *context) parseTerm(key string, val any) error {
+	switch v := val.(type) {
+	case nil:
+		delete(ctx.terms, key)
+	case string:
+		ctx.terms[key] = &term{id: v}
+	case map[string]any:
+		t := &term{id: key}
+		if id, has := v["@id"]; has {
+			s, ok := id.(string)
+			if !ok {
+				return fmt.Errorf("json-ld term %s do not support @id %T (%v)", key, id, id)
+			}
+			t.id = s
+		}
+
+		if id, has := v["@reverse"]; has {
+			s, ok := id.(string)
+			if !ok {
+				return fmt.Errorf("json-ld term %s do not support @reverse %T (%v)", key, id, id)
+			}
+			t.id = s
+			t.reverse = true
+		}
+
+		if kind, has := v["@type"]; has {
+			s, ok := kind.(string)
+			if !ok {
+				return fmt.Errorf("json-ld term %s do not support @type %T (%v)", key, kind, kind)
+			}
+			t.coerce = s
+		}
+
+		if container, has := v["@container"]; has {
+			s, ok := container.(string)
+			if !ok {
+				return fmt.Errorf("json-ld term %s do not support @container %T (%v)", key, container, container)
+			}
+			t.container = s
+		}
+
+		ctx.terms[key] = t
+	default:
+		return fmt.Errorf("json-ld term %s do not support %T (%v)", key, val, val)
+	}
+
+	return nil
+}
+
+// expands term, compact IRI or relative IRI to absolute IRI.
+// The vocab flag defines if value is relative to @vocab (properties, types)
+// or to @base (identifiers).
+func (ctx *context) expandIRI(value string, vocab bool) string {
+	return ctx.expand(value, vocab, 0)
+}
+
+// maximum depth of term definitions referencing each other
+const maxTermDepth = 8
+
+func (ctx *context) expand(value string, vocab bool, depth int) string {
+	if depth > maxTermDepth || strings.HasPrefix(value, "@") || strings.HasPrefix(value, "_:") {
+		return value
+	}
+
+	if t, has := ctx.terms[value]; has && vocab && t.id != value {
+		return ctx.expand(t.id, true, depth+1)
+	}
+
+	if prefix, suffix, found := strings.Cut(value, ":"); found {
+		if strings.HasPrefix(suffix, "//") {
+			return value
+		}
+
+		if t, has := ctx.terms[prefix]; has && t.id != value {
+			return ctx.expand(t.id, true, depth+1) + suffix
+		}
+
+		return value
+	}
+
+	if vocab && ctx.vocab != "" {
+		return ctx.vocab + value
+	}
+
+	if !vocab && ctx.base != "" {
+		base, err := url.Parse(ctx.base)
+		if err != nil {
+			return value
+		}
+		ref, err := url.Parse(value)
+		if err != nil {
+			return value
+		}
+		return base.ResolveReference(ref).String()
+	}
+
+	return value
+}
+
+// terms of compaction index sorted by key, guarantees deterministic output
+func (ctx *context) sortedTerms() []indexed {
+	if ctx.index != nil {
+		return ctx.index
+	}
+
+	keys := make([]string, 0, len(ctx.terms))
+	for key := range ctx.terms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ctx.index = make([]indexed, len(keys))
+	for i, key := range keys {
+		iri := ctx.expandIRI(key, true)
+		ctx.index[i] = indexed{key: key, iri: iri, prefix: ctx.isPrefix(key, iri)}
+	}
+
+	return ctx.index
+}
+
+// term is eligible to be used as prefix of compact IRI
+func (ctx *context) isPrefix(key string, iri string) bool {
+	if strings.ContainsAny(key, ":/") || iri == "" {
+		return false
+	}
+
+	switch iri[len(iri)-1] {
+	case '/', '#', ':', '?', '[', ']', '@':
+		return true
+	}
+
+	return false
+}
+
+// compacts absolute IRI to term, compact IRI or relative IRI.
+// The vocab flag defines if value is relative to @vocab (properties, types)
+// or to @base (identifiers).
+func (ctx *context) compactIRI(iri string, vocab bool) string {
+	if strings.HasPrefix(iri, "@") || strings.HasPrefix(iri, "_:") {
+		return iri
+	}
+
+	if vocab {
+		for _, t := range ctx.sortedTerms() {
+			if t.iri == iri {
+				return t.key
+			}
+		}
+
+		if ctx.vocab != "" && strings.HasPrefix(iri, ctx.vocab) {
+			suffix := iri[len(ctx.vocab):]
+			if _, has := ctx.terms[suffix]; suffix != "" && !has && !strings.Contains(suffix, ":") {
+				return suffix
+			}
+		}
+	}
+
+	if compact := ctx.compactPrefix(iri); compact != iri {
+		return compact
+	}
+
+	if !vocab && ctx.base != "" && strings.HasPrefix(iri, ctx.base) && len(iri) > len(ctx.base) {
+		return iri[len(ctx.base):]
+	}
+
+	return iri
+}
+
+// compacts absolute IRI to compact IRI using shortest matching prefix
+func (ctx *context) compactPrefix(iri string) string {
+	if strings.HasPrefix(iri, "@") || strings.HasPrefix(iri, "_:") {
+		return iri
+	}
+
+	compact := ""
+	for _, t := range ctx.sortedTerms() {
+		if !t.prefix || !strings.HasPrefix(iri, t.iri) || iri == t.iri {
+			continue
+		}
+
+		candidate := t.key + ":" + iri[len(t.iri):]
+		if compact == "" || len(candidate) < len(compact) {
+			compact = candidate
+		}
+	}
+
+	if compact == "" {
+		return iri
+	}
+
+	return compact
+}
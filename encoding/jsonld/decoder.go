@@ -0,0 +1,210 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package jsonld
+
+//
+// The file define streaming decoder of JSON-LD documents. The decoder reads
+// top-level array and @graph array element by element, the memory is
+// bounded by the size of single node object rather than the whole document.
+//
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kshard/spock"
+)
+
+// Decoder reads knowledge statements from JSON-LD document incrementally.
+// Decoder implements spock.Stream.
+//
+// The decoder streams @graph only if @context precedes it in the document,
+// otherwise the top-level object is decoded as a whole once it is read,
+// the @context that follows @graph applies to it.
+type Decoder struct {
+	dec      *json.Decoder
+	cfg      *config
+	ctx      *context
+	step     func() error
+	top      map[string]any
+	scoped   bool // @context of top-level object is known
+	streamed bool
+	queue    Bag
+	head     spock.SPOCK
+	err      error
+}
+
+var _ spock.Stream = (*Decoder)(nil)
+
+// Create new decoder that reads from r
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	cfg := newConfig(opts)
+
+	dec := &Decoder{dec: json.NewDecoder(r), cfg: cfg}
+	dec.dec.UseNumber()
+	dec.ctx = dec.context()
+	dec.step = dec.document
+	return dec
+}
+
+// empty active context of the document
+func (dec *Decoder) context() *context {
+	return dec.cfg.activeContext()
+}
+
+// Head returns current statement
+func (dec *Decoder) Head() spock.SPOCK { return dec.head }
+
+// Err returns the error (if any) that terminated the stream
+func (dec *Decoder) Err() error { return dec.err }
+
+// Next reads next statement from input
+func (dec *Decoder) Next() bool {
+	for len(dec.queue) == 0 {
+		if dec.err != nil || dec.step == nil {
+			return false
+		}
+
+		if err := dec.step(); err != nil {
+			dec.err = err
+			return false
+		}
+	}
+
+	dec.head = dec.queue[0]
+	dec.queue = dec.queue[1:]
+	return true
+}
+
+// FMap applies f to each statement, it returns the first error either from
+// f or decoder.
+func (dec *Decoder) FMap(f func(spock.SPOCK) error) error {
+	for dec.Next() {
+		if err := f(dec.Head()); err != nil {
+			return err
+		}
+	}
+	return dec.err
+}
+
+// document is either array of node objects or top-level object
+func (dec *Decoder) document() error {
+	tok, err := dec.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		dec.step = dec.element(func() error { dec.step = nil; return nil })
+		return nil
+	case json.Delim('{'):
+		dec.top = map[string]any{}
+		dec.step = dec.property
+		return nil
+	default:
+		dec.step = nil
+		return fmt.Errorf("json-ld codec do not support %T (%v)", tok, tok)
+	}
+}
+
+// property of top-level object, @graph is streamed if active context is
+// known, other properties are collected. The @graph that precedes @context
+// is collected too, it is decoded with the top-level object.
+func (dec *Decoder) property() error {
+	if !dec.dec.More() {
+		if _, err := dec.dec.Token(); err != nil {
+			return err
+		}
+		dec.step = nil
+
+		if dec.streamed {
+			return nil
+		}
+		return decodeDocument(&dec.queue, dec.context(), dec.top)
+	}
+
+	tok, err := dec.dec.Token()
+	if err != nil {
+		return err
+	}
+	key := tok.(string)
+
+	switch dec.ctx.keyword(key) {
+	case "@context":
+		if dec.streamed {
+			return fmt.Errorf("json-ld @context after @graph is not supported by streaming decoder")
+		}
+
+		var val any
+		if err := dec.dec.Decode(&val); err != nil {
+			return err
+		}
+		dec.top[key] = val
+		dec.scoped = true
+
+		dec.ctx, err = dec.context().scoped(map[string]any{"@context": val})
+		return err
+	case "@graph":
+		if !dec.scoped {
+			break
+		}
+
+		tok, err := dec.dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("json-ld graph codec do not support %T (%v)", tok, tok)
+		}
+
+		dec.streamed = true
+		dec.step = dec.element(dec.property)
+		return nil
+	}
+
+	var val any
+	if err := dec.dec.Decode(&val); err != nil {
+		return err
+	}
+	dec.top[key] = val
+
+	return nil
+}
+
+// element of array, the step continues with next once array is consumed
+func (dec *Decoder) element(next func() error) func() error {
+	return func() error {
+		if !dec.dec.More() {
+			dec.step = next
+			_, err := dec.dec.Token()
+			return err
+		}
+
+		var val any
+		if err := dec.dec.Decode(&val); err != nil {
+			return err
+		}
+
+		return decodeArray(&dec.queue, dec.ctx, nil, nil, []any{val})
+	}
+}
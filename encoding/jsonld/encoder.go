@@ -0,0 +1,650 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package jsonld
+
+//
+// The file define JSON-LD serializer. Knowledge statements are grouped by
+// subject into node objects of expanded document form, which are then
+// compacted against supplied context or framed.
+// See https://www.w3.org/TR/json-ld11/#forms-of-json-ld
+//
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+const (
+	rdfType    = "rdf:type"
+	rdfTypeIRI = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+)
+
+// MarshalJSON encodes bag as compacted JSON-LD document without context,
+// properties are written as compact IRIs.
+func (bag Bag) MarshalJSON() ([]byte, error) {
+	doc, err := Compact(spock.Bag(bag), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+// Option configures JSON-LD codec
+type Option func(*config)
+
+type config struct {
+	prefixes curie.Prefixes
+	context  any
+	frame    map[string]any
+	blank    BlankNode
+	datetime bool
+	strict   bool
+	loader   ContextLoader
+}
+
+// ContextLoader loads remote context by IRI, it returns either the context
+// document (object with @context) or the context definition.
+type ContextLoader func(iri string) (any, error)
+
+// WithContextLoader configures loader of remote contexts referenced by IRI.
+// Remote contexts are ignored without the loader, terms and compact IRIs
+// defined by them are not expanded, the rest is expanded against @vocab
+// and @base.
+func WithContextLoader(loader ContextLoader) Option {
+	return func(c *config) { c.loader = loader }
+}
+
+// WithPrefixes expands compact IRIs of knowledge statements to absolute IRIs
+// using given prefixes.
+func WithPrefixes(prefixes curie.Prefixes) Option {
+	return func(c *config) { c.prefixes = prefixes }
+}
+
+// WithContext instructs encoder to produce compacted document form
+func WithContext(context any) Option {
+	return func(c *config) { c.context = context }
+}
+
+// WithFrame instructs encoder to produce framed document form
+func WithFrame(frame map[string]any) Option {
+	return func(c *config) { c.frame = frame }
+}
+
+// WithBlankNode configures decoder strategy of minting blank nodes
+func WithBlankNode(blank BlankNode) Option {
+	return func(c *config) { c.blank = blank }
+}
+
+// WithDateTime enables detection of ISO-8601 date and time strings by
+// decoder, they are decoded as spock.DateTime so that order of values is
+// chronological. Values typed xsd:date or xsd:dateTime are always decoded
+// as spock.DateTime.
+func WithDateTime() Option {
+	return func(c *config) { c.datetime = true }
+}
+
+// WithStrictLiterals instructs decoder to fail on literals that cannot be
+// represented by the library without loss: language-tagged strings and
+// literals of data types other than xsd:string, xsd:anyURI, xsd:date and
+// xsd:dateTime. By default, decoder drops the language tag and the data
+// type of such literals, their lexical form is decoded as xsd:string.
+func WithStrictLiterals() Option {
+	return func(c *config) { c.strict = true }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// activeContext is empty active context configured by options
+func (c *config) activeContext() *context {
+	ctx := newContext()
+	if c.blank != nil {
+		ctx.blank = c.blank
+	}
+	ctx.datetime = c.datetime
+	ctx.strict = c.strict
+	ctx.loader = c.loader
+	return ctx
+}
+
+// Encoder writes stream of knowledge statements as JSON-LD document.
+// The document is expanded unless context or frame is configured.
+type Encoder struct {
+	w    io.Writer
+	opts []Option
+}
+
+// Create new encoder that writes to w
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	return &Encoder{w: w, opts: opts}
+}
+
+// Encode consumes stream and writes JSON-LD document to output.
+func (enc *Encoder) Encode(stream spock.Stream) error {
+	bag := spock.Bag{}
+	if err := stream.FMap(bag.Join); err != nil {
+		return err
+	}
+
+	var (
+		doc any
+		err error
+		cfg = newConfig(enc.opts)
+	)
+
+	switch {
+	case cfg.frame != nil:
+		doc, err = Frame(bag, cfg.frame, enc.opts...)
+	case cfg.context != nil:
+		doc, err = Compact(bag, cfg.context, enc.opts...)
+	default:
+		doc, err = Expand(bag, enc.opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(enc.w).Encode(doc)
+}
+
+// Expand produces expanded document form, all IRIs are absolute and values
+// are wrapped into value objects.
+func Expand(bag spock.Bag, opts ...Option) ([]any, error) {
+	g, err := newGraph(bag, newContext(), newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]any, 0, len(g.seq))
+	for _, n := range g.roots() {
+		seq = append(seq, g.expandNode(n, embedOnce, false, false))
+	}
+
+	return seq, nil
+}
+
+// Compact produces compacted document form against the context.
+// Document uses @graph if there are several root nodes.
+func Compact(bag spock.Bag, context any, opts ...Option) (map[string]any, error) {
+	cfg := newConfig(opts)
+	ctx := cfg.activeContext()
+	if err := ctx.parse(context); err != nil {
+		return nil, err
+	}
+
+	g, err := newGraph(bag, ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]map[string]any, 0, len(g.seq))
+	for _, n := range g.roots() {
+		seq = append(seq, ctx.compactNode(g.expandNode(n, embedOnce, false, false)))
+	}
+
+	return document(context, seq), nil
+}
+
+// Frame produces framed document form. The frame selects root nodes by
+// @id, @type or presence of properties, referenced nodes are embedded into
+// roots according to @embed flag (@once, @always, @never). The result is
+// compacted against frame's @context.
+func Frame(bag spock.Bag, frame map[string]any, opts ...Option) (map[string]any, error) {
+	context := frame["@context"]
+
+	cfg := newConfig(opts)
+	ctx := cfg.activeContext()
+	if err := ctx.parse(context); err != nil {
+		return nil, err
+	}
+
+	g, err := newGraph(bag, ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := newMatcher(ctx, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]map[string]any, 0)
+	for _, n := range g.seq {
+		if match.test(g, n) {
+			seq = append(seq, ctx.compactNode(g.expandNode(n, match.embed, true, false)))
+		}
+	}
+
+	return document(context, seq), nil
+}
+
+// document with @context and @graph
+func document(context any, seq []map[string]any) map[string]any {
+	var doc map[string]any
+
+	if len(seq) == 1 {
+		doc = seq[0]
+	} else {
+		graph := make([]any, len(seq))
+		for i, x := range seq {
+			graph[i] = x
+		}
+		doc = map[string]any{"@graph": graph}
+	}
+
+	if context != nil {
+		doc["@context"] = context
+	}
+
+	return doc
+}
+
+//------------------------------------------------------------------------------
+
+// embedding strategy of referenced nodes
+type embed string
+
+const (
+	embedOnce   = embed("@once")
+	embedAlways = embed("@always")
+	embedNever  = embed("@never")
+)
+
+// node is statements of the subject grouped by predicate
+type node struct {
+	id    xsd.AnyURI
+	types []string
+	keys  []xsd.AnyURI
+	vals  map[xsd.AnyURI][]xsd.Value
+}
+
+// graph of nodes, the order of subjects is preserved
+type graph struct {
+	ctx      *context
+	cfg      *config
+	seq      []*node
+	nodes    map[xsd.AnyURI]*node
+	refs     map[xsd.AnyURI]int
+	embedded map[xsd.AnyURI]bool
+}
+
+func newGraph(bag spock.Bag, ctx *context, cfg *config) (*graph, error) {
+	g := &graph{
+		ctx:      ctx,
+		cfg:      cfg,
+		seq:      make([]*node, 0),
+		nodes:    make(map[xsd.AnyURI]*node),
+		refs:     make(map[xsd.AnyURI]int),
+		embedded: make(map[xsd.AnyURI]bool),
+	}
+
+	for _, spock := range bag {
+		n, has := g.nodes[spock.S]
+		if !has {
+			n = &node{id: spock.S, vals: make(map[xsd.AnyURI][]xsd.Value)}
+			g.nodes[spock.S] = n
+			g.seq = append(g.seq, n)
+		}
+
+		switch o := spock.O.(type) {
+		case xsd.AnyURI:
+			if p := g.absolute(spock.P); p == rdfTypeIRI || spock.P.String() == rdfType {
+				n.types = append(n.types, g.absolute(o))
+				continue
+			}
+			g.refs[o]++
+		case xsd.String:
+		default:
+			if _, _, ok := codec.Lexical(o); !ok {
+				return nil, fmt.Errorf("json-ld codec do not support %T (%v)", o, o)
+			}
+		}
+
+		if _, has := n.vals[spock.P]; !has {
+			n.keys = append(n.keys, spock.P)
+		}
+		n.vals[spock.P] = append(n.vals[spock.P], spock.O)
+	}
+
+	return g, nil
+}
+
+// absolute IRI of knowledge statement component
+func (g *graph) absolute(iri xsd.AnyURI) string {
+	s := iri.String()
+	if strings.HasPrefix(s, "_:") {
+		return s
+	}
+
+	if prefix := curie.Prefix(curie.IRI(s)); prefix != "" {
+		if _, has := g.ctx.terms[prefix]; has {
+			return g.ctx.expandIRI(s, false)
+		}
+	}
+
+	if g.cfg.prefixes != nil {
+		return curie.URI(g.cfg.prefixes, curie.IRI(s))
+	}
+
+	return s
+}
+
+func isBlank(iri xsd.AnyURI) bool {
+	return strings.HasPrefix(iri.String(), "_:")
+}
+
+// blank node is embedded if it is referenced exactly once
+func (g *graph) isEmbedded(iri xsd.AnyURI) bool {
+	_, has := g.nodes[iri]
+	return has && isBlank(iri) && g.refs[iri] == 1
+}
+
+// nodes at top level of document
+func (g *graph) roots() []*node {
+	seq := make([]*node, 0, len(g.seq))
+	for _, n := range g.seq {
+		if !g.isEmbedded(n.id) {
+			seq = append(seq, n)
+		}
+	}
+
+	// blank nodes referencing each other in cycle are not reachable from roots
+	reachable := make(map[xsd.AnyURI]bool)
+	var walk func(*node)
+	walk = func(n *node) {
+		reachable[n.id] = true
+		for _, vals := range n.vals {
+			for _, o := range vals {
+				if iri, ok := o.(xsd.AnyURI); ok && g.isEmbedded(iri) && !reachable[iri] {
+					walk(g.nodes[iri])
+				}
+			}
+		}
+	}
+	for _, n := range seq {
+		walk(n)
+	}
+
+	for _, n := range g.seq {
+		if !reachable[n.id] {
+			seq = append(seq, n)
+			walk(n)
+		}
+	}
+
+	return seq
+}
+
+// expands node to node object, the framed flag embeds all referenced nodes
+// according to embedding strategy, otherwise only blank nodes are embedded.
+func (g *graph) expandNode(n *node, strategy embed, framed, inline bool) map[string]any {
+	g.embedded[n.id] = true
+
+	obj := make(map[string]any, len(n.keys)+2)
+	// blank node does not need an identity unless it is referenced elsewhere
+	if !isBlank(n.id) || !(g.refs[n.id] == 0 || (inline && g.refs[n.id] == 1)) {
+		obj["@id"] = g.absolute(n.id)
+	}
+
+	if len(n.types) > 0 {
+		types := make([]any, len(n.types))
+		for i, t := range n.types {
+			types[i] = t
+		}
+		obj["@type"] = types
+	}
+
+	for _, p := range n.keys {
+		vals := n.vals[p]
+		seq := make([]any, len(vals))
+		for i, o := range vals {
+			seq[i] = g.expandValue(o, strategy, framed)
+		}
+		obj[g.absolute(p)] = seq
+	}
+
+	return obj
+}
+
+func (g *graph) expandValue(o xsd.Value, strategy embed, framed bool) any {
+	switch v := o.(type) {
+	case xsd.AnyURI:
+		if n, has := g.nodes[v]; has {
+			switch {
+			case !framed && g.isEmbedded(v) && !g.embedded[v]:
+				return g.expandNode(n, strategy, framed, true)
+			case framed && strategy == embedOnce && !g.embedded[v]:
+				return g.expandNode(n, strategy, framed, true)
+			case framed && strategy == embedAlways && !g.embedded[v]:
+				obj := g.expandNode(n, strategy, framed, true)
+				delete(g.embedded, v)
+				return obj
+			}
+		}
+		return map[string]any{"@id": g.absolute(v)}
+	case xsd.String:
+		return map[string]any{"@value": string(v)}
+	case spock.Integer:
+		return map[string]any{"@value": int64(v)}
+	case spock.Boolean:
+		return map[string]any{"@value": bool(v)}
+	default:
+		// doubles are typed, native JSON number 1.0 is read back as integer
+		if lit, datatype, ok := codec.Lexical(o); ok {
+			return map[string]any{"@value": lit, "@type": datatype}
+		}
+		return nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// compacts expanded node object against active context
+func (ctx *context) compactNode(obj map[string]any) map[string]any {
+	compact := make(map[string]any, len(obj))
+
+	for key, val := range obj {
+		switch key {
+		case "@id":
+			compact[key] = ctx.compactIRI(val.(string), false)
+		case "@type":
+			types := val.([]any)
+			seq := make([]any, len(types))
+			for i, t := range types {
+				seq[i] = ctx.compactIRI(t.(string), true)
+			}
+			if len(seq) == 1 {
+				compact[key] = seq[0]
+			} else {
+				compact[key] = seq
+			}
+		default:
+			name := ctx.compactIRI(key, true)
+			def := ctx.terms[name]
+			if def == nil {
+				def = &term{}
+			}
+
+			vals := val.([]any)
+			seq := make([]any, len(vals))
+			for i, v := range vals {
+				seq[i] = ctx.compactValue(def, v.(map[string]any))
+			}
+			if len(seq) == 1 && def.container != "@set" && def.container != "@list" {
+				compact[name] = seq[0]
+			} else {
+				compact[name] = seq
+			}
+		}
+	}
+
+	return compact
+}
+
+func (ctx *context) compactValue(def *term, obj map[string]any) any {
+	if v, has := obj["@value"]; has {
+		if kind, typed := obj["@type"].(string); typed {
+			if def.coerce != "" && ctx.datatype(def.coerce) == kind {
+				return v
+			}
+			return map[string]any{"@value": v, "@type": ctx.compactIRI(kind, true)}
+		}
+		if def.coerce != "" && def.coerce != "@id" && def.coerce != "@vocab" {
+			return obj
+		}
+		return v
+	}
+
+	if id, has := obj["@id"]; has && len(obj) == 1 {
+		switch def.coerce {
+		case "@id":
+			return ctx.compactIRI(id.(string), false)
+		case "@vocab":
+			return ctx.compactIRI(id.(string), true)
+		default:
+			return map[string]any{"@id": ctx.compactIRI(id.(string), false)}
+		}
+	}
+
+	return ctx.compactNode(obj)
+}
+
+//------------------------------------------------------------------------------
+
+// matcher of root nodes to frame
+type matcher struct {
+	ids   map[string]bool
+	types map[string]bool
+	props []string
+	embed embed
+}
+
+func newMatcher(ctx *context, frame map[string]any) (*matcher, error) {
+	m := &matcher{embed: embedOnce}
+
+	for key, val := range frame {
+		switch key {
+		case "@context":
+			continue
+		case "@embed":
+			switch val {
+			case "@once", true:
+				m.embed = embedOnce
+			case "@always":
+				m.embed = embedAlways
+			case "@never", false:
+				m.embed = embedNever
+			default:
+				return nil, fmt.Errorf("json-ld frame do not support @embed %v", val)
+			}
+		case "@id":
+			seq, err := frameValues(key, val)
+			if err != nil {
+				return nil, err
+			}
+			m.ids = make(map[string]bool)
+			for _, x := range seq {
+				m.ids[ctx.expandIRI(x, false)] = true
+			}
+		case "@type":
+			seq, err := frameValues(key, val)
+			if err != nil {
+				return nil, err
+			}
+			m.types = make(map[string]bool)
+			for _, x := range seq {
+				m.types[ctx.expandIRI(x, true)] = true
+			}
+		default:
+			if !strings.HasPrefix(key, "@") {
+				m.props = append(m.props, ctx.expandIRI(key, true))
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func frameValues(key string, val any) ([]string, error) {
+	switch v := val.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		seq := make([]string, 0, len(v))
+		for _, x := range v {
+			s, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("json-ld frame do not support %s %T (%v)", key, x, x)
+			}
+			seq = append(seq, s)
+		}
+		return seq, nil
+	default:
+		return nil, fmt.Errorf("json-ld frame do not support %s %T (%v)", key, val, val)
+	}
+}
+
+func (m *matcher) test(g *graph, n *node) bool {
+	if m.ids != nil && !m.ids[g.absolute(n.id)] {
+		return false
+	}
+
+	if m.types != nil {
+		has := false
+		for _, t := range n.types {
+			if m.types[t] {
+				has = true
+				break
+			}
+		}
+		if !has {
+			return false
+		}
+	}
+
+	for _, prop := range m.props {
+		has := false
+		for _, p := range n.keys {
+			if g.absolute(p) == prop {
+				has = true
+				break
+			}
+		}
+		if !has {
+			return false
+		}
+	}
+
+	return true
+}
@@ -21,59 +21,113 @@
 package jsonld
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/fogfish/curie"
-	"github.com/fogfish/guid/v2"
 	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
 )
 
 type Bag spock.Bag
 
+const (
+	xsdNS     = codec.XSD
+	xsdAnyURI = xsdNS + "anyURI"
+	xsdString = xsdNS + "string"
+	rdfFirst  = curie.IRI("rdf:first")
+	rdfRest   = curie.IRI("rdf:rest")
+	rdfNil    = curie.IRI("rdf:nil")
+)
+
 func (bag *Bag) UnmarshalJSON(b []byte) error {
-	var raw any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
 
-	if err := json.Unmarshal(b, &raw); err != nil {
+	var val any
+	if err := dec.Decode(&val); err != nil {
 		return err
 	}
 
-	switch val := raw.(type) {
+	ctx := newConfig(nil).activeContext()
+	switch doc := val.(type) {
 	case []any:
-		return decodeArray(bag, nil, nil, val)
+		return decodeArray(bag, ctx, nil, nil, doc)
 	case map[string]any:
-		graph, has := val["@graph"]
-		if has {
-			switch seq := graph.(type) {
-			case []any:
-				return decodeArray(bag, nil, nil, seq)
-			default:
-				return fmt.Errorf("json-ld graph codec do not support %T (%v)", val, val)
-			}
-		}
-		return decodeObject(bag, nil, nil, val)
+		return decodeDocument(bag, ctx, doc)
 	default:
 		return fmt.Errorf("json-ld codec do not support %T (%v)", val, val)
 	}
 }
 
-func decodeArray(bag *Bag, s, p *curie.IRI, seq []any) error {
+// decodes top-level object, which is either node object or @graph
+func decodeDocument(bag *Bag, ctx *context, val map[string]any) error {
+	ctx, err := ctx.scoped(val)
+	if err != nil {
+		return err
+	}
+
+	graph, has := lookup(ctx, val, "@graph")
+	if has {
+		switch seq := graph.(type) {
+		case []any:
+			return decodeArray(bag, ctx, nil, nil, seq)
+		default:
+			return fmt.Errorf("json-ld graph codec do not support %T (%v)", val, val)
+		}
+	}
+	return decodeObject(bag, ctx, nil, nil, val)
+}
+
+// xsd value of scalar, the flag is false if value is not scalar (see
+// codec.Literal).
+func (ctx *context) literal(val any) (xsd.Value, bool) {
+	return codec.Literal(val, ctx.datetime)
+}
+
+// lookup value of keyword or its alias defined by context
+func lookup(ctx *context, obj map[string]any, keyword string) (any, bool) {
+	if val, has := obj[keyword]; has {
+		return val, true
+	}
+
+	for key, val := range obj {
+		if ctx.keyword(key) == keyword {
+			return val, true
+		}
+	}
+
+	return nil, false
+}
+
+// keys of object in deterministic order
+func sortedKeys(obj map[string]any) []string {
+	seq := make([]string, 0, len(obj))
+	for key := range obj {
+		seq = append(seq, key)
+	}
+	sort.Strings(seq)
+	return seq
+}
+
+func decodeArray(bag *Bag, ctx *context, s, p *curie.IRI, seq []any) error {
 	for _, val := range seq {
-		switch o := val.(type) {
-		case float64:
-			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
-			}
-		case string:
-			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
-			}
-		case bool:
+		if lit, ok := ctx.literal(val); ok {
 			if s != nil && p != nil {
-				*bag = append(*bag, spock.From(*s, *p, o))
+				*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(*s), P: xsd.ToAnyURI(*p), O: lit})
 			}
+			continue
+		}
+
+		switch o := val.(type) {
 		case map[string]any:
-			decodeObject(bag, s, p, o)
+			if err := decodeObject(bag, ctx, s, p, o); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("json-ld array codec do not support %T (%v)", val, val)
 		}
@@ -81,48 +135,63 @@ func decodeArray(bag *Bag, s, p *curie.IRI, seq []any) error {
 	return nil
 }
 
-func decodeObject(bag *Bag, s, p *curie.IRI, obj map[string]any) error {
-	uid, has := decodeObjectID(obj)
+func decodeObject(bag *Bag, ctx *context, s, p *curie.IRI, obj map[string]any) error {
+	ctx, err := ctx.scoped(obj)
+	if err != nil {
+		return err
+	}
+
+	uid, has := decodeObjectID(ctx, obj)
 	if !has {
-		uid = curie.New("_:%s", guid.L(guid.Clock))
+		uid = mintObject(ctx, s, p, obj)
 	}
 
 	if s != nil && p != nil {
 		*bag = append(*bag, spock.From(*s, *p, uid))
 	}
 
-	isa, has := decodeObjectType(obj)
-	if has {
-		*bag = append(*bag, spock.From(uid, curie.IRI("rdf:type"), isa))
-	}
-
-	return decodeObjectProperties(bag, uid, obj)
+	return decodeObjectBody(bag, ctx, uid, obj)
 }
 
-func decodeObjectID(obj map[string]any) (curie.IRI, bool) {
-	raw, has := obj["@id"]
-	if !has {
-		return "", false
+// decodes node object that is subject of reverse property p of node o
+func decodeReverseObject(bag *Bag, ctx *context, o, p curie.IRI, obj map[string]any) error {
+	ctx, err := ctx.scoped(obj)
+	if err != nil {
+		return err
 	}
 
-	id, ok := raw.(string)
-	if !ok {
-		return "", false
+	uid, has := decodeObjectID(ctx, obj)
+	if !has {
+		uid = ctx.blank(o, p, obj)
 	}
 
-	return curie.IRI(id), true
+	*bag = append(*bag, spock.From(uid, p, o))
+
+	return decodeObjectBody(bag, ctx, uid, obj)
 }
 
-func decodeObjectType(obj map[string]any) (curie.IRI, bool) {
-	if iri, has := decodeObjectTypeFrom("@type", obj); has {
-		return iri, true
+func decodeObjectBody(bag *Bag, ctx *context, uid curie.IRI, obj map[string]any) error {
+	isa, err := decodeObjectType(ctx, obj)
+	if err != nil {
+		return err
+	}
+	for _, t := range isa {
+		*bag = append(*bag, spock.From(uid, curie.IRI("rdf:type"), t))
 	}
 
-	return decodeObjectTypeFrom("rdf:type", obj)
+	return decodeObjectProperties(bag, ctx, uid, obj)
+}
+
+// mints blank node of the object referred by s and p
+func mintObject(ctx *context, s, p *curie.IRI, obj map[string]any) curie.IRI {
+	if s != nil && p != nil {
+		return ctx.blank(*s, *p, obj)
+	}
+	return ctx.blank("", "", obj)
 }
 
-func decodeObjectTypeFrom(prop string, obj map[string]any) (curie.IRI, bool) {
-	raw, has := obj[prop]
+func decodeObjectID(ctx *context, obj map[string]any) (curie.IRI, bool) {
+	raw, has := lookup(ctx, obj, "@id")
 	if !has {
 		return "", false
 	}
@@ -132,29 +201,99 @@ func decodeObjectTypeFrom(prop string, obj map[string]any) (curie.IRI, bool) {
 		return "", false
 	}
 
-	return curie.IRI(id), true
+	return ctx.curie(id, false), true
 }
 
-func decodeObjectProperties(bag *Bag, s curie.IRI, obj map[string]any) error {
-	for key, val := range obj {
-		if key == "@id" || key == "@type" || key == "rdf:type" {
+func decodeObjectType(ctx *context, obj map[string]any) ([]curie.IRI, error) {
+	if raw, has := lookup(ctx, obj, "@type"); has {
+		return decodeObjectTypeFrom(ctx, raw)
+	}
+
+	if raw, has := obj["rdf:type"]; has {
+		return decodeObjectTypeFrom(ctx, raw)
+	}
+
+	return nil, nil
+}
+
+func decodeObjectTypeFrom(ctx *context, raw any) ([]curie.IRI, error) {
+	switch v := raw.(type) {
+	case string:
+		return []curie.IRI{ctx.curie(v, true)}, nil
+	case []any:
+		seq := make([]curie.IRI, 0, len(v))
+		for _, x := range v {
+			id, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("json-ld type codec do not support %T (%v)", x, x)
+			}
+			seq = append(seq, ctx.curie(id, true))
+		}
+		return seq, nil
+	default:
+		return nil, fmt.Errorf("json-ld type codec do not support %T (%v)", raw, raw)
+	}
+}
+
+func decodeObjectProperties(bag *Bag, ctx *context, s curie.IRI, obj map[string]any) error {
+	for _, key := range sortedKeys(obj) {
+		switch ctx.keyword(key) {
+		case "":
+			if key == "rdf:type" {
+				continue
+			}
+		case "@reverse":
+			if err := decodeReverse(bag, ctx, s, obj[key]); err != nil {
+				return err
+			}
+			continue
+		case "@nest":
+			if err := decodeNest(bag, ctx, s, obj[key]); err != nil {
+				return err
+			}
+			continue
+		case "@included", "@graph":
+			if err := decodeIncluded(bag, ctx, obj[key]); err != nil {
+				return err
+			}
+			continue
+		default:
+			continue
+		}
+
+		def := ctx.term(key)
+		p := ctx.curie(key, true)
+		val := obj[key]
+
+		if def != nil && def.reverse {
+			if err := decodeReverseProperty(bag, ctx, def, s, p, val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if seq, ok := val.([]any); ok && def != nil && def.container == "@list" {
+			if err := decodeList(bag, ctx, def, s, p, seq); err != nil {
+				return err
+			}
 			continue
 		}
-		p := curie.IRI(key)
 
 		switch o := val.(type) {
-		case float64:
-			*bag = append(*bag, spock.From(s, p, o))
+		case json.Number, bool:
+			if err := decodeValue(bag, ctx, s, p, o); err != nil {
+				return err
+			}
 		case string:
-			*bag = append(*bag, spock.From(s, p, o))
-		case bool:
-			*bag = append(*bag, spock.From(s, p, o))
+			if err := decodeString(bag, ctx, def, s, p, o); err != nil {
+				return err
+			}
 		case map[string]any:
-			if err := decodeNodeObject(bag, s, p, o); err != nil {
+			if err := decodeNodeObject(bag, ctx, def, s, p, o); err != nil {
 				return err
 			}
 		case []any:
-			if err := decodeNodeArray(bag, s, p, o); err != nil {
+			if err := decodeNodeArray(bag, ctx, def, s, p, o); err != nil {
 				return err
 			}
 		default:
@@ -165,32 +304,192 @@ func decodeObjectProperties(bag *Bag, s curie.IRI, obj map[string]any) error {
 	return nil
 }
 
-func decodeNodeObject(bag *Bag, s, p curie.IRI, node map[string]any) error {
-	val, has := node["@value"]
-	if has {
-		return decodeValue(bag, s, p, val)
+// decodes string value according to type coercion of the term
+func decodeString(bag *Bag, ctx *context, def *term, s, p curie.IRI, o string) error {
+	switch {
+	case def != nil && def.coerce == "@id":
+		*bag = append(*bag, spock.From(s, p, ctx.curie(o, false)))
+	case def != nil && def.coerce == "@vocab":
+		*bag = append(*bag, spock.From(s, p, ctx.curie(o, true)))
+	case def != nil && def.coerce != "":
+		return decodeTypedValue(bag, ctx, s, p, o, def.coerce)
+	default:
+		lit, _ := ctx.literal(o)
+		*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: lit})
 	}
 
-	iri, has := decodeObjectID(node)
+	return nil
+}
+
+func decodeNodeObject(bag *Bag, ctx *context, def *term, s, p curie.IRI, node map[string]any) error {
+	val, has := lookup(ctx, node, "@value")
 	if has {
+		if datatype, has := lookup(ctx, node, "@type"); has {
+			kind, isKind := datatype.(string)
+			if isKind {
+				switch lit := val.(type) {
+				case string:
+					return decodeTypedValue(bag, ctx, s, p, lit, kind)
+				case json.Number:
+					return decodeTypedValue(bag, ctx, s, p, string(lit), kind)
+				case bool:
+					return decodeTypedValue(bag, ctx, s, p, strconv.FormatBool(lit), kind)
+				}
+			}
+
+			// the data type of non-scalar value is dropped
+			if ctx.strict {
+				return fmt.Errorf("json-ld typed value codec do not support %T (%v) of @type %v", val, val, datatype)
+			}
+		}
+
+		// Note: the library does not have data type for language-tagged
+		//       strings (rdf:langString), the tag is dropped unless decoder
+		//       is strict.
+		if lang, has := lookup(ctx, node, "@language"); has && ctx.strict {
+			return fmt.Errorf("json-ld value codec do not support @language %v, see WithStrictLiterals", lang)
+		}
+
+		return decodeValue(bag, ctx, s, p, val)
+	}
+
+	if val, has := lookup(ctx, node, "@list"); has {
+		seq, ok := val.([]any)
+		if !ok {
+			seq = []any{val}
+		}
+		return decodeList(bag, ctx, def, s, p, seq)
+	}
+
+	if val, has := lookup(ctx, node, "@set"); has {
+		seq, ok := val.([]any)
+		if !ok {
+			seq = []any{val}
+		}
+		return decodeNodeArray(bag, ctx, def, s, p, seq)
+	}
+
+	if iri, has := decodeObjectID(ctx, node); has && len(node) == 1 {
 		*bag = append(*bag, spock.From(s, p, iri))
 		return nil
 	}
 
-	return fmt.Errorf("json-ld node object codec do not support %T (%v)", node, node)
+	return decodeObject(bag, ctx, &s, &p, node)
+}
+
+// decodes ordered collection into rdf:first/rdf:rest chain
+func decodeList(bag *Bag, ctx *context, def *term, s, p curie.IRI, seq []any) error {
+	if len(seq) == 0 {
+		*bag = append(*bag, spock.From(s, p, rdfNil))
+		return nil
+	}
+
+	head := ctx.blank(s, p, seq)
+	*bag = append(*bag, spock.From(s, p, head))
+
+	for i, val := range seq {
+		if err := decodeNodeArray(bag, ctx, def, head, rdfFirst, []any{val}); err != nil {
+			return err
+		}
+
+		if i == len(seq)-1 {
+			*bag = append(*bag, spock.From(head, rdfRest, rdfNil))
+			break
+		}
+
+		next := ctx.blank(head, rdfRest, seq[i+1:])
+		*bag = append(*bag, spock.From(head, rdfRest, next))
+		head = next
+	}
+
+	return nil
+}
+
+// decodes @reverse map, properties of the map are flipped
+func decodeReverse(bag *Bag, ctx *context, o curie.IRI, val any) error {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return fmt.Errorf("json-ld reverse codec do not support %T (%v)", val, val)
+	}
+
+	for _, key := range sortedKeys(obj) {
+		def := ctx.term(key)
+		if err := decodeReverseProperty(bag, ctx, def, o, ctx.curie(key, true), obj[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodes values of reverse property p, each value is the subject
+func decodeReverseProperty(bag *Bag, ctx *context, def *term, o, p curie.IRI, val any) error {
+	switch v := val.(type) {
+	case string:
+		if def == nil || def.coerce != "@id" {
+			return fmt.Errorf("json-ld reverse property %s do not support literal (%v)", p, v)
+		}
+		*bag = append(*bag, spock.From(ctx.curie(v, false), p, o))
+	case map[string]any:
+		return decodeReverseObject(bag, ctx, o, p, v)
+	case []any:
+		for _, x := range v {
+			if err := decodeReverseProperty(bag, ctx, def, o, p, x); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("json-ld reverse property %s do not support %T (%v)", p, val, val)
+	}
+
+	return nil
+}
+
+// decodes @nest, properties of nested object belongs to enclosing node
+func decodeNest(bag *Bag, ctx *context, s curie.IRI, val any) error {
+	switch v := val.(type) {
+	case map[string]any:
+		return decodeObjectProperties(bag, ctx, s, v)
+	case []any:
+		for _, x := range v {
+			if err := decodeNest(bag, ctx, s, x); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("json-ld nest codec do not support %T (%v)", val, val)
+	}
+}
+
+// decodes @included (or nested @graph), node objects are not linked to the
+// enclosing node
+func decodeIncluded(bag *Bag, ctx *context, val any) error {
+	switch v := val.(type) {
+	case map[string]any:
+		return decodeObject(bag, ctx, nil, nil, v)
+	case []any:
+		return decodeArray(bag, ctx, nil, nil, v)
+	default:
+		return fmt.Errorf("json-ld included codec do not support %T (%v)", val, val)
+	}
 }
 
-func decodeNodeArray(bag *Bag, s, p curie.IRI, array []any) error {
+func decodeNodeArray(bag *Bag, ctx *context, def *term, s, p curie.IRI, array []any) error {
 	for _, val := range array {
 		switch o := val.(type) {
-		case float64:
-			*bag = append(*bag, spock.From(s, p, o))
+		case json.Number, bool:
+			if err := decodeValue(bag, ctx, s, p, o); err != nil {
+				return err
+			}
 		case string:
-			*bag = append(*bag, spock.From(s, p, o))
-		case bool:
-			*bag = append(*bag, spock.From(s, p, o))
+			if err := decodeString(bag, ctx, def, s, p, o); err != nil {
+				return err
+			}
 		case map[string]any:
-			decodeNodeObject(bag, s, p, o)
+			if err := decodeNodeObject(bag, ctx, def, s, p, o); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("json-ld node array codec do not support %T (%v)", val, val)
 		}
@@ -199,17 +498,42 @@ func decodeNodeArray(bag *Bag, s, p curie.IRI, array []any) error {
 	return nil
 }
 
-func decodeValue(bag *Bag, s, p curie.IRI, val any) error {
-	switch o := val.(type) {
-	case float64:
-		*bag = append(*bag, spock.From(s, p, o))
-	case string:
-		*bag = append(*bag, spock.From(s, p, o))
-	case bool:
-		*bag = append(*bag, spock.From(s, p, o))
-	default:
+func decodeValue(bag *Bag, ctx *context, s, p curie.IRI, val any) error {
+	lit, ok := ctx.literal(val)
+	if !ok {
 		return fmt.Errorf("json-ld value codec do not support %T (%v)", val, val)
 	}
 
+	*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: lit})
+	return nil
+}
+
+// decodes literal of the datatype. The datatype is expanded through active
+// context and mapped to corresponding xsd data type (see codec.Typed).
+// Literals of other data types, and literals with invalid lexical form, are
+// decoded as xsd:string, the data type is dropped unless decoder is strict
+// (see WithStrictLiterals).
+func decodeTypedValue(bag *Bag, ctx *context, s, p curie.IRI, lit string, datatype string) error {
+	var o xsd.Value
+
+	switch kind := ctx.datatype(datatype); kind {
+	case "@id", xsdAnyURI:
+		o = xsd.ToAnyURI(ctx.curie(lit, false))
+	case "@vocab":
+		o = xsd.ToAnyURI(ctx.curie(lit, true))
+	case xsdString:
+		o = xsd.String(lit)
+	default:
+		if val, ok := codec.Typed(lit, kind); ok {
+			o = val
+			break
+		}
+		if ctx.strict {
+			return fmt.Errorf("json-ld typed value codec do not support %q of @type %s, see WithStrictLiterals", lit, datatype)
+		}
+		o = xsd.String(lit)
+	}
+
+	*bag = append(*bag, spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: o})
 	return nil
 }
@@ -21,8 +21,12 @@
 package jsonld_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fogfish/curie"
 	"github.com/fogfish/guid/v2"
@@ -65,35 +69,35 @@ func TestJsonLdUnmarshal(t *testing.T) {
 		)
 	})
 
-	// t.Run("PropertyInt", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": 10
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 10),
-	// 		),
-	// 	)
-	// })
-
-	// t.Run("PropertyFloat", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": 10.0
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 10.0),
-	// 		),
-	// 	)
-	// })
-
-	// t.Run("PropertyBool", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": true
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", true),
-	// 		),
-	// 	)
-	// })
+	t.Run("PropertyInt", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": 10
+			}`).Equal(
+				spock.From(luid, "prop", 10),
+			),
+		)
+	})
+
+	t.Run("PropertyFloat", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": 10.0
+			}`).Equal(
+				spock.From(luid, "prop", 10.0),
+			),
+		)
+	})
+
+	t.Run("PropertyBool", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": true
+			}`).Equal(
+				spock.From(luid, "prop", true),
+			),
+		)
+	})
 
 	t.Run("PropertyArray", func(t *testing.T) {
 		it.Then(t).Should(
@@ -107,17 +111,17 @@ func TestJsonLdUnmarshal(t *testing.T) {
 		)
 	})
 
-	// t.Run("PropertyArrayHeterogenous", func(t *testing.T) {
-	// 	it.Then(t).Should(
-	// 		Codec(t, `{
-	// 			"prop": [1, "b", true]
-	// 		}`).Equal(
-	// 			spock.From(luid, "prop", 1),
-	// 			spock.From(luid, "prop", "b"),
-	// 			spock.From(luid, "prop", true),
-	// 		),
-	// 	)
-	// })
+	t.Run("PropertyArrayHeterogenous", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"prop": [1, "b", true]
+			}`).Equal(
+				spock.From(luid, "prop", 1),
+				spock.From(luid, "prop", "b"),
+				spock.From(luid, "prop", true),
+			),
+		)
+	})
 
 	t.Run("ArrayOfObjects", func(t *testing.T) {
 		it.Then(t).Should(
@@ -157,4 +161,660 @@ func TestJsonLdUnmarshal(t *testing.T) {
 			),
 		)
 	})
+
+	t.Run("ContextPrefix", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {"schema": "https://schema.org/"},
+				"@id": "https://schema.org/a",
+				"schema:name": "A"
+			}`).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+			),
+		)
+	})
+
+	t.Run("ContextTerm", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {
+					"schema": "https://schema.org/",
+					"name": "schema:name",
+					"knows": {"@id": "schema:knows", "@type": "@id"}
+				},
+				"@id": "schema:a",
+				"knows": "schema:b",
+				"name": "A"
+			}`).Equal(
+				spock.From("schema:a", "schema:knows", curie.IRI("schema:b")),
+				spock.From("schema:a", "schema:name", "A"),
+			),
+		)
+	})
+
+	t.Run("ContextVocab", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {
+					"@vocab": "https://schema.org/",
+					"schema": "https://schema.org/"
+				},
+				"@id": "a",
+				"@type": "Person",
+				"name": "A"
+			}`).Equal(
+				spock.From("a", "rdf:type", curie.IRI("schema:Person")),
+				spock.From("a", "schema:name", "A"),
+			),
+		)
+	})
+
+	t.Run("ContextBase", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {
+					"@base": "https://example.com/",
+					"ex": "https://example.com/"
+				},
+				"@id": "a",
+				"prop": {"@id": "b"}
+			}`).Equal(
+				spock.From("ex:a", "prop", curie.IRI("ex:b")),
+			),
+		)
+	})
+
+	t.Run("ContextArray", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": [
+					{"schema": "https://schema.org/"},
+					{"name": "schema:name", "id": "@id"}
+				],
+				"@graph": [
+					{"id": "schema:a", "name": "A"}
+				]
+			}`).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+			),
+		)
+	})
+
+	t.Run("TypeArray", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"@type": ["schema:Person", "foaf:Person"]
+			}`).Equal(
+				spock.From("a", "rdf:type", curie.IRI("schema:Person")),
+				spock.From("a", "rdf:type", curie.IRI("foaf:Person")),
+			),
+		)
+	})
+
+	t.Run("TypedValue", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {"xsd": "http://www.w3.org/2001/XMLSchema#"},
+				"@id": "a",
+				"created": {"@value": "2023-01-01T00:00:00Z", "@type": "xsd:dateTime"},
+				"homepage": {"@value": "https://example.com", "@type": "xsd:anyURI"},
+				"title": {"@value": "title", "@type": "xsd:string"}
+			}`).Equal(
+				spock.From("a", "created", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+				spock.From("a", "homepage", curie.IRI("https://example.com")),
+				spock.From("a", "title", "title"),
+			),
+		)
+	})
+
+	t.Run("TypedTerm", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {
+					"homepage": {"@type": "xsd:anyURI"}
+				},
+				"@id": "a",
+				"homepage": "https://example.com"
+			}`).Equal(
+				spock.From("a", "homepage", curie.IRI("https://example.com")),
+			),
+		)
+	})
+
+	t.Run("LanguageValue", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"title": {"@value": "otsikko", "@language": "fi"}
+			}`).Equal(
+				spock.From("a", "title", "otsikko"),
+			),
+		)
+	})
+
+	t.Run("EmbeddedNode", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"prop": {"@id": "b", "title": "title"}
+			}`).Equal(
+				spock.From("a", "prop", curie.IRI("b")),
+				spock.From("b", "title", "title"),
+			),
+		)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"prop": {"@list": ["x", {"@id": "b"}]}
+			}`).Equal(
+				spock.From("a", "prop", luid),
+				spock.From(luid, "rdf:first", "x"),
+				spock.From(luid, "rdf:rest", luid),
+				spock.From(luid, "rdf:first", curie.IRI("b")),
+				spock.From(luid, "rdf:rest", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("ListEmpty", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"prop": {"@list": []}
+			}`).Equal(
+				spock.From("a", "prop", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("ListContainer", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {"prop": {"@container": "@list"}},
+				"@id": "a",
+				"prop": ["x"]
+			}`).Equal(
+				spock.From("a", "prop", luid),
+				spock.From(luid, "rdf:first", "x"),
+				spock.From(luid, "rdf:rest", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"prop": {"@set": ["x", "y"]}
+			}`).Equal(
+				spock.From("a", "prop", "x"),
+				spock.From("a", "prop", "y"),
+			),
+		)
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"@reverse": {
+					"knows": [{"@id": "b"}, {"@id": "c", "title": "title"}]
+				}
+			}`).Equal(
+				spock.From("b", "knows", curie.IRI("a")),
+				spock.From("c", "knows", curie.IRI("a")),
+				spock.From("c", "title", "title"),
+			),
+		)
+	})
+
+	t.Run("ReverseTerm", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {
+					"knownBy": {"@reverse": "knows", "@type": "@id"}
+				},
+				"@id": "a",
+				"knownBy": "b"
+			}`).Equal(
+				spock.From("b", "knows", curie.IRI("a")),
+			),
+		)
+	})
+
+	t.Run("Nest", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@context": {"labels": "@nest"},
+				"@id": "a",
+				"labels": {"title": "title"}
+			}`).Equal(
+				spock.From("a", "title", "title"),
+			),
+		)
+	})
+
+	t.Run("Included", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `{
+				"@id": "a",
+				"@included": [{"@id": "b", "title": "title"}],
+				"title": "title"
+			}`).Equal(
+				spock.From("b", "title", "title"),
+				spock.From("a", "title", "title"),
+			),
+		)
+	})
+
+	t.Run("ContextRemote", func(t *testing.T) {
+		bag := jsonld.Bag{}
+		err := json.Unmarshal([]byte(`{
+			"@context": ["https://schema.org/", {"@vocab": "https://example.com/"}],
+			"@id": "a",
+			"name": "A"
+		}`), &bag)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "https://example.com/name", "A"),
+			),
+		)
+	})
+
+	t.Run("ContextLoader", func(t *testing.T) {
+		loader := func(iri string) (any, error) {
+			if iri != "https://schema.org/" {
+				return nil, fmt.Errorf("not found %s", iri)
+			}
+			return map[string]any{
+				"@context": map[string]any{"name": "https://schema.org/name"},
+			}, nil
+		}
+
+		doc := `{"@context": "https://schema.org/", "@id": "a", "name": "A"}`
+		bag := spock.Bag{}
+		dec := jsonld.NewDecoder(strings.NewReader(doc), jsonld.WithContextLoader(loader))
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "https://schema.org/name", "A"),
+			),
+		)
+
+		doc = `{"@context": "https://example.com/", "@id": "a", "name": "A"}`
+		dec = jsonld.NewDecoder(strings.NewReader(doc), jsonld.WithContextLoader(loader))
+		it.Then(t).ShouldNot(
+			it.Nil(dec.FMap(bag.Join)),
+		)
+	})
+}
+
+func TestJsonLdMarshal(t *testing.T) {
+	Codec := func(t *testing.T, doc any, err error) string {
+		t.Helper()
+		it.Then(t).Should(it.Nil(err))
+
+		b, err := json.Marshal(doc)
+		it.Then(t).Should(it.Nil(err))
+
+		return string(b)
+	}
+
+	bag := spock.Bag{
+		spock.From("schema:a", "rdf:type", curie.IRI("schema:Person")),
+		spock.From("schema:a", "schema:name", "A"),
+		spock.From("schema:a", "schema:knows", curie.IRI("schema:b")),
+		spock.From("schema:b", "rdf:type", curie.IRI("schema:Person")),
+		spock.From("schema:b", "schema:name", "B"),
+		spock.From("schema:b", "schema:address", curie.IRI("_:c")),
+		spock.From("_:c", "schema:city", "Helsinki"),
+	}
+
+	context := map[string]any{
+		"schema": "https://schema.org/",
+		"@vocab": "https://schema.org/",
+		"knows":  map[string]any{"@type": "@id"},
+	}
+
+	t.Run("MarshalJSON", func(t *testing.T) {
+		b, err := json.Marshal(jsonld.Bag{
+			spock.From("a", "rdf:type", curie.IRI("T")),
+			spock.From("a", "prop", "title"),
+		})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(string(b), `{"@id":"a","@type":"T","prop":"title"}`),
+		)
+	})
+
+	t.Run("Expand", func(t *testing.T) {
+		doc, err := jsonld.Expand(bag[5:],
+			jsonld.WithPrefixes(curie.Namespaces{"schema": "https://schema.org/"}),
+		)
+
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, doc, err),
+				`[{"@id":"https://schema.org/b","https://schema.org/address":[{"https://schema.org/city":[{"@value":"Helsinki"}]}]}]`,
+			),
+		)
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		doc, err := jsonld.Compact(bag, context)
+
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, doc, err),
+				`{"@context":{"@vocab":"https://schema.org/","knows":{"@type":"@id"},"schema":"https://schema.org/"},`+
+					`"@graph":[`+
+					`{"@id":"schema:a","@type":"Person","knows":"schema:b","name":"A"},`+
+					`{"@id":"schema:b","@type":"Person","address":{"city":"Helsinki"},"name":"B"}`+
+					`]}`,
+			),
+		)
+	})
+
+	t.Run("CompactTyped", func(t *testing.T) {
+		doc, err := jsonld.Compact(
+			spock.Bag{
+				spock.From("a", "n", 10),
+				spock.From("a", "pi", 1.5),
+				spock.From("a", "ok", true),
+				spock.From("a", "at", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+			},
+			map[string]any{
+				"xsd": "http://www.w3.org/2001/XMLSchema#",
+				"at":  map[string]any{"@type": "xsd:dateTime"},
+			},
+		)
+
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, doc, err),
+				`{"@context":{"at":{"@type":"xsd:dateTime"},"xsd":"http://www.w3.org/2001/XMLSchema#"},`+
+					`"@id":"a","at":"2023-01-02T03:04:05Z","n":10,"ok":true,"pi":{"@type":"xsd:double","@value":"1.5"}}`,
+			),
+		)
+
+		bag := spock.Bag{}
+		b, _ := json.Marshal(doc)
+		it.Then(t).Should(
+			it.Nil(jsonld.NewDecoder(bytes.NewReader(b)).FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "at", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+				spock.From("a", "n", 10),
+				spock.From("a", "ok", true),
+				spock.From("a", "pi", 1.5),
+			),
+		)
+	})
+
+	t.Run("Frame", func(t *testing.T) {
+		doc, err := jsonld.Frame(bag, map[string]any{
+			"@context": context,
+			"@id":      "schema:a",
+		})
+
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, doc, err),
+				`{"@context":{"@vocab":"https://schema.org/","knows":{"@type":"@id"},"schema":"https://schema.org/"},`+
+					`"@id":"schema:a","@type":"Person",`+
+					`"knows":{"@id":"schema:b","@type":"Person","address":{"city":"Helsinki"},"name":"B"},`+
+					`"name":"A"}`,
+			),
+		)
+	})
+
+	t.Run("Encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		seq := jsonld.Bag{}
+
+		err := jsonld.NewEncoder(&buf).Encode(
+			spock.NewFilter(func(spock.SPOCK) bool { return true }, bag[1:3].Stream()),
+		)
+		it.Then(t).Should(it.Nil(err))
+
+		err = json.Unmarshal(buf.Bytes(), &seq)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 2),
+		)
+	})
+}
+
+func TestJsonLdDecoder(t *testing.T) {
+	t.Run("Graph", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@context": {"schema": "https://schema.org/", "items": "@graph"},
+			"items": [
+				{"@id": "https://schema.org/a", "https://schema.org/name": "A"},
+				{"@id": "https://schema.org/b", "https://schema.org/name": "B"}
+			]
+		}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+				spock.From("schema:b", "schema:name", "B"),
+			),
+		)
+	})
+
+	t.Run("Object", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@id": "a",
+			"@context": {"name": "https://schema.org/name"},
+			"name": "A"
+		}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "https://schema.org/name", "A"),
+			),
+		)
+	})
+
+	t.Run("Incremental", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@context": {},
+			"@graph": [
+				{"@id": "a", "prop": "title"},
+				{"@id": "b", "prop": ???
+			]
+		}`))
+
+		it.Then(t).Should(
+			it.True(dec.Next()),
+			it.Equal(dec.Head(), spock.From("a", "prop", "title")),
+		).ShouldNot(
+			it.True(dec.Next()),
+			it.Nil(dec.Err()),
+		)
+	})
+
+	t.Run("ContextAfterGraph", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@graph": [{"@id": "a", "prop": "title"}],
+			"@context": {"prop": "https://schema.org/name"}
+		}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "https://schema.org/name", "title"),
+			),
+		)
+	})
+
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		var bag jsonld.Bag
+		err := json.Unmarshal([]byte(`{
+			"@graph": [{"@id": "a", "prop": "title"}],
+			"@context": {"prop": "https://schema.org/name"}
+		}`), &bag)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "https://schema.org/name", "title"),
+			),
+		)
+	})
+
+	t.Run("ContextAfterInvalidGraph", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@graph": [{"@id": "a", "prop": "title"}, ???],
+			"@context": {"prop": "https://schema.org/name"}
+		}`))
+
+		it.Then(t).ShouldNot(
+			it.True(dec.Next()),
+			it.Nil(dec.Err()),
+		)
+	})
+
+	t.Run("HashBlankNode", func(t *testing.T) {
+		decode := func() spock.Bag {
+			dec := jsonld.NewDecoder(strings.NewReader(`{"prop": "title", "nested": {"prop": "title"}}`),
+				jsonld.WithBlankNode(jsonld.HashBlankNode),
+			)
+			bag := spock.Bag{}
+			it.Then(t).Should(it.Nil(dec.FMap(bag.Join)))
+			return bag
+		}
+
+		a, b := decode(), decode()
+		it.Then(t).Should(
+			it.Equal(len(a), 3),
+			it.Seq(a).Equal(b...),
+			it.Equal(a[0].S, a[2].S),
+		).ShouldNot(
+			it.Equal(a[0].S, a[1].S),
+		)
+	})
+
+	t.Run("SeqBlankNode", func(t *testing.T) {
+		seq := 0
+		dec := jsonld.NewDecoder(strings.NewReader(`{"prop": "title", "nested": {"prop": "title"}}`),
+			jsonld.WithBlankNode(jsonld.SeqBlankNode(func() string {
+				seq++
+				return fmt.Sprintf("b%d", seq)
+			})),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("_:b1", "nested", curie.IRI("_:b2")),
+				spock.From("_:b2", "prop", "title"),
+				spock.From("_:b1", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Number", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{"@id": "a", "big": 12345678901234567890, "list": [1.50, false]}`))
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "big", "12345678901234567890"),
+				spock.From("a", "list", 1.5),
+				spock.From("a", "list", false),
+			),
+		)
+	})
+
+	t.Run("DateTime", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@context": {"xsd": "http://www.w3.org/2001/XMLSchema#"},
+			"@id": "a",
+			"at": "2023-01-02T03:04:05.5-01:00",
+			"on": {"@value": "2023-01-02T03:04:05Z", "@type": "xsd:dateTime"}
+		}`), jsonld.WithDateTime())
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "at", time.Date(2023, 1, 2, 4, 4, 5, 5e8, time.UTC)),
+				spock.From("a", "on", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+			),
+		)
+	})
+
+	t.Run("TypedValueIRI", func(t *testing.T) {
+		dec := jsonld.NewDecoder(strings.NewReader(`{
+			"@context": {"x": "http://www.w3.org/2001/XMLSchema#"},
+			"@id": "a",
+			"on": {"@value": "2023-01-02T03:04:05Z", "@type": "http://www.w3.org/2001/XMLSchema#dateTime"},
+			"at": {"@value": "2023-01-02", "@type": "x:date"},
+			"by": {"@value": "2023-01-02T03:04:05+01:00", "@type": "xsd:dateTime"},
+			"to": {"@value": "https://example.com", "@type": "http://www.w3.org/2001/XMLSchema#anyURI"}
+		}`), jsonld.WithDateTime())
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("a", "at", time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+				spock.From("a", "by", time.Date(2023, 1, 2, 2, 4, 5, 0, time.UTC)),
+				spock.From("a", "on", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+				spock.From("a", "to", curie.IRI("https://example.com")),
+			),
+		)
+	})
+
+	t.Run("TypedNumber", func(t *testing.T) {
+		for _, doc := range []string{
+			`{"@id": "a", "n": {"@value": "1", "@type": "http://www.w3.org/2001/XMLSchema#integer"}}`,
+			`{"@id": "a", "n": {"@value": "1", "@type": "xsd:integer"}}`,
+			`{"@context": {"n": {"@type": "xsd:integer"}}, "@id": "a", "n": "1"}`,
+			`{"@id": "a", "n": {"@value": 1, "@type": "xsd:integer"}}`,
+			`{"@id": "a", "n": 1}`,
+		} {
+			bag := spock.Bag{}
+			err := jsonld.NewDecoder(strings.NewReader(doc), jsonld.WithStrictLiterals()).FMap(bag.Join)
+			it.Then(t).Should(
+				it.Nil(err),
+				it.Seq(bag).Equal(spock.From("a", "n", 1)),
+			)
+		}
+	})
+
+	t.Run("NotSupportedLiteral", func(t *testing.T) {
+		for _, doc := range []string{
+			`{"@id": "a", "n": {"@value": "1", "@type": "xsd:gYear"}}`,
+			`{"@id": "a", "n": {"@value": "1", "@type": "http://example.com/type"}}`,
+			`{"@id": "a", "n": {"@value": "1", "@language": "fi"}}`,
+		} {
+			bag := spock.Bag{}
+			err := jsonld.NewDecoder(strings.NewReader(doc)).FMap(bag.Join)
+			it.Then(t).Should(
+				it.Nil(err),
+				it.Seq(bag).Equal(spock.From("a", "n", "1")),
+			)
+
+			bag = spock.Bag{}
+			err = jsonld.NewDecoder(strings.NewReader(doc), jsonld.WithStrictLiterals()).FMap(bag.Join)
+			it.Then(t).ShouldNot(
+				it.Nil(err),
+			)
+		}
+	})
 }
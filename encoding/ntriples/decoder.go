@@ -0,0 +1,422 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package ntriples
+
+//
+// The file define streaming reader of N-Triples and N-Quads documents.
+// See https://www.w3.org/TR/n-triples/ and https://www.w3.org/TR/n-quads/
+//
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+// Decoder reads knowledge statements from N-Triples or N-Quads document,
+// one statement per line. Decoder implements spock.Stream.
+type Decoder struct {
+	r        *bufio.Reader
+	prefixes curie.Prefixes
+	line     int
+	head     spock.SPOCK
+	graph    curie.IRI
+	err      error
+}
+
+var _ spock.Stream = (*Decoder)(nil)
+
+// Create new decoder that reads from r. Absolute IRIs are compacted to
+// CURIEs if prefixes are given.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	cfg := newConfig(opts)
+
+	return &Decoder{
+		r:        bufio.NewReader(r),
+		prefixes: cfg.prefixes,
+	}
+}
+
+// Head returns current statement
+func (dec *Decoder) Head() spock.SPOCK { return dec.head }
+
+// Graph returns graph of current statement, empty IRI is default graph
+func (dec *Decoder) Graph() curie.IRI { return dec.graph }
+
+// Err returns the error (if any) that terminated the stream
+func (dec *Decoder) Err() error { return dec.err }
+
+// Next reads next statement from input
+func (dec *Decoder) Next() bool {
+	if dec.err != nil {
+		return false
+	}
+
+	for {
+		line, err := dec.r.ReadString('\n')
+		if len(line) == 0 && err != nil {
+			if err != io.EOF {
+				dec.err = err
+			}
+			return false
+		}
+		dec.line++
+
+		lex := &lexer{dec: dec, line: dec.line, s: line}
+		lex.skipSpace()
+		if lex.eol() {
+			continue
+		}
+
+		if err := lex.statement(); err != nil {
+			dec.err = err
+			return false
+		}
+
+		return true
+	}
+}
+
+// FMap applies f to each statement, it returns the first error either from
+// f or decoder.
+func (dec *Decoder) FMap(f func(spock.SPOCK) error) error {
+	for dec.Next() {
+		if err := f(dec.Head()); err != nil {
+			return err
+		}
+	}
+	return dec.err
+}
+
+// Quads reads document and groups statements by graph, the default graph
+// is identified by empty IRI.
+func Quads(r io.Reader, opts ...Option) (map[curie.IRI]spock.Bag, error) {
+	dec := NewDecoder(r, opts...)
+	quads := make(map[curie.IRI]spock.Bag)
+
+	for dec.Next() {
+		quads[dec.Graph()] = append(quads[dec.Graph()], dec.Head())
+	}
+
+	return quads, dec.Err()
+}
+
+//------------------------------------------------------------------------------
+
+type lexer struct {
+	dec  *Decoder
+	line int
+	s    string
+	at   int
+}
+
+func (lex *lexer) errorf(format string, args ...any) error {
+	return fmt.Errorf("ntriples line %d:%d: %s", lex.line, lex.at+1, fmt.Sprintf(format, args...))
+}
+
+func (lex *lexer) eol() bool {
+	return lex.at >= len(lex.s) || lex.s[lex.at] == '#'
+}
+
+func (lex *lexer) skipSpace() {
+	for lex.at < len(lex.s) {
+		switch lex.s[lex.at] {
+		case ' ', '\t', '\r', '\n':
+			lex.at++
+		default:
+			return
+		}
+	}
+}
+
+func (lex *lexer) statement() error {
+	s, err := lex.subject()
+	if err != nil {
+		return err
+	}
+
+	lex.skipSpace()
+	p, err := lex.iri()
+	if err != nil {
+		return err
+	}
+
+	lex.skipSpace()
+	o, err := lex.object()
+	if err != nil {
+		return err
+	}
+
+	lex.skipSpace()
+	graph := curie.IRI("")
+	if lex.at < len(lex.s) && (lex.s[lex.at] == '<' || lex.s[lex.at] == '_') {
+		if graph, err = lex.subject(); err != nil {
+			return err
+		}
+		lex.skipSpace()
+	}
+
+	if lex.at >= len(lex.s) || lex.s[lex.at] != '.' {
+		return lex.errorf("expected '.'")
+	}
+	lex.at++
+
+	lex.skipSpace()
+	if !lex.eol() {
+		return lex.errorf("unexpected %q", lex.s[lex.at:])
+	}
+
+	lex.dec.head = spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: o}
+	lex.dec.graph = graph
+
+	return nil
+}
+
+func (lex *lexer) subject() (curie.IRI, error) {
+	if strings.HasPrefix(lex.s[lex.at:], "_:") {
+		return lex.blank()
+	}
+
+	return lex.iri()
+}
+
+func (lex *lexer) object() (xsd.Value, error) {
+	if lex.at >= len(lex.s) {
+		return nil, lex.errorf("expected object")
+	}
+
+	switch lex.s[lex.at] {
+	case '"':
+		return lex.literal()
+	case '_':
+		iri, err := lex.blank()
+		if err != nil {
+			return nil, err
+		}
+		return xsd.ToAnyURI(iri), nil
+	default:
+		iri, err := lex.iri()
+		if err != nil {
+			return nil, err
+		}
+		return xsd.ToAnyURI(iri), nil
+	}
+}
+
+func (lex *lexer) iri() (curie.IRI, error) {
+	if lex.at >= len(lex.s) || lex.s[lex.at] != '<' {
+		return "", lex.errorf("expected IRI")
+	}
+	lex.at++
+
+	var sb strings.Builder
+	for {
+		if lex.at >= len(lex.s) {
+			return "", lex.errorf("unterminated IRI")
+		}
+
+		c := lex.s[lex.at]
+		switch {
+		case c == '>':
+			lex.at++
+			uri := sb.String()
+			if lex.dec.prefixes != nil {
+				return curie.FromURI(lex.dec.prefixes, uri), nil
+			}
+			return curie.IRI(uri), nil
+		case c == '\\':
+			r, err := lex.uchar()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case c <= 0x20 || strings.IndexByte(`<"{}|^`+"`", c) != -1:
+			return "", lex.errorf("invalid IRI character %q", c)
+		default:
+			sb.WriteByte(c)
+			lex.at++
+		}
+	}
+}
+
+func (lex *lexer) blank() (curie.IRI, error) {
+	if !strings.HasPrefix(lex.s[lex.at:], "_:") {
+		return "", lex.errorf("expected blank node")
+	}
+	lex.at += 2
+
+	start := lex.at
+	for lex.at < len(lex.s) {
+		r, size := utf8.DecodeRuneInString(lex.s[lex.at:])
+		if !isLabelRune(r, lex.at == start) {
+			break
+		}
+		lex.at += size
+	}
+
+	// blank node label do not end with '.'
+	for lex.at > start && lex.s[lex.at-1] == '.' {
+		lex.at--
+	}
+
+	if lex.at == start {
+		return "", lex.errorf("empty blank node label")
+	}
+
+	return curie.IRI("_:" + lex.s[start:lex.at]), nil
+}
+
+func (lex *lexer) literal() (xsd.Value, error) {
+	lex.at++
+
+	var sb strings.Builder
+	for {
+		if lex.at >= len(lex.s) {
+			return nil, lex.errorf("unterminated literal")
+		}
+
+		c := lex.s[lex.at]
+		switch c {
+		case '"':
+			lex.at++
+			return lex.literalType(sb.String())
+		case '\\':
+			if lex.at+1 >= len(lex.s) {
+				return nil, lex.errorf("invalid escape")
+			}
+			switch lex.s[lex.at+1] {
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'f':
+				sb.WriteByte('\f')
+			case '"':
+				sb.WriteByte('"')
+			case '\'':
+				sb.WriteByte('\'')
+			case '\\':
+				sb.WriteByte('\\')
+			case 'u', 'U':
+				r, err := lex.uchar()
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteRune(r)
+				continue
+			default:
+				return nil, lex.errorf("invalid escape %q", lex.s[lex.at+1])
+			}
+			lex.at += 2
+		case '\n', '\r':
+			return nil, lex.errorf("unterminated literal")
+		default:
+			sb.WriteByte(c)
+			lex.at++
+		}
+	}
+}
+
+// literal is either language tagged or typed, language tags are dropped.
+// Literals of data types not supported by codec.Typed are xsd:string.
+func (lex *lexer) literalType(lit string) (xsd.Value, error) {
+	switch {
+	case strings.HasPrefix(lex.s[lex.at:], "@"):
+		lex.at++
+		for lex.at < len(lex.s) {
+			c := lex.s[lex.at]
+			if !(c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')) {
+				break
+			}
+			lex.at++
+		}
+		return xsd.String(lit), nil
+	case strings.HasPrefix(lex.s[lex.at:], "^^"):
+		lex.at += 2
+		datatype, err := lex.iri()
+		if err != nil {
+			return nil, err
+		}
+		if datatype == xsdAnyURI || datatype == "xsd:anyURI" {
+			return xsd.ToAnyURI(curie.IRI(lit)), nil
+		}
+		if val, ok := codec.Typed(lit, string(datatype)); ok {
+			return val, nil
+		}
+		return xsd.String(lit), nil
+	default:
+		return xsd.String(lit), nil
+	}
+}
+
+// decodes \uXXXX or \UXXXXXXXX
+func (lex *lexer) uchar() (rune, error) {
+	if lex.at+1 >= len(lex.s) {
+		return 0, lex.errorf("invalid escape")
+	}
+
+	n := 0
+	switch lex.s[lex.at+1] {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return 0, lex.errorf("invalid escape %q", lex.s[lex.at+1])
+	}
+
+	if lex.at+2+n > len(lex.s) {
+		return 0, lex.errorf("invalid escape")
+	}
+
+	code, err := strconv.ParseUint(lex.s[lex.at+2:lex.at+2+n], 16, 32)
+	if err != nil {
+		return 0, lex.errorf("invalid escape %s", lex.s[lex.at:lex.at+2+n])
+	}
+	lex.at += 2 + n
+
+	return rune(code), nil
+}
+
+// PN_CHARS_U, digits and PN_CHARS of blank node label
+func isLabelRune(r rune, first bool) bool {
+	switch {
+	case r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9'):
+		return true
+	case !first && (r == '-' || r == '.' || r == 0xB7):
+		return true
+	case r >= 0xC0 && r != 0xD7 && r != 0xF7 && r != 0x37E && !(0x2000 <= r && r <= 0x206F):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package ntriples
+
+//
+// The file define writer of N-Triples and N-Quads documents.
+//
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+// Encoder writes knowledge statements as N-Triples, or N-Quads if graph is
+// configured.
+type Encoder struct {
+	w         *bufio.Writer
+	prefixes  curie.Prefixes
+	graph     curie.IRI
+	canonical bool
+}
+
+// Create new encoder that writes to w. CURIEs are expanded to absolute IRIs
+// if prefixes are given.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	cfg := newConfig(opts)
+
+	return &Encoder{
+		w:         bufio.NewWriter(w),
+		prefixes:  cfg.prefixes,
+		graph:     cfg.graph,
+		canonical: cfg.canonical,
+	}
+}
+
+// Encode consumes stream and writes statements to output
+func (enc *Encoder) Encode(stream spock.Stream) error {
+	if err := stream.FMap(enc.encode); err != nil {
+		return err
+	}
+
+	return enc.w.Flush()
+}
+
+// Put writes single statement to output
+func (enc *Encoder) Put(spock spock.SPOCK) error {
+	if err := enc.encode(spock); err != nil {
+		return err
+	}
+
+	return enc.w.Flush()
+}
+
+func (enc *Encoder) encode(spock spock.SPOCK) error {
+	var sb strings.Builder
+
+	enc.writeSubject(&sb, curie.IRI(spock.S.String()))
+	sb.WriteByte(' ')
+	enc.writeIRI(&sb, curie.IRI(spock.P.String()))
+	sb.WriteByte(' ')
+
+	switch o := spock.O.(type) {
+	case xsd.AnyURI:
+		enc.writeSubject(&sb, curie.IRI(o.String()))
+	case xsd.String:
+		enc.writeLiteral(&sb, string(o))
+	default:
+		lit, datatype, ok := codec.Lexical(o)
+		if !ok {
+			return fmt.Errorf("ntriples codec do not support %T (%v)", o, o)
+		}
+		enc.writeLiteral(&sb, lit)
+		sb.WriteString("^^<" + datatype + ">")
+	}
+
+	if enc.graph != "" {
+		sb.WriteByte(' ')
+		enc.writeSubject(&sb, enc.graph)
+	}
+
+	sb.WriteString(" .\n")
+
+	_, err := enc.w.WriteString(sb.String())
+	return err
+}
+
+func (enc *Encoder) writeLiteral(sb *strings.Builder, lit string) {
+	if enc.canonical {
+		writeCanonicalLiteral(sb, lit)
+	} else {
+		writeLiteral(sb, lit)
+	}
+}
+
+func (enc *Encoder) writeSubject(sb *strings.Builder, iri curie.IRI) {
+	if strings.HasPrefix(string(iri), "_:") {
+		writeBlank(sb, string(iri)[2:])
+		return
+	}
+
+	enc.writeIRI(sb, iri)
+}
+
+func (enc *Encoder) writeIRI(sb *strings.Builder, iri curie.IRI) {
+	uri := string(iri)
+	if enc.prefixes != nil {
+		if prefix, has := enc.prefixes.Lookup(curie.Prefix(iri)); has {
+			uri = prefix + curie.Reference(iri)
+		}
+	}
+
+	sb.WriteByte('<')
+	if enc.canonical {
+		sb.WriteString(uri)
+		sb.WriteByte('>')
+		return
+	}
+
+	for _, r := range uri {
+		switch {
+		case r <= 0x20 || strings.ContainsRune(`<>"{}|^`+"`\\", r):
+			fmt.Fprintf(sb, "\\u%04X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('>')
+}
+
+func writeLiteral(sb *strings.Builder, lit string) {
+	sb.WriteByte('"')
+	for _, r := range lit {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				fmt.Fprintf(sb, "\\u%04X", r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// writes literal in canonical form, only characters that are not allowed
+// by STRING_LITERAL_QUOTE are escaped.
+func writeCanonicalLiteral(sb *strings.Builder, lit string) {
+	sb.WriteByte('"')
+	for _, r := range lit {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// reserved prefix of blank node labels written as hex
+const blankHex = "b."
+
+// writes blank node, the label which do not conform N-Triples grammar
+// (e.g. ends with '.') is written as hex with reserved prefix. Labels that
+// starts with the reserved prefix are written as hex too, so that distinct
+// labels are never written as same one.
+func writeBlank(sb *strings.Builder, label string) {
+	sb.WriteString("_:")
+
+	valid := len(label) > 0 && label[len(label)-1] != '.' && !strings.HasPrefix(label, blankHex)
+	for i, r := range label {
+		if !isLabelRune(r, i == 0) {
+			valid = false
+			break
+		}
+	}
+
+	if valid {
+		sb.WriteString(label)
+		return
+	}
+
+	sb.WriteString(blankHex)
+	sb.WriteString("x")
+	sb.WriteString(hex.EncodeToString([]byte(label)))
+}
@@ -0,0 +1,97 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package ntriples
+
+//
+// The file define line-oriented N-Triples and N-Quads codec.
+//
+
+import (
+	"bytes"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+const xsdAnyURI = curie.IRI("http://www.w3.org/2001/XMLSchema#anyURI")
+
+type Bag spock.Bag
+
+func (bag Bag) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	for _, spock := range bag {
+		if err := enc.encode(spock); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (bag *Bag) UnmarshalText(b []byte) error {
+	dec := NewDecoder(bytes.NewReader(b))
+
+	for dec.Next() {
+		*bag = append(*bag, dec.Head())
+	}
+
+	return dec.Err()
+}
+
+// Option configures codec
+type Option func(*config)
+
+type config struct {
+	prefixes  curie.Prefixes
+	graph     curie.IRI
+	canonical bool
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithPrefixes maps CURIEs to absolute IRIs and vice versa
+func WithPrefixes(prefixes curie.Prefixes) Option {
+	return func(c *config) { c.prefixes = prefixes }
+}
+
+// WithGraph writes statements as N-Quads of the graph
+func WithGraph(graph curie.IRI) Option {
+	return func(c *config) { c.graph = graph }
+}
+
+// WithCanonical writes canonical N-Quads required by RDF Dataset
+// Canonicalization (RDFC-1.0). Literals escape only `\`, `"`, `\n` and `\r`,
+// IRIs are written as is. See https://www.w3.org/TR/rdf-canon/#canonical-quads
+func WithCanonical() Option {
+	return func(c *config) { c.canonical = true }
+}
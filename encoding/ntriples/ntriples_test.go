@@ -0,0 +1,273 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package ntriples_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/encoding/ntriples"
+)
+
+func TestNTriplesUnmarshal(t *testing.T) {
+	Codec := func(t *testing.T, input string) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		bag := ntriples.Bag{}
+		err := bag.UnmarshalText([]byte(input))
+		it.Then(t).Should(it.Nil(err))
+
+		return it.Seq(bag)
+	}
+
+	t.Run("Triple", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> <prop> "title" .`).Equal(
+				spock.From("a", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("TripleIRI", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> <prop> <b> .`).Equal(
+				spock.From("a", "prop", curie.IRI("b")),
+			),
+		)
+	})
+
+	t.Run("BlankNode", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `_:a <prop> _:b.c .`).Equal(
+				spock.From("_:a", "prop", curie.IRI("_:b.c")),
+			),
+		)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, "# comment\n\n<a> <prop> \"title\" . # comment\n").Equal(
+				spock.From("a", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a\u0020b> <prop> "\"t\\i\ttleä\U0001F600\n" .`).Equal(
+				spock.From("a b", "prop", "\"t\\i\ttleä😀\n"),
+			),
+		)
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, strings.Join([]string{
+				`<a> <p> "x"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+				`<a> <p> "y"^^<http://www.w3.org/2001/XMLSchema#anyURI> .`,
+				`<a> <p> "2023-01-01"^^<http://www.w3.org/2001/XMLSchema#date> .`,
+				`<a> <p> "z"@en-GB .`,
+				`<a> <p> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+				`<a> <p> "4.2E1"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+				`<a> <p> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+				`<a> <p> "4x"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+			}, "\n")).Equal(
+				spock.From("a", "p", "x"),
+				spock.From("a", "p", curie.IRI("y")),
+				spock.From("a", "p", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+				spock.From("a", "p", "z"),
+				spock.From("a", "p", 42),
+				spock.From("a", "p", 42.0),
+				spock.From("a", "p", true),
+				spock.From("a", "p", "4x"),
+			),
+		)
+	})
+
+	t.Run("Prefixes", func(t *testing.T) {
+		dec := ntriples.NewDecoder(
+			strings.NewReader(`<https://schema.org/a> <https://schema.org/name> "A" .`),
+			ntriples.WithPrefixes(curie.Namespaces{"schema": "https://schema.org/"}),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+			),
+		)
+	})
+
+	t.Run("Quads", func(t *testing.T) {
+		quads, err := ntriples.Quads(strings.NewReader(strings.Join([]string{
+			`<a> <p> "x" <g> .`,
+			`<a> <p> "y" .`,
+			`<b> <p> "z" _:g .`,
+		}, "\n")))
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(quads["g"]).Equal(spock.From("a", "p", "x")),
+			it.Seq(quads[""]).Equal(spock.From("a", "p", "y")),
+			it.Seq(quads["_:g"]).Equal(spock.From("b", "p", "z")),
+		)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, input := range []string{
+			`<a> <prop> "title"`,
+			`<a> "prop" "title" .`,
+			`<a b> <prop> "title" .`,
+			`<a> <prop> "title .`,
+			`<a> <prop> "title" . x`,
+			`<a> <prop> "\q" .`,
+		} {
+			bag := ntriples.Bag{}
+			it.Then(t).ShouldNot(
+				it.Nil(bag.UnmarshalText([]byte(input))),
+			)
+		}
+	})
+}
+
+func TestNTriplesMarshal(t *testing.T) {
+	Codec := func(t *testing.T, bag spock.Bag) string {
+		t.Helper()
+		b, err := ntriples.Bag(bag).MarshalText()
+		it.Then(t).Should(it.Nil(err))
+
+		return string(b)
+	}
+
+	t.Run("Triple", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a", "prop", "title"),
+					spock.From("a", "prop", curie.IRI("b")),
+				}),
+				"<a> <prop> \"title\" .\n<a> <prop> <b> .\n",
+			),
+		)
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a", "prop", -42),
+					spock.From("a", "prop", 0.5),
+					spock.From("a", "prop", false),
+					spock.From("a", "prop", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+				}),
+				strings.Join([]string{
+					`<a> <prop> "-42"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+					`<a> <prop> "0.5"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+					`<a> <prop> "false"^^<http://www.w3.org/2001/XMLSchema#boolean> .`,
+					`<a> <prop> "2023-01-02T03:04:05Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .`,
+					``,
+				}, "\n"),
+			),
+		)
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("a b", "prop", "\"t\\i\ttle\n"),
+				}),
+				"<a\\u0020b> <prop> \"\\\"t\\\\i\\ttle\\n\" .\n",
+			),
+		)
+	})
+
+	t.Run("BlankNode", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("_:a", "prop", curie.IRI("_:b.")),
+				}),
+				"_:a <prop> _:b.x622e .\n",
+			),
+		)
+	})
+
+	t.Run("BlankNodeCollision", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("_:ab.", "prop", curie.IRI("_:b61622e")),
+					spock.From("_:b.x61622e", "prop", curie.IRI("_:b.")),
+				}),
+				"_:b.x61622e <prop> _:b61622e .\n"+
+					"_:b.x622e78363136323265 <prop> _:b.x622e .\n",
+			),
+		)
+	})
+
+	t.Run("Quads", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := ntriples.NewEncoder(&buf,
+			ntriples.WithGraph("g"),
+			ntriples.WithPrefixes(curie.Namespaces{"schema": "https://schema.org/"}),
+		)
+
+		it.Then(t).Should(
+			it.Nil(enc.Put(spock.From("schema:a", "schema:name", "A"))),
+			it.Equal(buf.String(), "<https://schema.org/a> <https://schema.org/name> \"A\" <g> .\n"),
+		)
+	})
+
+	t.Run("Canonical", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := ntriples.NewEncoder(&buf, ntriples.WithCanonical())
+
+		it.Then(t).Should(
+			it.Nil(enc.Put(spock.From("a^b", "prop", "\ttab\nline\\"))),
+			it.Equal(buf.String(), "<a^b> <prop> \"\ttab\\nline\\\\\" .\n"),
+		)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		bag := spock.Bag{
+			spock.From("a", "prop", "multi\nline \"title\""),
+			spock.From("a", "prop", curie.IRI("_:b")),
+			spock.From("_:b", "prop", "ä"),
+			spock.From("_:b", "prop", 1e100),
+			spock.From("_:b", "prop", time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)),
+		}
+
+		b, err := ntriples.Bag(bag).MarshalText()
+		it.Then(t).Should(it.Nil(err))
+
+		seq := ntriples.Bag{}
+		it.Then(t).Should(
+			it.Nil(seq.UnmarshalText(b)),
+			it.Seq(seq).Equal(bag...),
+		)
+	})
+}
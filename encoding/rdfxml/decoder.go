@@ -0,0 +1,528 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package rdfxml
+
+//
+// The file define decoder of RDF/XML documents.
+//
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+// Decoder reads knowledge statements from RDF/XML document.
+// Decoder implements spock.Stream.
+//
+// Names of XML elements (classes and properties) are decoded as CURIEs
+// using namespace prefixes declared by the document (e.g. rdfs:label),
+// unless application prefixes are given. In this case, all IRIs are
+// compacted using application prefixes.
+type Decoder struct {
+	r      io.Reader
+	cfg    *config
+	parsed bool
+	bag    spock.Bag
+	head   spock.SPOCK
+	err    error
+}
+
+var _ spock.Stream = (*Decoder)(nil)
+
+// Create new decoder that reads from r.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return &Decoder{r: r, cfg: newConfig(opts)}
+}
+
+// Head returns current statement
+func (dec *Decoder) Head() spock.SPOCK { return dec.head }
+
+// Err returns the error (if any) that terminated the stream
+func (dec *Decoder) Err() error { return dec.err }
+
+// Next reads next statement from input
+func (dec *Decoder) Next() bool {
+	if dec.err != nil {
+		return false
+	}
+
+	if !dec.parsed {
+		dec.parsed = true
+		p := &parser{dec: xml.NewDecoder(dec.r), prefixes: dec.cfg.prefixes}
+		if err := p.document(); err != nil {
+			dec.err = err
+			return false
+		}
+		dec.bag = p.bag
+	}
+
+	if len(dec.bag) == 0 {
+		return false
+	}
+
+	dec.head = dec.bag[0]
+	dec.bag = dec.bag[1:]
+	return true
+}
+
+// FMap applies f to each statement, it returns the first error either from
+// f or decoder.
+func (dec *Decoder) FMap(f func(spock.SPOCK) error) error {
+	for dec.Next() {
+		if err := f(dec.Head()); err != nil {
+			return err
+		}
+	}
+	return dec.err
+}
+
+//------------------------------------------------------------------------------
+
+// scope of XML element, inherited by children
+type scope struct {
+	base string
+	ns   map[string]string // namespace URI to prefix
+}
+
+// scope of the element, it is cloned if element declares namespaces
+func (sc scope) with(start xml.StartElement) scope {
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == xmlNS && attr.Name.Local == "base":
+			sc.base = resolve(sc.base, attr.Value)
+		case attr.Name.Space == "xmlns":
+			ns := make(map[string]string, len(sc.ns)+1)
+			for k, v := range sc.ns {
+				ns[k] = v
+			}
+			ns[attr.Value] = attr.Name.Local
+			sc.ns = ns
+		}
+	}
+	return sc
+}
+
+type parser struct {
+	dec      *xml.Decoder
+	prefixes curie.Prefixes
+	bag      spock.Bag
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line, col := p.dec.InputPos()
+	return fmt.Errorf("rdfxml line %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// reserves slot for statement, it guarantees that statement is emitted
+// before statements of nested nodes.
+func (p *parser) reserve() int {
+	p.bag = append(p.bag, spock.SPOCK{})
+	return len(p.bag) - 1
+}
+
+func (p *parser) emit(at int, s, pred curie.IRI, o xsd.Value) {
+	p.bag[at] = spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(pred), O: o}
+}
+
+func (p *parser) blank() curie.IRI {
+	return curie.New("_:%s", guid.L(guid.Clock))
+}
+
+// IRI of absolute URI
+func (p *parser) iri(uri string) curie.IRI {
+	if p.prefixes != nil {
+		return curie.FromURI(p.prefixes, uri)
+	}
+	return curie.IRI(uri)
+}
+
+// IRI of XML name, it is CURIE if namespace prefix is declared
+func (p *parser) name(sc scope, name xml.Name) curie.IRI {
+	if p.prefixes != nil {
+		return curie.FromURI(p.prefixes, name.Space+name.Local)
+	}
+
+	if prefix, has := sc.ns[name.Space]; has && prefix != "" {
+		return curie.IRI(prefix + ":" + name.Local)
+	}
+
+	return curie.IRI(name.Space + name.Local)
+}
+
+func (p *parser) rdf(sc scope, local string) curie.IRI {
+	return p.name(sc, xml.Name{Space: rdfNS, Local: local})
+}
+
+func resolve(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil || r.IsAbs() {
+		return ref
+	}
+
+	return b.ResolveReference(r).String()
+}
+
+func isRDF(name xml.Name, local string) bool {
+	return name.Space == rdfNS && name.Local == local
+}
+
+func attr(start xml.StartElement, local string) (string, bool) {
+	for _, a := range start.Attr {
+		if isRDF(a.Name, local) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// attribute is property of node
+func isPropertyAttr(name xml.Name) bool {
+	switch {
+	case name.Space == "" || name.Space == "xmlns" || name.Space == xmlNS:
+		return false
+	case name.Space == rdfNS:
+		switch name.Local {
+		case "about", "ID", "nodeID", "resource", "datatype", "parseType", "bagID", "aboutEach", "aboutEachPrefix", "li":
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// document is either rdf:RDF element or single node element
+func (p *parser) document() error {
+	sc := scope{ns: map[string]string{}}
+
+	for {
+		tok, err := p.dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if !isRDF(start.Name, "RDF") {
+			_, err := p.nodeElement(sc, start)
+			return err
+		}
+
+		sc = sc.with(start)
+		return p.nodeElementList(sc, func(curie.IRI) {})
+	}
+}
+
+// sequence of node elements until end of parent element
+func (p *parser) nodeElementList(sc scope, f func(curie.IRI)) error {
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			s, err := p.nodeElement(sc, t)
+			if err != nil {
+				return err
+			}
+			f(s)
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return p.errorf("unexpected text %q", t)
+			}
+		}
+	}
+}
+
+// node element, it returns subject of the node
+func (p *parser) nodeElement(sc scope, start xml.StartElement) (curie.IRI, error) {
+	sc = sc.with(start)
+
+	s := p.blank()
+	if about, has := attr(start, "about"); has {
+		s = p.iri(resolve(sc.base, about))
+	}
+	if id, has := attr(start, "ID"); has {
+		s = p.iri(resolve(sc.base, "#"+id))
+	}
+	if id, has := attr(start, "nodeID"); has {
+		s = curie.IRI("_:" + id)
+	}
+
+	if !isRDF(start.Name, "Description") {
+		p.emit(p.reserve(), s, p.rdf(sc, "type"), xsd.ToAnyURI(p.name(sc, start.Name)))
+	}
+
+	p.propertyAttrs(sc, s, start)
+
+	return s, p.propertyElementList(sc, s)
+}
+
+// property attributes of node
+func (p *parser) propertyAttrs(sc scope, s curie.IRI, start xml.StartElement) {
+	for _, a := range start.Attr {
+		if !isPropertyAttr(a.Name) {
+			continue
+		}
+
+		if isRDF(a.Name, "type") {
+			p.emit(p.reserve(), s, p.rdf(sc, "type"), xsd.ToAnyURI(p.iri(resolve(sc.base, a.Value))))
+			continue
+		}
+
+		p.emit(p.reserve(), s, p.name(sc, a.Name), xsd.String(a.Value))
+	}
+}
+
+// sequence of property elements until end of node element
+func (p *parser) propertyElementList(sc scope, s curie.IRI) error {
+	li := 0
+
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := p.propertyElement(sc, s, t, &li); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return p.errorf("unexpected text %q", t)
+			}
+		}
+	}
+}
+
+// property element, xml:lang is dropped. Literals of data types not
+// supported by codec.Typed are xsd:string.
+func (p *parser) propertyElement(sc scope, s curie.IRI, start xml.StartElement, li *int) error {
+	sc = sc.with(start)
+
+	pred := p.name(sc, start.Name)
+	if isRDF(start.Name, "li") {
+		*li++
+		pred = p.rdf(sc, "_"+strconv.Itoa(*li))
+	}
+
+	at := p.reserve()
+
+	if parseType, has := attr(start, "parseType"); has {
+		switch parseType {
+		case "Resource":
+			o := p.blank()
+			p.emit(at, s, pred, xsd.ToAnyURI(o))
+			return p.propertyElementList(sc, o)
+		case "Collection":
+			return p.collection(sc, at, s, pred)
+		default:
+			lit, err := p.xmlLiteral()
+			if err != nil {
+				return err
+			}
+			p.emit(at, s, pred, xsd.String(lit))
+			return nil
+		}
+	}
+
+	resource, hasResource := attr(start, "resource")
+	nodeID, hasNodeID := attr(start, "nodeID")
+	hasProperties := false
+	for _, a := range start.Attr {
+		hasProperties = hasProperties || isPropertyAttr(a.Name)
+	}
+
+	if hasResource || hasNodeID || hasProperties {
+		o := p.blank()
+		switch {
+		case hasResource:
+			o = p.iri(resolve(sc.base, resource))
+		case hasNodeID:
+			o = curie.IRI("_:" + nodeID)
+		}
+
+		p.emit(at, s, pred, xsd.ToAnyURI(o))
+		p.propertyAttrs(sc, o, start)
+		return p.empty()
+	}
+
+	var text strings.Builder
+	nested := false
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			if nested {
+				return p.errorf("property %s has multiple nodes", pred)
+			}
+			nested = true
+
+			o, err := p.nodeElement(sc, t)
+			if err != nil {
+				return err
+			}
+			p.emit(at, s, pred, xsd.ToAnyURI(o))
+		case xml.EndElement:
+			if nested {
+				if strings.TrimSpace(text.String()) != "" {
+					return p.errorf("property %s mixes text and node", pred)
+				}
+				return nil
+			}
+
+			datatype, _ := attr(start, "datatype")
+			datatype = resolve(sc.base, datatype)
+			if datatype == xsdAnyURI {
+				p.emit(at, s, pred, xsd.ToAnyURI(p.iri(text.String())))
+				return nil
+			}
+
+			if val, ok := codec.Typed(text.String(), datatype); ok {
+				p.emit(at, s, pred, val)
+				return nil
+			}
+
+			p.emit(at, s, pred, xsd.String(text.String()))
+			return nil
+		}
+	}
+}
+
+// empty property element
+func (p *parser) empty() error {
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return p.errorf("%s", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return p.errorf("unexpected text %q", t)
+			}
+		case xml.StartElement:
+			return p.errorf("unexpected element %s", t.Name.Local)
+		}
+	}
+}
+
+// collection of node elements as rdf:first/rdf:rest chain
+func (p *parser) collection(sc scope, at int, s, pred curie.IRI) error {
+	head := curie.IRI("")
+	node := curie.IRI("")
+
+	err := p.nodeElementList(sc, func(o curie.IRI) {
+		next := p.blank()
+		if node == "" {
+			head = next
+		} else {
+			p.emit(p.reserve(), node, p.rdf(sc, "rest"), xsd.ToAnyURI(next))
+		}
+		node = next
+		p.emit(p.reserve(), node, p.rdf(sc, "first"), xsd.ToAnyURI(o))
+	})
+	if err != nil {
+		return err
+	}
+
+	if node == "" {
+		p.emit(at, s, pred, xsd.ToAnyURI(p.rdf(sc, "nil")))
+		return nil
+	}
+
+	p.emit(p.reserve(), node, p.rdf(sc, "rest"), xsd.ToAnyURI(p.rdf(sc, "nil")))
+	p.emit(at, s, pred, xsd.ToAnyURI(head))
+	return nil
+}
+
+// content of property element as XML literal
+func (p *parser) xmlLiteral() (string, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+
+	for depth := 0; ; {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return "", p.errorf("%s", err)
+		}
+
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				if err := enc.Flush(); err != nil {
+					return "", err
+				}
+				return buf.String(), nil
+			}
+			depth--
+		}
+
+		if err := enc.EncodeToken(tok); err != nil {
+			return "", p.errorf("%s", err)
+		}
+	}
+}
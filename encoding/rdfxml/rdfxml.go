@@ -0,0 +1,71 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package rdfxml
+
+//
+// The file define RDF/XML codec.
+// See https://www.w3.org/TR/rdf-syntax-grammar/
+//
+
+import (
+	"bytes"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+const (
+	rdfNS     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNS     = "http://www.w3.org/XML/1998/namespace"
+	xsdAnyURI = "http://www.w3.org/2001/XMLSchema#anyURI"
+)
+
+type Bag spock.Bag
+
+func (bag *Bag) UnmarshalText(b []byte) error {
+	dec := NewDecoder(bytes.NewReader(b))
+
+	for dec.Next() {
+		*bag = append(*bag, dec.Head())
+	}
+
+	return dec.Err()
+}
+
+// Option configures codec
+type Option func(*config)
+
+type config struct {
+	prefixes curie.Prefixes
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithPrefixes compacts IRIs to application CURIEs
+func WithPrefixes(prefixes curie.Prefixes) Option {
+	return func(c *config) { c.prefixes = prefixes }
+}
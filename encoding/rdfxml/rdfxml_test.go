@@ -0,0 +1,231 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package rdfxml_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/encoding/rdfxml"
+)
+
+const header = `<?xml version="1.0"?>
+<rdf:RDF
+	xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns:rdfs="http://www.w3.org/2000/01/rdf-schema#"
+	xmlns:schema="https://schema.org/">
+`
+
+func TestRdfXmlUnmarshal(t *testing.T) {
+	guid.Clock = guid.NewClockMock()
+	luid := curie.IRI("_:5...............")
+
+	Codec := func(t *testing.T, input string) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		bag := rdfxml.Bag{}
+		err := bag.UnmarshalText([]byte(header + input + "</rdf:RDF>"))
+		it.Then(t).Should(it.Nil(err))
+
+		return it.Seq(bag)
+	}
+
+	t.Run("Description", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:about="a" schema:title="attr">
+					<schema:name>title</schema:name>
+					<schema:knows rdf:resource="b"/>
+				</rdf:Description>
+			`).Equal(
+				spock.From("a", "schema:title", "attr"),
+				spock.From("a", "schema:name", "title"),
+				spock.From("a", "schema:knows", curie.IRI("b")),
+			),
+		)
+	})
+
+	t.Run("TypedNode", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<schema:Person rdf:about="a">
+					<rdf:type rdf:resource="https://schema.org/Thing"/>
+				</schema:Person>
+			`).Equal(
+				spock.From("a", "rdf:type", curie.IRI("schema:Person")),
+				spock.From("a", "rdf:type", curie.IRI("https://schema.org/Thing")),
+			),
+		)
+	})
+
+	t.Run("Base", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description xml:base="https://example.com/a/" rdf:about="b" rdf:ID="c">
+					<schema:knows rdf:resource="../d"/>
+				</rdf:Description>
+			`).Equal(
+				spock.From("https://example.com/a/#c", "schema:knows", curie.IRI("https://example.com/d")),
+			),
+		)
+	})
+
+	t.Run("NodeID", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:nodeID="x">
+					<schema:knows rdf:nodeID="y"/>
+				</rdf:Description>
+			`).Equal(
+				spock.From("_:x", "schema:knows", curie.IRI("_:y")),
+			),
+		)
+	})
+
+	t.Run("Literals", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:about="a">
+					<schema:name xml:lang="en">title</schema:name>
+					<schema:date rdf:datatype="http://www.w3.org/2001/XMLSchema#date">2023-01-01</schema:date>
+					<schema:url rdf:datatype="http://www.w3.org/2001/XMLSchema#anyURI">b</schema:url>
+					<schema:size rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">42</schema:size>
+					<schema:text rdf:parseType="Literal"><b>x</b></schema:text>
+					<schema:none/>
+				</rdf:Description>
+			`).Equal(
+				spock.From("a", "schema:name", "title"),
+				spock.From("a", "schema:date", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+				spock.From("a", "schema:url", curie.IRI("b")),
+				spock.From("a", "schema:size", 42),
+				spock.From("a", "schema:text", "<b>x</b>"),
+				spock.From("a", "schema:none", ""),
+			),
+		)
+	})
+
+	t.Run("NestedNode", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:about="a">
+					<schema:knows>
+						<schema:Person schema:name="B"/>
+					</schema:knows>
+					<schema:author schema:name="C"/>
+				</rdf:Description>
+			`).Equal(
+				spock.From("a", "schema:knows", luid),
+				spock.From(luid, "rdf:type", curie.IRI("schema:Person")),
+				spock.From(luid, "schema:name", "B"),
+				spock.From("a", "schema:author", luid),
+				spock.From(luid, "schema:name", "C"),
+			),
+		)
+	})
+
+	t.Run("ParseTypeResource", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:about="a">
+					<schema:knows rdf:parseType="Resource">
+						<schema:name>B</schema:name>
+					</schema:knows>
+				</rdf:Description>
+			`).Equal(
+				spock.From("a", "schema:knows", luid),
+				spock.From(luid, "schema:name", "B"),
+			),
+		)
+	})
+
+	t.Run("ParseTypeCollection", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Description rdf:about="a">
+					<schema:items rdf:parseType="Collection">
+						<rdf:Description rdf:about="b"/>
+					</schema:items>
+					<schema:none rdf:parseType="Collection"></schema:none>
+				</rdf:Description>
+			`).Equal(
+				spock.From("a", "schema:items", luid),
+				spock.From(luid, "rdf:first", curie.IRI("b")),
+				spock.From(luid, "rdf:rest", curie.IRI("rdf:nil")),
+				spock.From("a", "schema:none", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				<rdf:Seq rdf:about="a">
+					<rdf:li>x</rdf:li>
+					<rdf:li rdf:resource="b"/>
+				</rdf:Seq>
+			`).Equal(
+				spock.From("a", "rdf:type", curie.IRI("rdf:Seq")),
+				spock.From("a", "rdf:_1", "x"),
+				spock.From("a", "rdf:_2", curie.IRI("b")),
+			),
+		)
+	})
+
+	t.Run("WithPrefixes", func(t *testing.T) {
+		dec := rdfxml.NewDecoder(
+			strings.NewReader(header+`
+				<rdf:Description rdf:about="https://schema.org/a">
+					<rdfs:label>A</rdfs:label>
+				</rdf:Description>
+			</rdf:RDF>`),
+			rdfxml.WithPrefixes(curie.Namespaces{
+				"schema": "https://schema.org/",
+				"label":  "http://www.w3.org/2000/01/rdf-schema#",
+			}),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("schema:a", "label:label", "A"),
+			),
+		)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, input := range []string{
+			`<rdf:Description rdf:about="a">text</rdf:Description>`,
+			`<rdf:Description rdf:about="a"><schema:p><a/><b/></schema:p></rdf:Description>`,
+			`<rdf:Description rdf:about="a"><schema:p rdf:resource="b">text</schema:p></rdf:Description>`,
+			`<rdf:Description rdf:about="a">`,
+		} {
+			bag := rdfxml.Bag{}
+			it.Then(t).ShouldNot(
+				it.Nil(bag.UnmarshalText([]byte(header + input + "</rdf:RDF>"))),
+			)
+		}
+	})
+}
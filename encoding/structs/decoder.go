@@ -0,0 +1,272 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package structs
+
+//
+// The file define decoder of knowledge statements into Go structs
+//
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// Unmarshal decodes statements of the stream into struct or slice of
+// structs pointed by v. The struct is decoded from statements of its id,
+// if the id is not set then from statements of the first root subject
+// (subject that is not object of any other statement). The slice is decoded
+// from all root subjects. Numbers and booleans are parsed from their
+// lexical form.
+func Unmarshal(stream spock.Stream, v any) error {
+	bag := spock.Bag{}
+	if err := stream.FMap(bag.Join); err != nil {
+		return err
+	}
+
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Pointer || val.IsNil() {
+		return fmt.Errorf("struct codec requires non-nil pointer, got %T", v)
+	}
+
+	dec := newDecoder(bag)
+	val = val.Elem()
+
+	switch val.Kind() {
+	case reflect.Struct:
+		s := curie.IRI("")
+		if f, err := typeOf(val.Type()); err == nil && f.id != nil {
+			s = curie.IRI(val.FieldByIndex(f.id).String())
+		}
+
+		if s == "" {
+			roots := dec.roots()
+			if len(roots) == 0 {
+				return nil
+			}
+			s = roots[0]
+		}
+
+		return dec.node(s, val)
+	case reflect.Slice:
+		for _, s := range dec.roots() {
+			item := reflect.New(val.Type().Elem()).Elem()
+			if err := dec.value(item, xsd.ToAnyURI(s)); err != nil {
+				return err
+			}
+			val.Set(reflect.Append(val, item))
+		}
+		return nil
+	default:
+		return fmt.Errorf("struct codec do not support %T", v)
+	}
+}
+
+type decoder struct {
+	nodes    map[xsd.AnyURI][]spock.SPOCK
+	subjects []xsd.AnyURI
+	objects  map[xsd.AnyURI]struct{}
+	pointers map[ref]reflect.Value // decoded pointers, guards cycles
+	path     map[curie.IRI]struct{}
+}
+
+type ref struct {
+	s curie.IRI
+	t reflect.Type
+}
+
+func newDecoder(bag spock.Bag) *decoder {
+	dec := &decoder{
+		nodes:    map[xsd.AnyURI][]spock.SPOCK{},
+		objects:  map[xsd.AnyURI]struct{}{},
+		pointers: map[ref]reflect.Value{},
+		path:     map[curie.IRI]struct{}{},
+	}
+
+	for _, x := range bag {
+		if _, has := dec.nodes[x.S]; !has {
+			dec.subjects = append(dec.subjects, x.S)
+		}
+		dec.nodes[x.S] = append(dec.nodes[x.S], x)
+
+		if o, ok := x.O.(xsd.AnyURI); ok {
+			dec.objects[o] = struct{}{}
+		}
+	}
+
+	return dec
+}
+
+// subjects that are not objects of other statements, the first subject is
+// the root if graph is cyclic.
+func (dec *decoder) roots() []curie.IRI {
+	seq := []curie.IRI{}
+	for _, s := range dec.subjects {
+		if _, has := dec.objects[s]; !has {
+			seq = append(seq, curie.IRI(s.String()))
+		}
+	}
+
+	if len(seq) == 0 && len(dec.subjects) > 0 {
+		seq = append(seq, curie.IRI(dec.subjects[0].String()))
+	}
+
+	return seq
+}
+
+// decodes statements of subject s into struct
+func (dec *decoder) node(s curie.IRI, val reflect.Value) error {
+	f, err := typeOf(val.Type())
+	if err != nil {
+		return err
+	}
+
+	if f.id != nil {
+		val.FieldByIndex(f.id).SetString(string(s))
+	}
+
+	// cyclic reference of struct values is decoded as id only
+	if _, has := dec.path[s]; has {
+		return nil
+	}
+	dec.path[s] = struct{}{}
+	defer delete(dec.path, s)
+
+	stmts := dec.nodes[xsd.ToAnyURI(s)]
+	for _, fld := range f.seq {
+		p := xsd.ToAnyURI(fld.predicate)
+
+		v := val.FieldByIndex(fld.index)
+		isSeq := v.Kind() == reflect.Slice
+
+		for _, x := range stmts {
+			if x.P != p {
+				continue
+			}
+
+			if !isSeq {
+				if err := dec.value(v, x.O); err != nil {
+					return err
+				}
+				break
+			}
+
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := dec.value(item, x.O); err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, item))
+		}
+	}
+
+	return nil
+}
+
+// decodes object into value
+func (dec *decoder) value(v reflect.Value, o xsd.Value) error {
+	switch {
+	case v.Type() == typeIRI:
+		v.SetString(lexical(o))
+		return nil
+	case v.Type() == typeTime:
+		t, ok := o.(spock.DateTime)
+		if !ok {
+			return fmt.Errorf("struct codec do not support %T (%v) as %s", o, o, v.Type())
+		}
+		v.Set(reflect.ValueOf(time.Time(t)))
+		return nil
+	case v.Kind() == reflect.Pointer:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			key := ref{s: curie.IRI(lexical(o)), t: v.Type()}
+			if ptr, has := dec.pointers[key]; has {
+				v.Set(ptr)
+				return nil
+			}
+
+			v.Set(reflect.New(v.Type().Elem()))
+			dec.pointers[key] = v
+			return dec.value(v.Elem(), o)
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return dec.value(v.Elem(), o)
+	case v.Kind() == reflect.Struct:
+		iri, ok := o.(xsd.AnyURI)
+		if !ok {
+			return fmt.Errorf("struct codec do not support %T (%v) as node %s", o, o, v.Type())
+		}
+		return dec.node(curie.IRI(iri.String()), v)
+	}
+
+	lit := lexical(o)
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(lit)
+	case reflect.Bool:
+		x, err := strconv.ParseBool(lit)
+		if err != nil {
+			return err
+		}
+		v.SetBool(x)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(lit, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := strconv.ParseUint(lit, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(lit, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	default:
+		return fmt.Errorf("struct codec do not support %s", v.Type())
+	}
+
+	return nil
+}
+
+// lexical form of object
+func lexical(o xsd.Value) string {
+	switch v := o.(type) {
+	case xsd.AnyURI:
+		return v.String()
+	case xsd.String:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", o)
+	}
+}
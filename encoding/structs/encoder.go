@@ -0,0 +1,202 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package structs
+
+//
+// The file define encoder of Go structs into knowledge statements
+//
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// Marshal encodes struct, pointer to struct or slice of them into bag of
+// knowledge statements. Structs without id are encoded as blank nodes.
+func Marshal(v any) (spock.Bag, error) {
+	bag := spock.Bag{}
+	enc := &encoder{bag: &bag, seen: map[uintptr]curie.IRI{}}
+
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Pointer && !val.IsNil() && val.Elem().Kind() != reflect.Struct {
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if _, err := enc.node(val.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if _, err := enc.node(val); err != nil {
+			return nil, err
+		}
+	}
+
+	return bag, nil
+}
+
+type encoder struct {
+	bag  *spock.Bag
+	seen map[uintptr]curie.IRI // visited pointers, guards cycles
+}
+
+// encodes struct as node, it returns subject of the node
+func (enc *encoder) node(val reflect.Value) (curie.IRI, error) {
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return "", fmt.Errorf("struct codec do not support nil node")
+		}
+
+		if id, has := enc.seen[val.Pointer()]; has {
+			return id, nil
+		}
+
+		ptr := val.Pointer()
+		val = val.Elem()
+		enc.seen[ptr] = enc.subject(val)
+		return enc.seen[ptr], enc.properties(enc.seen[ptr], val)
+	}
+
+	if val.Kind() != reflect.Struct {
+		return "", fmt.Errorf("struct codec do not support %s", val.Type())
+	}
+
+	s := enc.subject(val)
+	return s, enc.properties(s, val)
+}
+
+// subject of node, blank node is minted if struct has no id
+func (enc *encoder) subject(val reflect.Value) curie.IRI {
+	f, err := typeOf(val.Type())
+	if err == nil && f.id != nil {
+		if id := val.FieldByIndex(f.id).String(); id != "" {
+			return curie.IRI(id)
+		}
+	}
+
+	return curie.New("_:%s", guid.L(guid.Clock))
+}
+
+func (enc *encoder) properties(s curie.IRI, val reflect.Value) error {
+	f, err := typeOf(val.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, fld := range f.seq {
+		v := val.FieldByIndex(fld.index)
+		if fld.omitempty && v.IsZero() {
+			continue
+		}
+
+		if err := enc.property(s, fld.predicate, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *encoder) property(s, p curie.IRI, v reflect.Value) error {
+	switch {
+	case v.Type() == typeIRI:
+		enc.append(s, p, xsd.ToAnyURI(curie.IRI(v.String())))
+		return nil
+	case v.Type() == typeTime:
+		enc.append(s, p, spock.ValueOf(v.Interface().(time.Time)))
+		return nil
+	case v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Elem().Kind() != reflect.Struct {
+			return enc.property(s, p, v.Elem())
+		}
+		fallthrough
+	case v.Kind() == reflect.Struct:
+		at := len(*enc.bag)
+		o, err := enc.node(v)
+		if err != nil {
+			return err
+		}
+		enc.insert(at, s, p, o)
+		return nil
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.property(s, p, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	o, err := literal(v)
+	if err != nil {
+		return err
+	}
+
+	enc.append(s, p, o)
+	return nil
+}
+
+func (enc *encoder) append(s, p curie.IRI, o xsd.Value) {
+	*enc.bag = append(*enc.bag, spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: o})
+}
+
+// inserts link to node before statements of the node
+func (enc *encoder) insert(at int, s, p, o curie.IRI) {
+	enc.append(s, p, xsd.ToAnyURI(o))
+
+	bag := *enc.bag
+	copy(bag[at+1:], bag[at:len(bag)-1])
+	bag[at] = spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: xsd.ToAnyURI(o)}
+}
+
+// lexical form of scalar value
+func literal(v reflect.Value) (xsd.Value, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return xsd.String(v.String()), nil
+	case reflect.Bool:
+		return spock.Boolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return spock.Integer(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("struct codec do not support %s %d, it overflows spock.Integer", v.Type(), v.Uint())
+		}
+		return spock.Integer(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return spock.Double(v.Float()), nil
+	default:
+		return nil, fmt.Errorf("struct codec do not support %s", v.Type())
+	}
+}
@@ -0,0 +1,143 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+// Package structs maps Go structs to knowledge statements and back using
+// struct tags:
+//
+//	type Person struct {
+//		ID    curie.IRI   `spock:"id"`
+//		Name  string      `spock:"foaf:name"`
+//		Knows []*Person   `spock:"foaf:knows"`
+//		Tags  []string    `spock:"schema:keywords,omitempty"`
+//	}
+//
+// The field tagged as `id` is the subject of statements, nested structs are
+// linked nodes and slices are multi-valued predicates. Fields without tag
+// are ignored. The option `omitempty` skips the field with zero value,
+// otherwise the zero value is encoded (e.g. 0, false, "").
+//
+// Scalars are encoded as typed values: integers as spock.Integer, floats as
+// spock.Double, booleans as spock.Boolean and time.Time as spock.DateTime.
+// Decoder converts typed values back into the type of field, numbers and
+// booleans are also parsed from their lexical form (e.g. "-42", "0.5").
+// Fields of type curie.IRI are encoded as IRIs.
+package structs
+
+//
+// The file define field mapping of Go structs
+//
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogfish/curie"
+)
+
+var (
+	typeIRI  = reflect.TypeOf(curie.IRI(""))
+	typeTime = reflect.TypeOf(time.Time{})
+)
+
+// field of struct mapped to predicate
+type field struct {
+	index     []int
+	predicate curie.IRI
+	omitempty bool
+}
+
+// fields of struct type
+type fields struct {
+	id  []int
+	seq []field
+}
+
+var cache sync.Map
+
+func typeOf(t reflect.Type) (*fields, error) {
+	if f, has := cache.Load(t); has {
+		return f.(*fields), nil
+	}
+
+	f := &fields{}
+	if err := f.scan(t, nil); err != nil {
+		return nil, err
+	}
+
+	cache.Store(t, f)
+	return f, nil
+}
+
+func (f *fields) scan(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		at := append(append([]int{}, index...), i)
+
+		tag, has := sf.Tag.Lookup("spock")
+		if !has {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := f.scan(sf.Type, at); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if !sf.IsExported() {
+			return fmt.Errorf("struct codec do not support unexported field %s.%s", t.Name(), sf.Name)
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		switch name {
+		case "-":
+			continue
+		case "id":
+			if sf.Type.Kind() != reflect.String {
+				return fmt.Errorf("struct codec do not support id of type %s", sf.Type)
+			}
+			f.id = at
+		case "":
+			return fmt.Errorf("struct codec requires predicate for field %s.%s", t.Name(), sf.Name)
+		default:
+			f.seq = append(f.seq, field{
+				index:     at,
+				predicate: curie.IRI(name),
+				omitempty: hasOption(opts, "omitempty"),
+			})
+		}
+	}
+
+	return nil
+}
+
+// hasOption checks comma-separated options of the tag
+func hasOption(opts, opt string) bool {
+	for opts != "" {
+		var name string
+		name, opts, _ = strings.Cut(opts, ",")
+		if name == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package structs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/encoding/structs"
+)
+
+type Address struct {
+	City string `spock:"schema:addressLocality"`
+}
+
+type Person struct {
+	ID      curie.IRI `spock:"id"`
+	Name    string    `spock:"foaf:name"`
+	Age     int       `spock:"foaf:age,omitempty"`
+	Score   float64   `spock:"schema:score,omitempty"`
+	Active  bool      `spock:"schema:active,omitempty"`
+	Born    time.Time `spock:"schema:birthDate,omitempty"`
+	Home    curie.IRI `spock:"foaf:homepage,omitempty"`
+	Tags    []string  `spock:"schema:keywords"`
+	Address *Address  `spock:"schema:address"`
+	Knows   []*Person `spock:"foaf:knows"`
+	Note    string
+}
+
+func TestStructMarshal(t *testing.T) {
+	guid.Clock = guid.NewClockMock()
+	luid := curie.IRI("_:5...............")
+
+	t.Run("Struct", func(t *testing.T) {
+		bag, err := structs.Marshal(Person{
+			ID:      "a",
+			Name:    "A",
+			Age:     42,
+			Born:    time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC),
+			Home:    "https://example.com",
+			Tags:    []string{"x", "y"},
+			Address: &Address{City: "Helsinki"},
+			Knows:   []*Person{{ID: "b", Name: "B"}},
+			Note:    "ignored",
+		})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "foaf:name", "A"),
+				spock.From("a", "foaf:age", 42),
+				spock.From("a", "schema:birthDate", time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC)),
+				spock.From("a", "foaf:homepage", curie.IRI("https://example.com")),
+				spock.From("a", "schema:keywords", "x"),
+				spock.From("a", "schema:keywords", "y"),
+				spock.From("a", "schema:address", luid),
+				spock.From(luid, "schema:addressLocality", "Helsinki"),
+				spock.From("a", "foaf:knows", curie.IRI("b")),
+				spock.From("b", "foaf:name", "B"),
+			),
+		)
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		bag, err := structs.Marshal([]Person{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "foaf:name", "A"),
+				spock.From("b", "foaf:name", "B"),
+			),
+		)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		a := &Person{ID: "a", Name: "A"}
+		a.Knows = []*Person{a}
+
+		bag, err := structs.Marshal(a)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "foaf:name", "A"),
+				spock.From("a", "foaf:knows", curie.IRI("a")),
+			),
+		)
+	})
+
+	t.Run("OmitEmpty", func(t *testing.T) {
+		bag, err := structs.Marshal(struct {
+			ID     curie.IRI `spock:"id"`
+			Name   string    `spock:"foaf:name"`
+			Age    int       `spock:"foaf:age"`
+			Active bool      `spock:"schema:active"`
+			Score  float64   `spock:"schema:score,omitempty"`
+			Nick   string    `spock:"foaf:nick,string,omitempty"`
+		}{ID: "a"})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(bag).Equal(
+				spock.From("a", "foaf:name", ""),
+				spock.From("a", "foaf:age", 0),
+				spock.From("a", "schema:active", false),
+			),
+		)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := structs.Marshal(struct {
+			Map map[string]string `spock:"schema:map"`
+		}{Map: map[string]string{}})
+
+		it.Then(t).ShouldNot(it.Nil(err))
+	})
+}
+
+func TestStructUnmarshal(t *testing.T) {
+	bag := spock.Bag{
+		spock.From("a", "foaf:name", "A"),
+		spock.From("a", "foaf:age", "42"),
+		spock.From("a", "schema:score", "0.5"),
+		spock.From("a", "schema:active", "true"),
+		spock.From("a", "foaf:homepage", curie.IRI("https://example.com")),
+		spock.From("a", "schema:keywords", "x"),
+		spock.From("a", "schema:keywords", "y"),
+		spock.From("a", "schema:address", curie.IRI("_:addr")),
+		spock.From("_:addr", "schema:addressLocality", "Helsinki"),
+		spock.From("a", "foaf:knows", curie.IRI("b")),
+		spock.From("b", "foaf:name", "B"),
+		spock.From("b", "foaf:knows", curie.IRI("a")),
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		var p Person
+		err := structs.Unmarshal(bag.Stream(), &p)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(p.ID, "a"),
+			it.Equal(p.Name, "A"),
+			it.Equal(p.Age, 42),
+			it.Equal(p.Score, 0.5),
+			it.True(p.Active),
+			it.Equal(p.Home, "https://example.com"),
+			it.Seq(p.Tags).Equal("x", "y"),
+			it.Equal(p.Address.City, "Helsinki"),
+			it.Equal(len(p.Knows), 1),
+			it.Equal(p.Knows[0].Name, "B"),
+			it.Equal(p.Knows[0].Knows[0].ID, "a"),
+		)
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		var p Person
+		err := structs.Unmarshal(spock.Bag{
+			spock.From("a", "foaf:age", 42),
+			spock.From("a", "schema:score", 0.5),
+			spock.From("a", "schema:active", true),
+			spock.From("a", "schema:birthDate", time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC)),
+		}.Stream(), &p)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(p.Age, 42),
+			it.Equal(p.Score, 0.5),
+			it.True(p.Active),
+			it.Equal(p.Born, time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC)),
+		)
+	})
+
+	t.Run("StructByID", func(t *testing.T) {
+		p := Person{ID: "b"}
+		err := structs.Unmarshal(bag.Stream(), &p)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(p.Name, "B"),
+			it.Equal(p.Knows[0].Name, "A"),
+		)
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		var seq []Person
+		err := structs.Unmarshal(spock.Bag{
+			spock.From("a", "foaf:name", "A"),
+			spock.From("b", "foaf:name", "B"),
+		}.Stream(), &seq)
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 2),
+			it.Equal(seq[0].Name, "A"),
+			it.Equal(seq[1].Name, "B"),
+		)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		in := Person{ID: "a", Name: "A", Age: 7, Tags: []string{"x"}}
+		bag, err := structs.Marshal(in)
+		it.Then(t).Should(it.Nil(err))
+
+		var out Person
+		it.Then(t).Should(
+			it.Nil(structs.Unmarshal(bag.Stream(), &out)),
+			it.Equal(out.Name, in.Name),
+			it.Equal(out.Age, in.Age),
+			it.Seq(out.Tags).Equal(in.Tags...),
+		)
+	})
+
+	t.Run("InvalidValue", func(t *testing.T) {
+		var p Person
+		err := structs.Unmarshal(spock.Bag{spock.From("a", "foaf:age", "x")}.Stream(), &p)
+
+		it.Then(t).ShouldNot(it.Nil(err))
+	})
+}
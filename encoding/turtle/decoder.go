@@ -0,0 +1,898 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package turtle
+
+//
+// The file define recursive descent parser of Turtle and TriG documents.
+//
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+// Decoder reads knowledge statements from Turtle or TriG document.
+// Decoder implements spock.Stream.
+//
+// Prefixed names are decoded as CURIEs (e.g. schema:name), unless
+// application prefixes are given. In this case, all IRIs are expanded and
+// compacted using application prefixes.
+type Decoder struct {
+	r      io.Reader
+	cfg    *config
+	parser *parser
+	head   spock.SPOCK
+	graph  curie.IRI
+	err    error
+}
+
+var _ spock.Stream = (*Decoder)(nil)
+
+// Create new decoder that reads from r.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return &Decoder{r: r, cfg: newConfig(opts)}
+}
+
+// Head returns current statement
+func (dec *Decoder) Head() spock.SPOCK { return dec.head }
+
+// Graph returns graph of current statement, empty IRI is default graph
+func (dec *Decoder) Graph() curie.IRI { return dec.graph }
+
+// Err returns the error (if any) that terminated the stream
+func (dec *Decoder) Err() error { return dec.err }
+
+// Prefixes returns namespaces declared by the document so far
+func (dec *Decoder) Prefixes() curie.Namespaces {
+	if dec.parser == nil {
+		return curie.Namespaces{}
+	}
+	return dec.parser.namespaces
+}
+
+// Next reads next statement from input
+func (dec *Decoder) Next() bool {
+	if dec.err != nil {
+		return false
+	}
+
+	if dec.parser == nil {
+		b, err := io.ReadAll(dec.r)
+		if err != nil {
+			dec.err = err
+			return false
+		}
+		dec.parser = newParser(string(b), dec.cfg.prefixes)
+	}
+
+	for len(dec.parser.quads) == 0 {
+		dec.parser.skip()
+		if dec.parser.eof() {
+			return false
+		}
+
+		if err := dec.parser.block(); err != nil {
+			dec.err = err
+			return false
+		}
+	}
+
+	q := dec.parser.quads[0]
+	dec.parser.quads = dec.parser.quads[1:]
+	dec.head, dec.graph = q.spock, q.graph
+
+	return true
+}
+
+// FMap applies f to each statement, it returns the first error either from
+// f or decoder.
+func (dec *Decoder) FMap(f func(spock.SPOCK) error) error {
+	for dec.Next() {
+		if err := f(dec.Head()); err != nil {
+			return err
+		}
+	}
+	return dec.err
+}
+
+// Quads reads TriG document and groups statements by graph, the default
+// graph is identified by empty IRI.
+func Quads(r io.Reader, opts ...Option) (map[curie.IRI]spock.Bag, error) {
+	dec := NewDecoder(r, opts...)
+	quads := make(map[curie.IRI]spock.Bag)
+
+	for dec.Next() {
+		quads[dec.Graph()] = append(quads[dec.Graph()], dec.Head())
+	}
+
+	return quads, dec.Err()
+}
+
+//------------------------------------------------------------------------------
+
+type quad struct {
+	spock spock.SPOCK
+	graph curie.IRI
+}
+
+type parser struct {
+	s          string
+	at         int
+	base       string
+	namespaces curie.Namespaces
+	prefixes   curie.Prefixes
+	graph      curie.IRI
+	quads      []quad
+}
+
+func newParser(s string, prefixes curie.Prefixes) *parser {
+	return &parser{
+		s:          s,
+		namespaces: curie.Namespaces{},
+		prefixes:   prefixes,
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.s[:p.at], "\n")
+	col := p.at - strings.LastIndexByte(p.s[:p.at], '\n')
+	return fmt.Errorf("turtle line %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) eof() bool { return p.at >= len(p.s) }
+
+func (p *parser) peek(c byte) bool { return p.at < len(p.s) && p.s[p.at] == c }
+
+func (p *parser) accept(c byte) bool {
+	if p.peek(c) {
+		p.at++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(c byte) error {
+	p.skip()
+	if !p.accept(c) {
+		return p.errorf("expected '%c'", c)
+	}
+	return nil
+}
+
+// skips white spaces and comments
+func (p *parser) skip() {
+	for p.at < len(p.s) {
+		switch p.s[p.at] {
+		case ' ', '\t', '\r', '\n':
+			p.at++
+		case '#':
+			for p.at < len(p.s) && p.s[p.at] != '\n' {
+				p.at++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// case-insensitive SPARQL-style keyword (PREFIX, BASE, GRAPH)
+func (p *parser) keyword(kw string) bool {
+	end := p.at + len(kw)
+	if end > len(p.s) || !strings.EqualFold(p.s[p.at:end], kw) {
+		return false
+	}
+
+	return p.token(end)
+}
+
+// case-sensitive keyword (@prefix, @base, a, true, false)
+func (p *parser) literalKeyword(kw string) bool {
+	end := p.at + len(kw)
+	if end > len(p.s) || p.s[p.at:end] != kw {
+		return false
+	}
+
+	return p.token(end)
+}
+
+// keyword is not a prefix of name, it consumes input until end
+func (p *parser) token(end int) bool {
+	if end < len(p.s) {
+		if r, _ := utf8.DecodeRuneInString(p.s[end:]); isNameRune(r) || r == ':' {
+			return false
+		}
+	}
+
+	p.at = end
+	return true
+}
+
+func (p *parser) emit(at int, s, pred curie.IRI, o xsd.Value) {
+	p.quads[at] = quad{
+		spock: spock.SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(pred), O: o},
+		graph: p.graph,
+	}
+}
+
+// reserves slot for statement, it guarantees that statement is emitted
+// before statements of nested blank nodes and collections.
+func (p *parser) reserve() int {
+	p.quads = append(p.quads, quad{})
+	return len(p.quads) - 1
+}
+
+func (p *parser) blank() curie.IRI {
+	return curie.New("_:%s", guid.L(guid.Clock))
+}
+
+func (p *parser) rdf(name string) curie.IRI {
+	if p.prefixes != nil {
+		return curie.FromURI(p.prefixes, rdfNS+name)
+	}
+	return curie.IRI("rdf:" + name)
+}
+
+func (p *parser) compact(uri string) curie.IRI {
+	if p.prefixes != nil {
+		return curie.FromURI(p.prefixes, uri)
+	}
+	return curie.IRI(uri)
+}
+
+//------------------------------------------------------------------------------
+
+// block is either directive, triples or graph (TriG)
+func (p *parser) block() error {
+	switch {
+	case p.accept('@'):
+		switch {
+		case p.literalKeyword("prefix"):
+			if err := p.prefixDirective(); err != nil {
+				return err
+			}
+		case p.literalKeyword("base"):
+			if err := p.baseDirective(); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unknown directive")
+		}
+		return p.expect('.')
+	case p.keyword("PREFIX"):
+		return p.prefixDirective()
+	case p.keyword("BASE"):
+		return p.baseDirective()
+	case p.keyword("GRAPH"):
+		p.skip()
+		g, _, err := p.resource()
+		if err != nil {
+			return err
+		}
+		return p.graphBlock(g)
+	case p.peek('{'):
+		return p.graphBlock("")
+	case p.peek('[') || p.peek('('):
+		if err := p.triples(); err != nil {
+			return err
+		}
+		return p.expect('.')
+	}
+
+	s, _, err := p.resource()
+	if err != nil {
+		return err
+	}
+
+	p.skip()
+	if p.peek('{') {
+		return p.graphBlock(s)
+	}
+
+	if err := p.predicateObjectList(s); err != nil {
+		return err
+	}
+
+	return p.expect('.')
+}
+
+func (p *parser) prefixDirective() error {
+	p.skip()
+	start := p.at
+	for p.at < len(p.s) && p.s[p.at] != ':' {
+		r, size := utf8.DecodeRuneInString(p.s[p.at:])
+		if !isNameRune(r) {
+			return p.errorf("invalid prefix")
+		}
+		p.at += size
+	}
+	prefix := p.s[start:p.at]
+	if !p.accept(':') {
+		return p.errorf("expected ':'")
+	}
+
+	p.skip()
+	uri, err := p.iriref()
+	if err != nil {
+		return err
+	}
+
+	p.namespaces[prefix] = uri
+	return nil
+}
+
+func (p *parser) baseDirective() error {
+	p.skip()
+	uri, err := p.iriref()
+	if err != nil {
+		return err
+	}
+
+	p.base = uri
+	return nil
+}
+
+func (p *parser) graphBlock(g curie.IRI) error {
+	if err := p.expect('{'); err != nil {
+		return err
+	}
+
+	p.graph = g
+	defer func() { p.graph = "" }()
+
+	for {
+		p.skip()
+		if p.accept('}') {
+			return nil
+		}
+
+		if err := p.triples(); err != nil {
+			return err
+		}
+
+		p.skip()
+		if p.accept('.') {
+			continue
+		}
+
+		if !p.peek('}') {
+			return p.errorf("expected '.' or '}'")
+		}
+	}
+}
+
+// triples of subject
+func (p *parser) triples() error {
+	switch {
+	case p.peek('['):
+		s, empty, err := p.blankNodePropertyList()
+		if err != nil {
+			return err
+		}
+
+		p.skip()
+		if !empty && (p.eof() || p.peek('.') || p.peek('}')) {
+			return nil
+		}
+		return p.predicateObjectList(s)
+	case p.peek('('):
+		s, err := p.collection()
+		if err != nil {
+			return err
+		}
+		return p.predicateObjectList(s)
+	default:
+		s, _, err := p.resource()
+		if err != nil {
+			return err
+		}
+		return p.predicateObjectList(s)
+	}
+}
+
+func (p *parser) predicateObjectList(s curie.IRI) error {
+	for {
+		p.skip()
+		pred, err := p.verb()
+		if err != nil {
+			return err
+		}
+
+		for {
+			p.skip()
+			at := p.reserve()
+			o, err := p.object()
+			if err != nil {
+				return err
+			}
+			p.emit(at, s, pred, o)
+
+			p.skip()
+			if !p.accept(',') {
+				break
+			}
+		}
+
+		if !p.accept(';') {
+			return nil
+		}
+
+		for p.skip(); p.accept(';'); p.skip() {
+		}
+
+		if p.eof() || p.peek('.') || p.peek(']') || p.peek('}') {
+			return nil
+		}
+	}
+}
+
+func (p *parser) verb() (curie.IRI, error) {
+	if p.literalKeyword("a") {
+		return p.rdf("type"), nil
+	}
+
+	pred, blank, err := p.resource()
+	if err != nil {
+		return "", err
+	}
+
+	if blank {
+		return "", p.errorf("blank node %s is not allowed as predicate", pred)
+	}
+
+	return pred, nil
+}
+
+func (p *parser) object() (xsd.Value, error) {
+	if p.eof() {
+		return nil, p.errorf("expected object")
+	}
+
+	switch c := p.s[p.at]; {
+	case c == '"' || c == '\'':
+		return p.literal()
+	case c == '+' || c == '-' || c == '.' || ('0' <= c && c <= '9'):
+		return p.numeric()
+	case c == '[':
+		o, _, err := p.blankNodePropertyList()
+		if err != nil {
+			return nil, err
+		}
+		return xsd.ToAnyURI(o), nil
+	case c == '(':
+		o, err := p.collection()
+		if err != nil {
+			return nil, err
+		}
+		return xsd.ToAnyURI(o), nil
+	}
+
+	if p.literalKeyword("true") {
+		return spock.Boolean(true), nil
+	}
+
+	if p.literalKeyword("false") {
+		return spock.Boolean(false), nil
+	}
+
+	o, _, err := p.resource()
+	if err != nil {
+		return nil, err
+	}
+
+	return xsd.ToAnyURI(o), nil
+}
+
+// blank node property list [ p o ; ... ], the flag is true for []
+func (p *parser) blankNodePropertyList() (curie.IRI, bool, error) {
+	p.at++
+	s := p.blank()
+
+	p.skip()
+	if p.accept(']') {
+		return s, true, nil
+	}
+
+	if err := p.predicateObjectList(s); err != nil {
+		return "", false, err
+	}
+
+	if err := p.expect(']'); err != nil {
+		return "", false, err
+	}
+
+	return s, false, nil
+}
+
+// collection ( o1 o2 ... ) as rdf:first/rdf:rest chain
+func (p *parser) collection() (curie.IRI, error) {
+	p.at++
+
+	head := p.rdf("nil")
+	node := curie.IRI("")
+
+	for {
+		p.skip()
+		if p.accept(')') {
+			break
+		}
+
+		if p.eof() {
+			return "", p.errorf("unterminated collection")
+		}
+
+		next := p.blank()
+		if node == "" {
+			head = next
+		} else {
+			p.emit(p.reserve(), node, p.rdf("rest"), xsd.ToAnyURI(next))
+		}
+		node = next
+
+		at := p.reserve()
+		o, err := p.object()
+		if err != nil {
+			return "", err
+		}
+		p.emit(at, node, p.rdf("first"), o)
+	}
+
+	if node != "" {
+		p.emit(p.reserve(), node, p.rdf("rest"), xsd.ToAnyURI(p.rdf("nil")))
+	}
+
+	return head, nil
+}
+
+//------------------------------------------------------------------------------
+
+// resource is either IRI reference, prefixed name or blank node label.
+// The flag is true for blank nodes.
+func (p *parser) resource() (curie.IRI, bool, error) {
+	uri, iri, err := p.term()
+	if err != nil {
+		return "", false, err
+	}
+
+	return iri, uri == "", nil
+}
+
+// term returns absolute IRI and its decoded form. Absolute IRI is empty for
+// blank nodes.
+func (p *parser) term() (string, curie.IRI, error) {
+	if p.peek('<') {
+		uri, err := p.iriref()
+		if err != nil {
+			return "", "", err
+		}
+		return uri, p.compact(uri), nil
+	}
+
+	start := p.at
+	name, err := p.name()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix, local, found := strings.Cut(name, ":")
+	if !found {
+		p.at = start
+		return "", "", p.errorf("expected IRI")
+	}
+
+	if prefix == "_" {
+		if local == "" {
+			p.at = start
+			return "", "", p.errorf("empty blank node label")
+		}
+		return "", curie.IRI(name), nil
+	}
+
+	ns, has := p.namespaces[prefix]
+	if !has {
+		p.at = start
+		return "", "", p.errorf("undefined prefix %s", prefix)
+	}
+
+	if prefix != "" && p.prefixes == nil {
+		return ns + local, curie.IRI(name), nil
+	}
+
+	return ns + local, p.compact(ns + local), nil
+}
+
+// reads prefixed name or blank node label, escapes of local name are decoded
+func (p *parser) name() (string, error) {
+	var sb strings.Builder
+
+	for p.at < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.at:])
+		switch {
+		case r == '\\':
+			if p.at+1 >= len(p.s) || !strings.ContainsRune(`_~.-!$&'()*+,;=/?#@%`, rune(p.s[p.at+1])) {
+				return "", p.errorf("invalid escape")
+			}
+			sb.WriteByte(p.s[p.at+1])
+			p.at += 2
+		case r == ':' || r == '%' || r == '.' || isNameRune(r):
+			sb.WriteRune(r)
+			p.at += size
+		default:
+			return p.trimDots(sb.String()), nil
+		}
+	}
+
+	return p.trimDots(sb.String()), nil
+}
+
+// name do not end with '.', it is the end of statement
+func (p *parser) trimDots(name string) string {
+	for strings.HasSuffix(name, ".") && p.s[p.at-1] == '.' {
+		name = name[:len(name)-1]
+		p.at--
+	}
+	return name
+}
+
+// reads <IRI>, relative IRIs are resolved against base
+func (p *parser) iriref() (string, error) {
+	if !p.accept('<') {
+		return "", p.errorf("expected IRI")
+	}
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated IRI")
+		}
+
+		c := p.s[p.at]
+		switch {
+		case c == '>':
+			p.at++
+			return p.resolve(sb.String()), nil
+		case c == '\\':
+			r, err := p.uchar()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case c <= 0x20 || strings.IndexByte(`<"{}|^`+"`", c) != -1:
+			return "", p.errorf("invalid IRI character %q", c)
+		default:
+			sb.WriteByte(c)
+			p.at++
+		}
+	}
+}
+
+func (p *parser) resolve(uri string) string {
+	if p.base == "" {
+		return uri
+	}
+
+	base, err := url.Parse(p.base)
+	if err != nil {
+		return uri
+	}
+
+	ref, err := url.Parse(uri)
+	if err != nil || ref.IsAbs() {
+		return uri
+	}
+
+	return base.ResolveReference(ref).String()
+}
+
+// literal is either language tagged or typed, language tags are dropped.
+// Literals of data types not supported by codec.Typed are xsd:string.
+func (p *parser) literal() (xsd.Value, error) {
+	lit, err := p.quoted()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.accept('@'):
+		for p.at < len(p.s) {
+			c := p.s[p.at]
+			if !(c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')) {
+				break
+			}
+			p.at++
+		}
+		return xsd.String(lit), nil
+	case strings.HasPrefix(p.s[p.at:], "^^"):
+		p.at += 2
+		uri, _, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if uri == string(xsdAnyURI) {
+			return xsd.ToAnyURI(p.compact(lit)), nil
+		}
+		if val, ok := codec.Typed(lit, uri); ok {
+			return val, nil
+		}
+		return xsd.String(lit), nil
+	default:
+		return xsd.String(lit), nil
+	}
+}
+
+// reads "...", '...', """...""" or ”'...”'
+func (p *parser) quoted() (string, error) {
+	q := p.s[p.at : p.at+1]
+	long := strings.HasPrefix(p.s[p.at:], q+q+q)
+	if long {
+		q = q + q + q
+	}
+	p.at += len(q)
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated literal")
+		}
+
+		if strings.HasPrefix(p.s[p.at:], q) {
+			p.at += len(q)
+			return sb.String(), nil
+		}
+
+		c := p.s[p.at]
+		switch c {
+		case '\\':
+			if p.at+1 >= len(p.s) {
+				return "", p.errorf("invalid escape")
+			}
+			switch p.s[p.at+1] {
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'f':
+				sb.WriteByte('\f')
+			case '"':
+				sb.WriteByte('"')
+			case '\'':
+				sb.WriteByte('\'')
+			case '\\':
+				sb.WriteByte('\\')
+			case 'u', 'U':
+				r, err := p.uchar()
+				if err != nil {
+					return "", err
+				}
+				sb.WriteRune(r)
+				continue
+			default:
+				return "", p.errorf("invalid escape %q", p.s[p.at+1])
+			}
+			p.at += 2
+		case '\n', '\r':
+			if !long {
+				return "", p.errorf("unterminated literal")
+			}
+			sb.WriteByte(c)
+			p.at++
+		default:
+			sb.WriteByte(c)
+			p.at++
+		}
+	}
+}
+
+// reads integer, decimal or double in the lexical form (see codec.Number)
+func (p *parser) numeric() (xsd.Value, error) {
+	start := p.at
+	if p.peek('+') || p.peek('-') {
+		p.at++
+	}
+
+	digits := p.digits()
+	if p.peek('.') && p.at+1 < len(p.s) && isDigit(p.s[p.at+1]) {
+		p.at++
+		digits += p.digits()
+	}
+
+	if digits == 0 {
+		p.at = start
+		return nil, p.errorf("invalid number")
+	}
+
+	if p.peek('e') || p.peek('E') {
+		p.at++
+		if p.peek('+') || p.peek('-') {
+			p.at++
+		}
+		if p.digits() == 0 {
+			return nil, p.errorf("invalid exponent")
+		}
+	}
+
+	val, _ := codec.Number(p.s[start:p.at])
+	return val, nil
+}
+
+func (p *parser) digits() int {
+	n := 0
+	for p.at < len(p.s) && isDigit(p.s[p.at]) {
+		p.at++
+		n++
+	}
+	return n
+}
+
+// decodes \uXXXX or \UXXXXXXXX
+func (p *parser) uchar() (rune, error) {
+	if p.at+1 >= len(p.s) {
+		return 0, p.errorf("invalid escape")
+	}
+
+	n := 0
+	switch p.s[p.at+1] {
+	case 'u':
+		n = 4
+	case 'U':
+		n = 8
+	default:
+		return 0, p.errorf("invalid escape %q", p.s[p.at+1])
+	}
+
+	if p.at+2+n > len(p.s) {
+		return 0, p.errorf("invalid escape")
+	}
+
+	code, err := strconv.ParseUint(p.s[p.at+2:p.at+2+n], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape %s", p.s[p.at:p.at+2+n])
+	}
+	p.at += 2 + n
+
+	return rune(code), nil
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// PN_CHARS of prefixed names and blank node labels
+func isNameRune(r rune) bool {
+	switch {
+	case r == '_' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9'):
+		return true
+	case r == 0xB7 || (r >= 0xC0 && r != 0xD7 && r != 0xF7 && r != 0x37E && r != utf8.RuneError && !(0x2000 <= r && r <= 0x206F)):
+		return true
+	}
+	return false
+}
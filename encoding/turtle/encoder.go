@@ -0,0 +1,302 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package turtle
+
+//
+// The file define pretty-printer of Turtle and TriG documents. Statements
+// are grouped by subject and predicate, prefixes are declared for CURIEs
+// known to the application.
+//
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+// Encoder writes knowledge statements as Turtle, or TriG if graph is
+// configured.
+type Encoder struct {
+	w        *bufio.Writer
+	prefixes curie.Prefixes
+	graph    curie.IRI
+}
+
+// Create new encoder that writes to w. CURIEs are written as prefixed names
+// if prefixes are given, otherwise as IRIs.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	cfg := newConfig(opts)
+
+	return &Encoder{
+		w:        bufio.NewWriter(w),
+		prefixes: cfg.prefixes,
+		graph:    cfg.graph,
+	}
+}
+
+// Encode consumes stream and writes document to output
+func (enc *Encoder) Encode(stream spock.Stream) error {
+	bag := spock.Bag{}
+	if err := stream.FMap(bag.Join); err != nil {
+		return err
+	}
+
+	if err := enc.encode(bag); err != nil {
+		return err
+	}
+
+	return enc.w.Flush()
+}
+
+// node is statements of the subject grouped by predicate
+type node struct {
+	id   xsd.AnyURI
+	keys []xsd.AnyURI
+	vals map[xsd.AnyURI][]xsd.Value
+}
+
+func (enc *Encoder) encode(bag spock.Bag) error {
+	seq := make([]*node, 0)
+	nodes := make(map[xsd.AnyURI]*node)
+
+	for _, spock := range bag {
+		n, has := nodes[spock.S]
+		if !has {
+			n = &node{id: spock.S, vals: make(map[xsd.AnyURI][]xsd.Value)}
+			nodes[spock.S] = n
+			seq = append(seq, n)
+		}
+
+		if _, has := n.vals[spock.P]; !has {
+			n.keys = append(n.keys, spock.P)
+		}
+		n.vals[spock.P] = append(n.vals[spock.P], spock.O)
+	}
+
+	used := map[string]string{}
+	indent := ""
+	if enc.graph != "" {
+		indent = "    "
+	}
+
+	var body strings.Builder
+	for i, n := range seq {
+		if i > 0 {
+			body.WriteString("\n")
+		}
+
+		body.WriteString(indent)
+		body.WriteString(enc.subject(used, curie.IRI(n.id.String())))
+
+		for k, p := range n.keys {
+			if k > 0 {
+				body.WriteString(" ;\n" + indent + "    ")
+			} else {
+				body.WriteString(" ")
+			}
+			body.WriteString(enc.predicate(used, curie.IRI(p.String())))
+
+			for v, o := range n.vals[p] {
+				if v > 0 {
+					body.WriteString(" ,")
+				}
+				body.WriteString(" ")
+
+				switch o := o.(type) {
+				case xsd.AnyURI:
+					body.WriteString(enc.subject(used, curie.IRI(o.String())))
+				case xsd.String:
+					body.WriteString(literal(string(o)))
+				case spock.Integer, spock.Boolean:
+					body.WriteString(fmt.Sprint(o))
+				default:
+					lit, datatype, ok := codec.Lexical(o)
+					if !ok {
+						return fmt.Errorf("turtle codec do not support %T (%v)", o, o)
+					}
+					body.WriteString(literal(lit) + "^^" + iriref(datatype))
+				}
+			}
+		}
+		body.WriteString(" .\n")
+	}
+
+	if len(used) > 0 {
+		keys := make([]string, 0, len(used))
+		for prefix := range used {
+			keys = append(keys, prefix)
+		}
+		sort.Strings(keys)
+
+		for _, prefix := range keys {
+			fmt.Fprintf(enc.w, "@prefix %s: %s .\n", prefix, iriref(used[prefix]))
+		}
+		enc.w.WriteString("\n")
+	}
+
+	if enc.graph != "" {
+		enc.w.WriteString(enc.subject(used, enc.graph))
+		enc.w.WriteString(" {\n")
+		enc.w.WriteString(body.String())
+		enc.w.WriteString("}\n")
+		return nil
+	}
+
+	_, err := enc.w.WriteString(body.String())
+	return err
+}
+
+func (enc *Encoder) predicate(used map[string]string, iri curie.IRI) string {
+	if iri == rdfType || iri == rdfTypeIRI {
+		return "a"
+	}
+
+	if enc.prefixes != nil && curie.URI(enc.prefixes, iri) == string(rdfTypeIRI) {
+		return "a"
+	}
+
+	return enc.subject(used, iri)
+}
+
+func (enc *Encoder) subject(used map[string]string, iri curie.IRI) string {
+	if strings.HasPrefix(string(iri), "_:") {
+		return blank(string(iri)[2:])
+	}
+
+	if enc.prefixes == nil {
+		return iriref(string(iri))
+	}
+
+	prefix, local := curie.Prefix(iri), curie.Reference(iri)
+	ns, has := enc.prefixes.Lookup(prefix)
+	if prefix == "" || !has {
+		return iriref(string(iri))
+	}
+
+	if !isLocalName(local) {
+		return iriref(ns + local)
+	}
+
+	used[prefix] = ns
+	return prefix + ":" + local
+}
+
+func iriref(uri string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('<')
+	for _, r := range uri {
+		switch {
+		case r <= 0x20 || strings.ContainsRune(`<>"{}|^`+"`\\", r):
+			fmt.Fprintf(&sb, "\\u%04X", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('>')
+
+	return sb.String()
+}
+
+func literal(lit string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+	for _, r := range lit {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				fmt.Fprintf(&sb, "\\u%04X", r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+// reserved prefix of blank node labels written as hex
+const blankHex = "b."
+
+// writes blank node, the label which do not conform Turtle grammar
+// (e.g. ends with '.') is written as hex with reserved prefix. Labels that
+// starts with the reserved prefix are written as hex too, so that distinct
+// labels are never written as same one.
+func blank(label string) string {
+	valid := label != "" && label[0] != '-' && label[len(label)-1] != '.' &&
+		!strings.HasPrefix(label, blankHex)
+	for _, r := range label {
+		if !(r == '.' || isNameRune(r)) {
+			valid = false
+			break
+		}
+	}
+
+	if valid {
+		return "_:" + label
+	}
+
+	return "_:" + blankHex + "x" + hex.EncodeToString([]byte(label))
+}
+
+// local part of prefixed name which do not require escaping
+func isLocalName(local string) bool {
+	if local == "" {
+		return true
+	}
+
+	if local[0] == '-' || local[0] == '.' || local[len(local)-1] == '.' {
+		return false
+	}
+
+	for _, r := range local {
+		if !(r == '.' || r == ':' || isNameRune(r)) {
+			return false
+		}
+	}
+
+	return true
+}
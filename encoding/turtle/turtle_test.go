@@ -0,0 +1,317 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package turtle_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/encoding/turtle"
+)
+
+func TestTurtleUnmarshal(t *testing.T) {
+	guid.Clock = guid.NewClockMock()
+	luid := curie.IRI("_:5...............")
+
+	Codec := func(t *testing.T, input string) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		bag := turtle.Bag{}
+		err := bag.UnmarshalText([]byte(input))
+		it.Then(t).Should(it.Nil(err))
+
+		return it.Seq(bag)
+	}
+
+	t.Run("Triple", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> <prop> "title" .`).Equal(
+				spock.From("a", "prop", "title"),
+			),
+		)
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				@prefix schema: <https://schema.org/> .
+				PREFIX : <https://example.com/>
+				schema:a schema:name "A" ; schema:knows :b .
+			`).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+				spock.From("schema:a", "schema:knows", curie.IRI("https://example.com/b")),
+			),
+		)
+	})
+
+	t.Run("Base", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				@base <https://example.com/a/> .
+				<b> <c> <../d> .
+			`).Equal(
+				spock.From("https://example.com/a/b", "https://example.com/a/c", curie.IRI("https://example.com/d")),
+			),
+		)
+	})
+
+	t.Run("Shorthand", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> a <T> ; <p> "x", "y" ;; <q> <b> ; .`).Equal(
+				spock.From("a", "rdf:type", curie.IRI("T")),
+				spock.From("a", "p", "x"),
+				spock.From("a", "p", "y"),
+				spock.From("a", "q", curie.IRI("b")),
+			),
+		)
+	})
+
+	t.Run("Literals", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `
+				@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+				<a> <p> 'x', """multi
+"line" end""", "y"@en, "z"^^xsd:string, "b"^^xsd:anyURI, "ä\n",
+				"7"^^xsd:int, "2023-01-01"^^xsd:date .
+			`).Equal(
+				spock.From("a", "p", "x"),
+				spock.From("a", "p", "multi\n\"line\" end"),
+				spock.From("a", "p", "y"),
+				spock.From("a", "p", "z"),
+				spock.From("a", "p", curie.IRI("b")),
+				spock.From("a", "p", "ä\n"),
+				spock.From("a", "p", 7),
+				spock.From("a", "p", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+			),
+		)
+	})
+
+	t.Run("Numeric", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> <p> 1, -2.5, .5, 1e10, +3.0E-2, true, false, 12345678901234567890.`).Equal(
+				spock.From("a", "p", 1),
+				spock.From("a", "p", -2.5),
+				spock.From("a", "p", 0.5),
+				spock.From("a", "p", 1e10),
+				spock.From("a", "p", 3.0e-2),
+				spock.From("a", "p", true),
+				spock.From("a", "p", false),
+				spock.From("a", "p", "12345678901234567890"),
+			),
+		)
+	})
+
+	t.Run("BlankNode", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `_:x <p> [ <q> "title" ] . [ <q> _:x ] .`).Equal(
+				spock.From("_:x", "p", luid),
+				spock.From(luid, "q", "title"),
+				spock.From(luid, "q", curie.IRI("_:x")),
+			),
+		)
+	})
+
+	t.Run("Collection", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, `<a> <p> ( "x" ), () .`).Equal(
+				spock.From("a", "p", luid),
+				spock.From(luid, "rdf:first", "x"),
+				spock.From(luid, "rdf:rest", curie.IRI("rdf:nil")),
+				spock.From("a", "p", curie.IRI("rdf:nil")),
+			),
+		)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		it.Then(t).Should(
+			Codec(t, "# comment\n<a> <p> \"#title\" . # comment\n").Equal(
+				spock.From("a", "p", "#title"),
+			),
+		)
+	})
+
+	t.Run("WithPrefixes", func(t *testing.T) {
+		dec := turtle.NewDecoder(
+			strings.NewReader(`
+				@prefix s: <https://schema.org/> .
+				<https://schema.org/a> s:name "A" .
+			`),
+			turtle.WithPrefixes(curie.Namespaces{"schema": "https://schema.org/"}),
+		)
+		bag := spock.Bag{}
+
+		it.Then(t).Should(
+			it.Nil(dec.FMap(bag.Join)),
+			it.Seq(bag).Equal(
+				spock.From("schema:a", "schema:name", "A"),
+			),
+			it.Equal(dec.Prefixes()["s"], "https://schema.org/"),
+		)
+	})
+
+	t.Run("TriG", func(t *testing.T) {
+		quads, err := turtle.Quads(strings.NewReader(`
+			@prefix ex: <https://example.com/> .
+			<a> <p> "x" .
+			ex:g { <a> <p> "y" . <b> <p> "z" }
+			GRAPH <h> { <c> <p> "w" }
+			{ <d> <p> "v" }
+		`))
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(quads[""]).Equal(
+				spock.From("a", "p", "x"),
+				spock.From("d", "p", "v"),
+			),
+			it.Seq(quads["ex:g"]).Equal(
+				spock.From("a", "p", "y"),
+				spock.From("b", "p", "z"),
+			),
+			it.Seq(quads["h"]).Equal(spock.From("c", "p", "w")),
+		)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, input := range []string{
+			`<a> <p> "title"`,
+			`<a> "p" "title" .`,
+			`<a> <p> "title .`,
+			`ex:a <p> "title" .`,
+			`<a> _:p "title" .`,
+			`<a> <p> ( "x" .`,
+			`<a> <p> [ <q> "x" .`,
+			`@prefixes ex: <a> .`,
+			`<g> { <a> <p> "x" `,
+		} {
+			bag := turtle.Bag{}
+			it.Then(t).ShouldNot(
+				it.Nil(bag.UnmarshalText([]byte(input))),
+			)
+		}
+	})
+}
+
+func TestTurtleMarshal(t *testing.T) {
+	Codec := func(t *testing.T, bag spock.Bag, opts ...turtle.Option) string {
+		t.Helper()
+		var buf bytes.Buffer
+		enc := turtle.NewEncoder(&buf, opts...)
+		it.Then(t).Should(it.Nil(enc.Encode(bag.Stream())))
+
+		return buf.String()
+	}
+
+	bag := spock.Bag{
+		spock.From("schema:a", "rdf:type", curie.IRI("schema:Person")),
+		spock.From("schema:a", "schema:name", "A"),
+		spock.From("schema:b", "schema:name", "B\n"),
+		spock.From("schema:a", "schema:knows", curie.IRI("schema:b")),
+		spock.From("schema:a", "schema:knows", curie.IRI("_:c.")),
+	}
+
+	t.Run("Plain", func(t *testing.T) {
+		b, err := turtle.Bag(bag[1:3]).MarshalText()
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(string(b), "<schema:a> <schema:name> \"A\" .\n\n<schema:b> <schema:name> \"B\\n\" .\n"),
+		)
+	})
+
+	t.Run("Pretty", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, bag, turtle.WithPrefixes(curie.Namespaces{"schema": "https://schema.org/"})),
+				`@prefix schema: <https://schema.org/> .
+
+schema:a a schema:Person ;
+    schema:name "A" ;
+    schema:knows schema:b , _:b.x632e .
+
+schema:b schema:name "B\n" .
+`,
+			),
+		)
+	})
+
+	t.Run("BlankNodeCollision", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, spock.Bag{
+					spock.From("_:ac.", "schema:knows", curie.IRI("_:b61632e")),
+					spock.From("_:b.x61632e", "schema:knows", curie.IRI("_:b.")),
+				}),
+				"_:b.x61632e <schema:knows> _:b61632e .\n\n"+
+					"_:b.x622e78363136333265 <schema:knows> _:b.x622e .\n",
+			),
+		)
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		typed := spock.Bag{
+			spock.From("a", "p", -42),
+			spock.From("a", "p", 0.5),
+			spock.From("a", "p", true),
+			spock.From("a", "p", time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+		}
+		doc := Codec(t, typed)
+
+		seq := spock.Bag{}
+		it.Then(t).Should(
+			it.Equal(doc,
+				`<a> <p> -42 , "0.5"^^<http://www.w3.org/2001/XMLSchema#double> , true , `+
+					`"2023-01-02T03:04:05Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+`,
+			),
+			it.Nil(turtle.NewDecoder(strings.NewReader(doc)).FMap(seq.Join)),
+			it.Seq(seq).Equal(typed...),
+		)
+	})
+
+	t.Run("TriG", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Equal(
+				Codec(t, bag[1:2], turtle.WithGraph("g")),
+				"<g> {\n    <schema:a> <schema:name> \"A\" .\n}\n",
+			),
+		)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		prefixes := curie.Namespaces{
+			"schema": "https://schema.org/",
+			"rdf":    "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+		}
+		doc := Codec(t, bag[:4], turtle.WithPrefixes(prefixes))
+
+		dec := turtle.NewDecoder(strings.NewReader(doc), turtle.WithPrefixes(prefixes))
+		seq := spock.Bag{}
+		it.Then(t).Should(
+			it.Nil(dec.FMap(seq.Join)),
+			it.Seq(seq).Equal(bag[0], bag[1], bag[3], bag[2]),
+		)
+	})
+}
@@ -0,0 +1,71 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package codec
+
+//
+// The file define strategies of minting blank node identifiers, they are
+// shared by JSON and JSON-LD codecs.
+//
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/guid/v2"
+)
+
+// BlankNode mints identifier of blank node. It is called with subject and
+// predicate that refer the node (empty for top-level nodes) and the JSON
+// value of the node.
+type BlankNode func(s, p curie.IRI, node any) curie.IRI
+
+// ClockBlankNode mints unique identifier using guid.Clock, identifiers are
+// different each time the document is decoded. It is the default strategy.
+func ClockBlankNode(curie.IRI, curie.IRI, any) curie.IRI {
+	return curie.New("_:%s", guid.L(guid.Clock))
+}
+
+// HashBlankNode mints identifier from the content of the node, the subject
+// and predicate that refer it. Identifiers are stable across runs, so that
+// re-imports of the same document are idempotent. Structurally identical
+// nodes of the same subject and predicate are merged.
+func HashBlankNode(s, p curie.IRI, node any) curie.IRI {
+	// Note: json.Marshal sorts keys of maps, the output is deterministic
+	b, _ := json.Marshal(node)
+
+	h := sha256.New()
+	h.Write([]byte(s))
+	h.Write([]byte{0})
+	h.Write([]byte(p))
+	h.Write([]byte{0})
+	h.Write(b)
+
+	return curie.New("_:%x", h.Sum(nil)[:16])
+}
+
+// SeqBlankNode mints identifiers from caller-supplied generator, e.g. a
+// sequence of labels b0, b1, ...
+func SeqBlankNode(f func() string) BlankNode {
+	return func(curie.IRI, curie.IRI, any) curie.IRI {
+		return curie.IRI("_:" + f())
+	}
+}
@@ -0,0 +1,133 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package codec_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/spock/internal/codec"
+	"github.com/kshard/xsd"
+)
+
+func TestDateTime(t *testing.T) {
+	for input, expect := range map[string]time.Time{
+		"2023-01-02":                time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2023-01-02T03:04:05Z":      time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2023-01-02T03:04:05+02:00": time.Date(2023, 1, 2, 1, 4, 5, 0, time.UTC),
+		"2023-01-02T03:04:05.5":     time.Date(2023, 1, 2, 3, 4, 5, 5e8, time.UTC),
+	} {
+		val, ok := codec.DateTime(input)
+		it.Then(t).Should(
+			it.True(ok),
+			it.True(val.Equal(expect)),
+		)
+	}
+
+	for _, input := range []string{"", "2023", "2023-13-01", "1234-56-7890", "tomorrow"} {
+		_, ok := codec.DateTime(input)
+		it.Then(t).ShouldNot(
+			it.True(ok),
+		)
+	}
+}
+
+func TestHashBlankNode(t *testing.T) {
+	a := codec.HashBlankNode("s", "p", map[string]any{"a": "1", "b": "2"})
+	b := codec.HashBlankNode("s", "p", map[string]any{"b": "2", "a": "1"})
+	c := codec.HashBlankNode("s", "q", map[string]any{"a": "1", "b": "2"})
+
+	it.Then(t).Should(
+		it.Equal(a, b),
+	).ShouldNot(
+		it.Equal(a, c),
+	)
+}
+
+func TestLiteral(t *testing.T) {
+	for input, expect := range map[any]xsd.Value{
+		json.Number("10"):                   spock.Integer(10),
+		json.Number("9007199254740993"):     spock.Integer(9007199254740993),
+		json.Number("1.5e300"):              spock.Double(1.5e300),
+		json.Number("12345678901234567890"): xsd.String("12345678901234567890"),
+		true:                                spock.Boolean(true),
+		"text":                              xsd.String("text"),
+		"2023-01-02T03:04:05.5":             spock.DateTime(time.Date(2023, 1, 2, 3, 4, 5, 5e8, time.UTC)),
+	} {
+		val, ok := codec.Literal(input, true)
+		it.Then(t).Should(
+			it.True(ok),
+			it.Equal(spock.Compare(val, expect), 0),
+		)
+	}
+
+	for _, input := range []any{nil, map[string]any{}, []any{}} {
+		_, ok := codec.Literal(input, true)
+		it.Then(t).ShouldNot(
+			it.True(ok),
+		)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	for _, tt := range []struct {
+		lit, datatype string
+		expect        xsd.Value
+	}{
+		{"-42", codec.XSD + "integer", spock.Integer(-42)},
+		{"+7", "xsd:long", spock.Integer(7)},
+		{"1.5E2", codec.XSD + "double", spock.Double(150)},
+		{"-INF", codec.XSD + "float", spock.Double(math.Inf(-1))},
+		{"0.25", codec.XSD + "decimal", spock.Double(0.25)},
+		{"1", codec.XSD + "boolean", spock.Boolean(true)},
+		{"2023-01-02", codec.XSD + "date", spock.DateTime(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC))},
+	} {
+		val, ok := codec.Typed(tt.lit, tt.datatype)
+		it.Then(t).Should(
+			it.True(ok),
+			it.Equal(spock.Compare(val, tt.expect), 0),
+		)
+
+		lit, datatype, ok := codec.Lexical(val)
+		back, _ := codec.Typed(lit, datatype)
+		it.Then(t).Should(
+			it.True(ok),
+			it.Equal(spock.Compare(back, val), 0),
+		)
+	}
+
+	for _, tt := range [][2]string{
+		{"1.5", codec.XSD + "integer"},
+		{"0x10", codec.XSD + "double"},
+		{"yes", codec.XSD + "boolean"},
+		{"42", codec.XSD + "gYear"},
+		{"42", "http://example.com/integer"},
+	} {
+		_, ok := codec.Typed(tt[0], tt[1])
+		it.Then(t).ShouldNot(
+			it.True(ok),
+		)
+	}
+}
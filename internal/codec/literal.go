@@ -0,0 +1,55 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package codec
+
+//
+// The file define xsd values of JSON scalars, it is shared by JSON and
+// JSON-LD codecs.
+//
+
+import (
+	"encoding/json"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// Literal returns xsd value of JSON scalar: number, boolean or string. The
+// flag is false if value is not scalar. Numbers are never converted through
+// float64, integers keep their precision (see Number). ISO-8601 date and
+// time strings are spock.DateTime if datetime is enabled.
+func Literal(val any, datetime bool) (xsd.Value, bool) {
+	switch o := val.(type) {
+	case json.Number:
+		return Number(string(o))
+	case bool:
+		return spock.Boolean(o), true
+	case string:
+		if datetime {
+			if t, ok := DateTime(o); ok {
+				return spock.DateTime(t), true
+			}
+		}
+		return xsd.String(o), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,160 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package codec
+
+//
+// The file define lexical forms of typed literals, it is shared by codecs.
+//
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// XSD is the namespace of xsd data types
+const XSD = "http://www.w3.org/2001/XMLSchema#"
+
+// data types of literals, decoded to data types of spock package
+var datatypes = map[string]func(string) (xsd.Value, bool){
+	"integer":            integer,
+	"int":                integer,
+	"long":               integer,
+	"short":              integer,
+	"byte":               integer,
+	"nonNegativeInteger": integer,
+	"nonPositiveInteger": integer,
+	"positiveInteger":    integer,
+	"negativeInteger":    integer,
+	"unsignedInt":        integer,
+	"unsignedShort":      integer,
+	"unsignedByte":       integer,
+	"double":             double,
+	"float":              double,
+	"decimal":            double,
+	"boolean":            boolean,
+	"dateTime":           dateTime,
+	"date":               dateTime,
+}
+
+// Typed decodes lexical form of literal of the datatype. The datatype is
+// either absolute IRI or CURIE of xsd namespace. The flag is false if the
+// datatype is not supported or the lexical form is not valid.
+func Typed(lit string, datatype string) (xsd.Value, bool) {
+	name, ok := strings.CutPrefix(datatype, XSD)
+	if !ok {
+		name, ok = strings.CutPrefix(datatype, "xsd:")
+	}
+	if !ok {
+		return nil, false
+	}
+
+	f, has := datatypes[name]
+	if !has {
+		return nil, false
+	}
+
+	return f(strings.TrimSpace(lit))
+}
+
+// Lexical returns lexical form and absolute IRI of data type of the value.
+// The flag is false if value is neither spock.Integer, spock.Double,
+// spock.Boolean nor spock.DateTime.
+func Lexical(val xsd.Value) (string, string, bool) {
+	switch v := val.(type) {
+	case spock.Integer:
+		return v.String(), XSD + "integer", true
+	case spock.Double:
+		return v.String(), XSD + "double", true
+	case spock.Boolean:
+		return v.String(), XSD + "boolean", true
+	case spock.DateTime:
+		return v.String(), XSD + "dateTime", true
+	default:
+		return "", "", false
+	}
+}
+
+// Number decodes lexical form of JSON number. Integers are spock.Integer,
+// numbers with fraction or exponent are spock.Double. Integers above 64-bit
+// are kept in lexical form as xsd:string, so that the precision of large
+// identifiers is not lost.
+func Number(lit string) (xsd.Value, bool) {
+	if v, ok := integer(lit); ok {
+		return v, true
+	}
+
+	if strings.Trim(lit, "+-0123456789") == "" {
+		return xsd.String(lit), true
+	}
+
+	return double(lit)
+}
+
+func integer(lit string) (xsd.Value, bool) {
+	v, err := strconv.ParseInt(strings.TrimPrefix(lit, "+"), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return spock.Integer(v), true
+}
+
+func double(lit string) (xsd.Value, bool) {
+	switch lit {
+	case "INF", "+INF":
+		lit = "+Inf"
+	case "-INF":
+		lit = "-Inf"
+	case "NaN":
+	default:
+		// rejects Go specific forms (e.g. 0x1p-2, Inf, 1_000)
+		if strings.ContainsAny(lit, "xXpP_iInN") {
+			return nil, false
+		}
+	}
+
+	v, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return nil, false
+	}
+	return spock.Double(v), true
+}
+
+func boolean(lit string) (xsd.Value, bool) {
+	switch lit {
+	case "true", "1":
+		return spock.Boolean(true), true
+	case "false", "0":
+		return spock.Boolean(false), true
+	default:
+		return nil, false
+	}
+}
+
+func dateTime(lit string) (xsd.Value, bool) {
+	t, ok := DateTime(lit)
+	if !ok {
+		return nil, false
+	}
+	return spock.DateTime(t), true
+}
@@ -81,32 +81,31 @@ func (iri) Equal(value curie.IRI) *Predicate[xsd.AnyURI] {
 }
 
 // Makes `prefix` to IRI predicate
-// func (iri) HasPrefix(value curie.IRI) *Predicate[xsd.AnyURI] {
-// 	return &Predicate[xsd.AnyURI]{Clause: PQ, Value: xsd.AnyURI(symbol.New(string(value)))}
-// 	// return &Predicate[curie.IRI]{Clause: PQ, Value: value}
-// }
+func (iri) HasPrefix(value curie.IRI) *Predicate[xsd.AnyURI] {
+	return &Predicate[xsd.AnyURI]{Clause: PQ, Value: xsd.ToAnyURI(value)}
+}
 
 // Makes `equal to` value predicate
-func Eq[T xsd.DataType](value T) *Predicate[xsd.Value] {
-	return &Predicate[xsd.Value]{Clause: EQ, Value: xsd.From(value)}
+func Eq[T DataType](value T) *Predicate[xsd.Value] {
+	return &Predicate[xsd.Value]{Clause: EQ, Value: ValueOf(value)}
 }
 
 // Makes `prefix` value predicate
-func HasPrefix[T xsd.DataType](value T) *Predicate[xsd.Value] {
-	return &Predicate[xsd.Value]{Clause: PQ, Value: xsd.From(value)}
+func HasPrefix[T DataType](value T) *Predicate[xsd.Value] {
+	return &Predicate[xsd.Value]{Clause: PQ, Value: ValueOf(value)}
 }
 
 // Makes `less than` value predicate
-func Lt[T xsd.DataType](value T) *Predicate[xsd.Value] {
-	return &Predicate[xsd.Value]{Clause: LT, Value: xsd.From(value)}
+func Lt[T DataType](value T) *Predicate[xsd.Value] {
+	return &Predicate[xsd.Value]{Clause: LT, Value: ValueOf(value)}
 }
 
 // Makes `greater than` value predicate
-func Gt[T xsd.DataType](value T) *Predicate[xsd.Value] {
-	return &Predicate[xsd.Value]{Clause: GT, Value: xsd.From(value)}
+func Gt[T DataType](value T) *Predicate[xsd.Value] {
+	return &Predicate[xsd.Value]{Clause: GT, Value: ValueOf(value)}
 }
 
 // Makes `in range` predicate
-func In[T xsd.DataType](from, to T) *Predicate[xsd.Value] {
-	return &Predicate[xsd.Value]{Clause: IN, Value: xsd.From(from), Other: xsd.From(to)}
+func In[T DataType](from, to T) *Predicate[xsd.Value] {
+	return &Predicate[xsd.Value]{Clause: IN, Value: ValueOf(from), Other: ValueOf(to)}
 }
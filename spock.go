@@ -18,6 +18,13 @@
 
 */
 
+// Package spock defines knowledge statements ⟨s, p, o, c, k⟩, the DSL for
+// pattern queries and streams of statements.
+//
+// Objects of statements are xsd values: xsd.AnyURI, xsd.String and data
+// types Integer, Double, Boolean and DateTime defined by the package (see
+// ValueOf, Compare). Codecs decode literals of other xsd data types, and
+// language-tagged strings, as xsd.String of their lexical form.
 package spock
 
 //
@@ -46,8 +53,8 @@ func (spock SPOCK) String() string {
 }
 
 // Create new knowledge statement From
-func From[T xsd.DataType](s, p curie.IRI, o T) SPOCK {
-	return SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: xsd.From(o)}
+func From[T DataType](s, p curie.IRI, o T) SPOCK {
+	return SPOCK{S: xsd.ToAnyURI(s), P: xsd.ToAnyURI(p), O: ValueOf(o)}
 }
 
 // Collection of knowledge statements
@@ -0,0 +1,277 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define bulk loader of statements. Statements are grouped by the
+// key of index permutation, values of the same key are merged into single
+// set. Entries are written by transactions of up to 100 items with bounded
+// concurrency, throttled transactions are retried with backoff.
+//
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+// the limit of items in single transaction
+const maxTransactItems = 100
+
+// LoadOption configures bulk loader
+type LoadOption func(*loader)
+
+type loader struct {
+	concurrency int
+	attempts    int
+	backoff     time.Duration
+}
+
+// WithConcurrency bounds number of concurrent transactions, default is 4.
+func WithConcurrency(n int) LoadOption {
+	return func(l *loader) {
+		if n > 0 {
+			l.concurrency = n
+		}
+	}
+}
+
+// WithRetry configures number of attempts for throttled transactions and
+// initial backoff, the backoff is doubled for each attempt. Default is
+// 5 attempts starting from 100ms.
+func WithRetry(attempts int, backoff time.Duration) LoadOption {
+	return func(l *loader) {
+		if attempts > 0 {
+			l.attempts = attempts
+		}
+		l.backoff = backoff
+	}
+}
+
+// entry of index permutation merged from multiple statements
+type entry struct {
+	hash, sort curie.IRI
+	attr       string
+	vals       []string
+	size       int
+	seen       map[string]struct{}
+	stmts      []int
+}
+
+func (e *entry) HashKey() curie.IRI        { return e.hash }
+func (e *entry) SortKey() curie.IRI        { return e.sort }
+func (e *entry) SetOf() (string, []string) { return e.attr, e.vals }
+
+// Load statements into the graph using batched transactions. Unlike Add,
+// the statement is not written atomically, it returns statements that are
+// not written completely on failure. Loading them again is safe.
+func Load(ctx context.Context, store *Store, graph curie.IRI, bag spock.Bag, opts ...LoadOption) (spock.Bag, error) {
+	l := &loader{concurrency: 4, attempts: 5, backoff: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	for _, x := range bag {
+		if err := checkStatement(x); err != nil {
+			return bag, err
+		}
+	}
+
+	chunks := chunksOf(groupBy(store, graph, bag))
+	failed := make([]error, len(chunks))
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < l.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				failed[i] = l.transact(ctx, store.table, graph, chunks[i])
+			}
+		}()
+	}
+
+	for i := range chunks {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	// statements of failed chunks are unprocessed
+	var errs []error
+	unprocessed := make([]bool, len(bag))
+	for i, err := range failed {
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		for _, w := range chunks[i] {
+			for _, k := range w.(*entry).stmts {
+				unprocessed[k] = true
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	seq := spock.Bag{}
+	for i, x := range bag {
+		if unprocessed[i] {
+			seq = append(seq, x)
+		}
+	}
+
+	return seq, errors.Join(errs...)
+}
+
+// groups statements by keys of index permutations, the order is preserved
+func groupBy(store *Store, graph curie.IRI, bag spock.Bag) []*entry {
+	seq := make([]*entry, 0)
+	keys := map[[2]curie.IRI]*entry{}
+
+	for i, x := range bag {
+		for _, w := range store.writers(graph, x) {
+			key := [2]curie.IRI{w.HashKey(), w.SortKey()}
+			attr, vals := w.SetOf()
+
+			e, has := keys[key]
+			seen := map[string]struct{}{}
+			if has {
+				seen = e.seen
+			}
+
+			fresh := make([]string, 0, len(vals))
+			for _, v := range vals {
+				if _, has := seen[v]; !has {
+					fresh = append(fresh, v)
+				}
+			}
+
+			// the entry is split if it exceeds the limit of set
+			if !has || e.size+sizeOf(fresh) > store.table.size {
+				e = &entry{hash: key[0], sort: key[1], attr: attr, seen: seen}
+				keys[key] = e
+				seq = append(seq, e)
+			}
+
+			for _, v := range fresh {
+				e.seen[v] = struct{}{}
+				e.vals = append(e.vals, v)
+				e.size += len(v)
+			}
+			e.stmts = append(e.stmts, i)
+		}
+	}
+
+	return seq
+}
+
+// splits entries into chunks of transaction size, the transaction cannot
+// write the same item twice, split entries are placed to different chunks.
+func chunksOf(seq []*entry) [][]Writer {
+	chunks := make([][]Writer, 0)
+	chunk := make([]Writer, 0, maxTransactItems)
+	keys := map[[2]curie.IRI]struct{}{}
+
+	for _, e := range seq {
+		key := [2]curie.IRI{e.hash, e.sort}
+		if _, has := keys[key]; has || len(chunk) == maxTransactItems {
+			chunks = append(chunks, chunk)
+			chunk = make([]Writer, 0, maxTransactItems)
+			keys = map[[2]curie.IRI]struct{}{}
+		}
+
+		chunk = append(chunk, e)
+		keys[key] = struct{}{}
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// transact writes chunk, retrying throttled transactions with backoff
+func (l *loader) transact(ctx context.Context, t *table, graph curie.IRI, chunk []Writer) error {
+	backoff := l.backoff
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := t.transact(ctx, "ADD", graph, chunk)
+		if err == nil || attempt >= l.attempts || !isThrottled(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isThrottled returns true if the transaction is failed due to capacity
+// or conflicts and it is safe to retry it.
+func isThrottled(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if reason.Code == nil {
+				continue
+			}
+			switch *reason.Code {
+			case "None", "ThrottlingError", "TransactionConflict", "ProvisionedThroughputExceeded":
+			default:
+				return false
+			}
+		}
+		return true
+	}
+
+	var api smithy.APIError
+	if errors.As(err, &api) {
+		switch api.ErrorCode() {
+		case "ThrottlingException",
+			"ProvisionedThroughputExceededException",
+			"RequestLimitExceeded",
+			"TransactionInProgressException",
+			"TransactionConflictException",
+			"InternalServerError":
+			return true
+		}
+	}
+
+	return false
+}
@@ -21,10 +21,15 @@
 package dynamo
 
 import (
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fogfish/curie"
-	"github.com/kshard/spock/xsd"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
 )
 
 //
@@ -40,7 +45,7 @@ func encodeII(a, b curie.IRI) string {
 }
 
 func decodeII(val string) (curie.IRI, curie.IRI) {
-	seq := strings.SplitN(val, "|", 2)
+	seq := strings.SplitN(trimContinuation(val), "|", 2)
 	return curie.IRI(seq[0]), curie.IRI(seq[1])
 }
 
@@ -49,7 +54,7 @@ func encodeIV(a curie.IRI, b xsd.Value) string {
 }
 
 func decodeIV(val string) (curie.IRI, xsd.Value) {
-	seq := strings.SplitN(val, "|", 2)
+	seq := strings.SplitN(trimContinuation(val), "|", 2)
 	return curie.IRI(seq[0]), decodeValue(seq[1])
 }
 
@@ -58,32 +63,175 @@ func encodeVI(a xsd.Value, b curie.IRI) string {
 }
 
 func decodeVI(val string) (xsd.Value, curie.IRI) {
-	seq := strings.SplitN(val, "|", 2)
+	seq := strings.SplitN(trimContinuation(val), "|", 2)
 	return decodeValue(seq[0]), curie.IRI(seq[1])
 }
 
 //
 // Value codec - ᴸᴵᴳ
 //
+// Values are encoded as type tag followed by the form of the value that
+// preserves the order of values within the type, the sort key range queries
+// are correct for each type:
+//
+//	ᴵ xsd:anyURI    IRI
+//	ᴸ xsd:string    string
+//	ᴺ xsd:integer   16 hex digits of int64 with flipped sign bit
+//	ᴰ xsd:double    16 hex digits of IEEE 754 bits, negative values are
+//	                inverted, non-negative values have flipped sign bit
+//	ᴮ xsd:boolean   0 or 1
+//	ᵀ xsd:dateTime  fixed width UTC 2006-01-02T15:04:05.000000000Z
+//
+// The next code point after the tag is the upper bound of the type, it is
+// never used as a tag.
+//
+// Note: values of other data types, and date-time outside of years 0000 -
+// 9999, are rejected by the store with error rather than crash the writer.
+// The character \x1f is reserved by sort keys of continuation items (see
+// overflow), statements that contain it are rejected as well.
+//
+
+const (
+	tagAnyURI   = "ᴵ"
+	tagString   = "ᴸ"
+	tagInteger  = "ᴺ"
+	tagDouble   = "ᴰ"
+	tagBoolean  = "ᴮ"
+	tagDateTime = "ᵀ"
 
+	// fixed width layout of date-time, lexical order is chronological order
+	dateTimeLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// errNotSupported is returned if data type of value is not supported by codec
+type errNotSupported struct{ xsd.Value }
+
+func (err errNotSupported) Error() string {
+	return fmt.Sprintf("dynamo codec do not support %T (%v)", err.Value, err.Value)
+}
+func (errNotSupported) NotSupported() {}
+
+// checkValue validates that value is supported by codec
+func checkValue(value xsd.Value) error {
+	switch v := value.(type) {
+	case xsd.AnyURI, xsd.String, spock.Integer, spock.Double, spock.Boolean:
+		return nil
+	case spock.DateTime:
+		if year := time.Time(v).UTC().Year(); year < 0 || year > 9999 {
+			return errNotSupported{value}
+		}
+		return nil
+	default:
+		return errNotSupported{value}
+	}
+}
+
+// errReserved is returned if statement contains the separator of
+// continuation items, the sort key of such statement is ambiguous.
+type errReserved struct{ string }
+
+func (err errReserved) Error() string {
+	return fmt.Sprintf("dynamo codec do not support %q, \\x1f is reserved", err.string)
+}
+func (errReserved) NotSupported() {}
+
+// checkStatement validates that statement is supported by codec
+func checkStatement(x spock.SPOCK) error {
+	if err := checkValue(x.O); err != nil {
+		return err
+	}
+
+	for _, v := range []string{x.S.String(), x.P.String(), encodeValue(x.O)} {
+		if strings.Contains(v, continuation) {
+			return errReserved{v}
+		}
+	}
+
+	return nil
+}
+
+// encodeValue expects value validated by checkValue
 func encodeValue(value xsd.Value) string {
 	switch v := value.(type) {
 	case xsd.AnyURI:
-		return "ᴵ" + string(v)
+		return tagAnyURI + v.String()
 	case xsd.String:
-		return "ᴸ" + string(v)
+		return tagString + string(v)
+	case spock.Integer:
+		return tagInteger + fmt.Sprintf("%016x", uint64(v)^(1<<63))
+	case spock.Double:
+		bits := math.Float64bits(float64(v))
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		return tagDouble + fmt.Sprintf("%016x", bits)
+	case spock.Boolean:
+		if v {
+			return tagBoolean + "1"
+		}
+		return tagBoolean + "0"
+	case spock.DateTime:
+		return tagDateTime + time.Time(v).UTC().Format(dateTimeLayout)
 	default:
 		panic("not supported")
 	}
 }
 
+// encodeValueMin is the lower bound of encoded values of the same type
+func encodeValueMin(value xsd.Value) string {
+	return encodeValue(value)[:3]
+}
+
+// encodeValueMax is the upper bound of encoded values of the same type,
+// it is the next code point after the type tag, which is never used.
+func encodeValueMax(value xsd.Value) string {
+	tag := []rune(encodeValueMin(value))
+	return string(tag[0] + 1)
+}
+
 func decodeValue(value string) xsd.Value {
-	switch value[:3] {
-	case "ᴵ":
-		return xsd.AnyURI(curie.IRI(value[3:]))
-	case "ᴸ":
-		return xsd.String(value[3:])
+	if len(value) < 3 {
+		return nil
+	}
+
+	switch lit := value[3:]; value[:3] {
+	case tagAnyURI:
+		return xsd.ToAnyURI(curie.IRI(lit))
+	case tagString:
+		return xsd.String(lit)
+	case tagInteger:
+		bits, err := strconv.ParseUint(lit, 16, 64)
+		if err != nil {
+			return nil
+		}
+		return spock.Integer(bits ^ (1 << 63))
+	case tagDouble:
+		bits, err := strconv.ParseUint(lit, 16, 64)
+		if err != nil {
+			return nil
+		}
+		if bits&(1<<63) != 0 {
+			bits &^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		return spock.Double(math.Float64frombits(bits))
+	case tagBoolean:
+		return spock.Boolean(lit == "1")
+	case tagDateTime:
+		t, err := time.Parse(dateTimeLayout, lit)
+		if err != nil {
+			return nil
+		}
+		return spock.DateTime(t)
 	}
 
 	return nil
 }
+
+// iriOf returns IRI of the symbol
+func iriOf(x xsd.AnyURI) curie.IRI {
+	return curie.IRI(x.String())
+}
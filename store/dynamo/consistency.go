@@ -0,0 +1,175 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define consistency checker of six index permutations. Indexes
+// written by earlier releases without transactions might diverge if the
+// write failed half-way. The statement found in any permutation is
+// restored into all of them.
+//
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+// Divergence is the statement missing in some of index permutations
+type Divergence struct {
+	spock.SPOCK
+	Missing []string
+}
+
+// names of permutations, in order of writers and scans
+var permutations = []string{"spo", "sop", "pos", "pso", "osp", "ops"}
+
+// Check scans index permutations of the graph page by page and returns
+// statements that are missing in some of them. Statements of ⟨s, p, o⟩
+// permutation are looked up in all other permutations, statements of other
+// permutations are looked up in ⟨s, p, o⟩ one. The memory is bounded by the
+// page, the check reports progress and is resumable (see GraphOption). On
+// failure, it returns divergences found before it.
+func Check(ctx context.Context, store *Store, graph curie.IRI, opts ...GraphOption) ([]Divergence, error) {
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	divergences := make([]Divergence, 0)
+	_, err = store.scan(ctx, graph, l, func(i int, page []Writer) error {
+		seq, err := store.diverged(ctx, graph, i, page)
+		if err != nil {
+			return err
+		}
+		divergences = append(divergences, seq...)
+		return nil
+	})
+
+	sort.Slice(divergences, func(i, j int) bool {
+		a, b := divergences[i].SPOCK, divergences[j].SPOCK
+		switch {
+		case a.S != b.S:
+			return a.S.String() < b.S.String()
+		case a.P != b.P:
+			return a.P.String() < b.P.String()
+		default:
+			return encodeValue(a.O) < encodeValue(b.O)
+		}
+	})
+
+	return divergences, err
+}
+
+// Repair restores divergent statements into all index permutations.
+// It returns statements that have been repaired.
+func Repair(ctx context.Context, store *Store, graph curie.IRI, opts ...GraphOption) ([]Divergence, error) {
+	divergences, err := Check(ctx, store, graph, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, d := range divergences {
+		if err := Put(ctx, store, graph, d.SPOCK); err != nil {
+			return divergences[:i], err
+		}
+	}
+
+	return divergences, nil
+}
+
+// diverged returns statements of the page of i-th permutation that are
+// missing in other permutations. The statement missing in several of them
+// is reported by the first permutation it is found in.
+func (store *Store) diverged(ctx context.Context, graph curie.IRI, i int, page []Writer) ([]Divergence, error) {
+	// sets of entries looked up by the page
+	known := map[[2]curie.IRI]map[string]struct{}{}
+
+	has := func(w Writer) (bool, error) {
+		key := [2]curie.IRI{w.HashKey(), w.SortKey()}
+		set, ok := known[key]
+		if !ok {
+			slots, _, err := store.table.slots(ctx, w)
+			if err != nil {
+				return false, err
+			}
+
+			set = map[string]struct{}{}
+			for _, s := range slots {
+				for _, v := range s.vals {
+					set[v] = struct{}{}
+				}
+			}
+			known[key] = set
+		}
+
+		_, vals := w.SetOf()
+		_, ok = set[vals[0]]
+		return ok, nil
+	}
+
+	seq := make([]Divergence, 0)
+	for _, w := range page {
+		for _, x := range w.(interface{ ToSPOCK() []spock.SPOCK }).ToSPOCK() {
+			if checkValue(x.O) != nil {
+				continue
+			}
+
+			writers := store.writers(graph, x)
+			if i != 0 {
+				ok, err := has(writers[0])
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					continue
+				}
+			}
+
+			d := Divergence{SPOCK: x}
+			first := -1
+			for j, name := range permutations {
+				ok := j == i
+				if !ok && j != 0 {
+					var err error
+					if ok, err = has(writers[j]); err != nil {
+						return nil, err
+					}
+				}
+
+				switch {
+				case !ok:
+					d.Missing = append(d.Missing, name)
+				case first == -1:
+					first = j
+				}
+			}
+
+			if first == i && len(d.Missing) > 0 {
+				seq = append(seq, d)
+			}
+		}
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,192 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define paging of queries and continuation tokens. The token is
+// the position of the stream: partition of the graph, sort key of the last
+// consumed entry and number of statements consumed from the following entry.
+// The token is opaque for clients, it is serialised as base64url of JSON.
+//
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/spock"
+)
+
+// Stream of statements that is resumable by continuation token. Cursor
+// returns token of the position after the last consumed statement, the
+// token is empty when the stream is exhausted. Err returns the error that
+// terminated the stream, the cursor of failed stream resumes the query.
+type Stream interface {
+	spock.Stream
+	Cursor() string
+	Err() error
+}
+
+// MatchOption configures Match
+type MatchOption func(*matcher)
+
+type matcher struct {
+	pageSize int32
+	token    string
+	cursor   *position
+}
+
+// WithPageSize configures number of entries fetched by single request
+func WithPageSize(n int) MatchOption {
+	return func(m *matcher) {
+		if n > 0 {
+			m.pageSize = int32(n)
+		}
+	}
+}
+
+// WithCursor resumes the query from continuation token, which is obtained
+// from the stream of same query.
+func WithCursor(token string) MatchOption {
+	return func(m *matcher) {
+		m.token = token
+	}
+}
+
+func newMatcher(opts []MatchOption) (*matcher, error) {
+	m := &matcher{pageSize: 100}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.token != "" {
+		pos, err := decodePosition(m.token)
+		if err != nil {
+			return nil, err
+		}
+		m.cursor = pos
+	}
+
+	return m, nil
+}
+
+// start position of i-th partition, the query resumes at the partition of cursor
+func (m *matcher) start(hash []curie.IRI) (int, position, error) {
+	if m.cursor == nil {
+		return 0, position{}, nil
+	}
+
+	if m.cursor.P >= len(hash) || hash[m.cursor.P] != m.cursor.H {
+		return 0, position{}, &invalidCursor{token: m.token}
+	}
+
+	return m.cursor.P, *m.cursor, nil
+}
+
+// invalid continuation token
+type invalidCursor struct {
+	token string
+	err   error
+}
+
+func (err *invalidCursor) Error() string {
+	if err.err != nil {
+		return fmt.Sprintf("invalid cursor %q: %s", err.token, err.err)
+	}
+	return fmt.Sprintf("invalid cursor %q", err.token)
+}
+
+func (err *invalidCursor) Unwrap() error { return err.err }
+func (*invalidCursor) InvalidCursor()    {}
+
+// position of the stream
+type position struct {
+	P int       `json:"p,omitempty"`
+	H curie.IRI `json:"h"`
+	S curie.IRI `json:"s,omitempty"`
+	N int       `json:"n,omitempty"`
+}
+
+func (pos position) String() string {
+	b, _ := json.Marshal(pos)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePosition(token string) (*position, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, &invalidCursor{token: token, err: err}
+	}
+
+	var pos position
+	if err := json.Unmarshal(b, &pos); err != nil {
+		return nil, &invalidCursor{token: token, err: err}
+	}
+
+	if pos.P < 0 || pos.N < 0 || pos.H == "" {
+		return nil, &invalidCursor{token: token}
+	}
+
+	return &pos, nil
+}
+
+// resume of filtered stream keeps continuation token of the chain
+type resume struct {
+	spock.Stream
+	chain Stream
+}
+
+func resumable(chain Stream, stream spock.Stream) Stream {
+	if s, ok := stream.(Stream); ok {
+		return s
+	}
+	return &resume{Stream: stream, chain: chain}
+}
+
+func (r *resume) Cursor() string { return r.chain.Cursor() }
+func (r *resume) Err() error     { return r.chain.Err() }
+
+func (r *resume) FMap(f func(spock.SPOCK) error) error {
+	if err := r.Stream.FMap(f); err != nil {
+		return err
+	}
+	return r.chain.Err()
+}
+
+// Close releases the chain through the filter
+func (r *resume) Close() error {
+	return spock.Close(r.Stream)
+}
+
+// statements of the entry are ordered, the set is not ordered by DynamoDB
+func sortBag(bag []spock.SPOCK) {
+	sort.Slice(bag, func(i, j int) bool {
+		if bag[i].S != bag[j].S {
+			return bag[i].S.String() < bag[j].S.String()
+		}
+		if bag[i].P != bag[j].P {
+			return bag[i].P.String() < bag[j].P.String()
+		}
+		return encodeValue(bag[i].O) < encodeValue(bag[j].O)
+	})
+}
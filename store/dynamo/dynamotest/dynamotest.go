@@ -0,0 +1,655 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+// Package dynamotest is in-memory stand-in of AWS DynamoDB for offline
+// testing of the store:
+//
+//	store, err := dynamo.New("ddb:///spock",
+//		dynamo.WithService(dynamotest.New()),
+//	)
+//
+// It implements key-value operations, queries and transactions used by
+// the store with semantic of DynamoDB: condition, update and projection
+// expressions, string-set union and minus, pagination of queries and
+// atomic transactions. Tables are created on the first use, keys are
+// strings, items are limited by 400 KB.
+//
+// The transaction holds its items until it is applied (see WithLatency),
+// concurrent writes of these items are rejected with TransactionConflict
+// as DynamoDB does.
+package dynamotest
+
+//
+// The file define the in-memory service
+//
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// DynamoDB is in-memory service
+type DynamoDB struct {
+	sync.Mutex
+	hashKey string
+	sortKey string
+	tables  map[string]map[key]item
+	latency time.Duration
+
+	// items held by transactions in progress
+	pending map[string]map[key]struct{}
+}
+
+// Option configures the service
+type Option func(*DynamoDB)
+
+// WithKeySchema defines names of partition and sort keys, the default
+// names are `prefix` and `suffix` as the store connector.
+func WithKeySchema(hashKey, sortKey string) Option {
+	return func(db *DynamoDB) {
+		db.hashKey = hashKey
+		db.sortKey = sortKey
+	}
+}
+
+// WithLatency defines the time transaction holds its items before they are
+// written, concurrent writes of these items conflict with the transaction.
+// The default is zero, transaction only yields to other goroutines.
+func WithLatency(d time.Duration) Option {
+	return func(db *DynamoDB) {
+		db.latency = d
+	}
+}
+
+// New creates in-memory service
+func New(opts ...Option) *DynamoDB {
+	db := &DynamoDB{
+		hashKey: "prefix",
+		sortKey: "suffix",
+		tables:  map[string]map[key]item{},
+		pending: map[string]map[key]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(db)
+	}
+
+	return db
+}
+
+// limit of item size
+const maxItemSize = 400 * 1024
+
+// primary key of item
+type key struct{ hash, sort string }
+
+func (db *DynamoDB) table(name *string) (map[key]item, error) {
+	if name == nil || *name == "" {
+		return nil, validation("1 validation error detected: Value null at 'tableName' failed to satisfy constraint: Member must not be null")
+	}
+
+	t, has := db.tables[*name]
+	if !has {
+		t = map[key]item{}
+		db.tables[*name] = t
+	}
+	return t, nil
+}
+
+func (db *DynamoDB) keyOf(it item) (key, error) {
+	hash, okh := it[db.hashKey].(*types.AttributeValueMemberS)
+	sort, oks := it[db.sortKey].(*types.AttributeValueMemberS)
+	if !okh || !oks {
+		return key{}, validation("The provided key element does not match the schema")
+	}
+	return key{hash: hash.Value, sort: sort.Value}, nil
+}
+
+func (db *DynamoDB) itemOf(k key) item {
+	return item{
+		db.hashKey: &types.AttributeValueMemberS{Value: k.hash},
+		db.sortKey: &types.AttributeValueMemberS{Value: k.sort},
+	}
+}
+
+// GetItem returns item by key
+func (db *DynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := db.keyOf(in.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseProjection(in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	val := &dynamodb.GetItemOutput{}
+	if it, has := t[k]; has {
+		val.Item = project(it, attrs)
+	}
+	return val, nil
+}
+
+// PutItem creates or replaces item
+func (db *DynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	op, err := db.put(in.TableName, in.Item, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.isPending(op) {
+		return nil, conflict()
+	}
+
+	if err := op.check(); err != nil {
+		return nil, err
+	}
+
+	val := &dynamodb.PutItemOutput{}
+	if in.ReturnValues == types.ReturnValueAllOld && op.old != nil {
+		val.Attributes = project(op.old, nil)
+	}
+
+	op.apply()
+	return val, nil
+}
+
+// DeleteItem deletes item by key
+func (db *DynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	op, err := db.delete(in.TableName, in.Key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.isPending(op) {
+		return nil, conflict()
+	}
+
+	if err := op.check(); err != nil {
+		return nil, err
+	}
+
+	val := &dynamodb.DeleteItemOutput{}
+	if in.ReturnValues == types.ReturnValueAllOld && op.old != nil {
+		val.Attributes = project(op.old, nil)
+	}
+
+	op.apply()
+	return val, nil
+}
+
+// UpdateItem updates attributes of item, the item is created if it does not exist
+func (db *DynamoDB) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	op, err := db.update(in.TableName, in.Key, in.UpdateExpression, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.isPending(op) {
+		return nil, conflict()
+	}
+
+	if err := op.check(); err != nil {
+		return nil, err
+	}
+
+	val := &dynamodb.UpdateItemOutput{}
+	switch in.ReturnValues {
+	case types.ReturnValueAllOld:
+		if op.old != nil {
+			val.Attributes = project(op.old, nil)
+		}
+	case types.ReturnValueAllNew:
+		val.Attributes = project(op.new, nil)
+	}
+
+	op.apply()
+	return val, nil
+}
+
+// Query items of partition, items are ordered by sort key
+func (db *DynamoDB) Query(ctx context.Context, in *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.IndexName != nil {
+		return nil, validation("The table does not have the specified index: %s", *in.IndexName)
+	}
+
+	if in.KeyConditionExpression == nil {
+		return nil, validation("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	cond, err := parseCondition(in.KeyConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseCondition(in.FilterExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseProjection(in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := make([]key, 0)
+	for k, it := range t {
+		if cond(it) {
+			seq = append(seq, k)
+		}
+	}
+
+	forward := in.ScanIndexForward == nil || *in.ScanIndexForward
+	sort.Slice(seq, func(i, j int) bool {
+		if forward {
+			return seq[i].sort < seq[j].sort
+		}
+		return seq[i].sort > seq[j].sort
+	})
+
+	if in.ExclusiveStartKey != nil {
+		start, err := db.keyOf(in.ExclusiveStartKey)
+		if err != nil {
+			return nil, err
+		}
+
+		i := sort.Search(len(seq), func(i int) bool {
+			if forward {
+				return seq[i].sort > start.sort
+			}
+			return seq[i].sort < start.sort
+		})
+		seq = seq[i:]
+	}
+
+	val := &dynamodb.QueryOutput{Items: []item{}}
+	for _, k := range seq {
+		if in.Limit != nil && val.ScannedCount == aws.ToInt32(in.Limit) {
+			break
+		}
+
+		val.ScannedCount++
+		if filter(t[k]) {
+			val.Items = append(val.Items, project(t[k], attrs))
+		}
+
+		if in.Limit != nil && val.ScannedCount == aws.ToInt32(in.Limit) {
+			// DynamoDB returns the key once the limit is reached
+			val.LastEvaluatedKey = db.itemOf(k)
+		}
+	}
+	val.Count = int32(len(val.Items))
+
+	return val, nil
+}
+
+// BatchGetItem returns items by keys from tables
+func (db *DynamoDB) BatchGetItem(ctx context.Context, in *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	val := &dynamodb.BatchGetItemOutput{Responses: map[string][]item{}}
+	for name, req := range in.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		attrs, err := parseProjection(req.ProjectionExpression, req.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+
+		seq := []item{}
+		for _, x := range req.Keys {
+			k, err := db.keyOf(x)
+			if err != nil {
+				return nil, err
+			}
+
+			if it, has := t[k]; has {
+				seq = append(seq, project(it, attrs))
+			}
+		}
+		val.Responses[name] = seq
+	}
+
+	return val, nil
+}
+
+// TransactWriteItems applies all writes or none of them
+func (db *DynamoDB) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if len(in.TransactItems) == 0 || len(in.TransactItems) > 100 {
+		return nil, validation("Member must have length less than or equal to 100 and greater than or equal to 1")
+	}
+
+	seq := make([]*operation, len(in.TransactItems))
+	for i, x := range in.TransactItems {
+		var err error
+		switch {
+		case x.Put != nil:
+			seq[i], err = db.put(x.Put.TableName, x.Put.Item, x.Put.ConditionExpression, x.Put.ExpressionAttributeNames, x.Put.ExpressionAttributeValues)
+		case x.Delete != nil:
+			seq[i], err = db.delete(x.Delete.TableName, x.Delete.Key, x.Delete.ConditionExpression, x.Delete.ExpressionAttributeNames, x.Delete.ExpressionAttributeValues)
+		case x.Update != nil:
+			seq[i], err = db.update(x.Update.TableName, x.Update.Key, x.Update.UpdateExpression, x.Update.ConditionExpression, x.Update.ExpressionAttributeNames, x.Update.ExpressionAttributeValues)
+		case x.ConditionCheck != nil:
+			seq[i], err = db.conditionCheck(x.ConditionCheck.TableName, x.ConditionCheck.Key, x.ConditionCheck.ConditionExpression, x.ConditionCheck.ExpressionAttributeNames, x.ConditionCheck.ExpressionAttributeValues)
+		default:
+			err = validation("TransactItems can only contain one of Check, Put, Update or Delete")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := map[string]map[key]struct{}{}
+	for _, op := range seq {
+		if keys[op.table] == nil {
+			keys[op.table] = map[key]struct{}{}
+		}
+		if _, has := keys[op.table][op.key]; has {
+			return nil, validation("Transaction request cannot include multiple operations on one item")
+		}
+		keys[op.table][op.key] = struct{}{}
+	}
+
+	failed := false
+	reasons := make([]types.CancellationReason, len(seq))
+	for i, op := range seq {
+		reasons[i].Code = aws.String("None")
+		switch {
+		case db.isPending(op):
+			reasons[i].Code = aws.String("TransactionConflict")
+			reasons[i].Message = aws.String("Transaction is ongoing for the item")
+			failed = true
+		case op.check() != nil:
+			reasons[i].Code = aws.String("ConditionalCheckFailed")
+			reasons[i].Message = aws.String("The conditional request failed")
+			failed = true
+		}
+	}
+
+	if failed {
+		codes := ""
+		for i, reason := range reasons {
+			if i > 0 {
+				codes += ", "
+			}
+			codes += *reason.Code
+		}
+
+		return nil, &types.TransactionCanceledException{
+			Message:             aws.String(fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]", codes)),
+			CancellationReasons: reasons,
+		}
+	}
+
+	// items are held by the transaction while it is in progress, the lock
+	// of service is released so that concurrent writes conflict with it.
+	for _, op := range seq {
+		db.hold(op)
+	}
+	db.Unlock()
+	if db.latency > 0 {
+		time.Sleep(db.latency)
+	} else {
+		runtime.Gosched()
+	}
+	db.Lock()
+
+	for _, op := range seq {
+		db.release(op)
+		op.apply()
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+//------------------------------------------------------------------------------
+//
+// write operations
+//
+//------------------------------------------------------------------------------
+
+// operation is write of single item, it is checked before it is applied
+type operation struct {
+	t     map[key]item
+	table string
+	key   key
+	cond  condition
+	old   item
+	new   item // nil deletes the item
+	noop  bool
+}
+
+// isPending returns true if the item of operation is held by transaction
+func (db *DynamoDB) isPending(op *operation) bool {
+	_, has := db.pending[op.table][op.key]
+	return has
+}
+
+func (db *DynamoDB) hold(op *operation) {
+	if db.pending[op.table] == nil {
+		db.pending[op.table] = map[key]struct{}{}
+	}
+	db.pending[op.table][op.key] = struct{}{}
+}
+
+func (db *DynamoDB) release(op *operation) {
+	delete(db.pending[op.table], op.key)
+}
+
+func (op *operation) check() error {
+	if !op.cond(op.old) {
+		return &types.ConditionalCheckFailedException{
+			Message: aws.String("The conditional request failed"),
+		}
+	}
+	return nil
+}
+
+func (op *operation) apply() {
+	switch {
+	case op.noop:
+		return
+	case op.new == nil:
+		delete(op.t, op.key)
+	default:
+		op.t[op.key] = op.new
+	}
+}
+
+func (db *DynamoDB) operation(table *string, it item, expr *string, names map[string]string, values map[string]types.AttributeValue) (*operation, error) {
+	t, err := db.table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := db.keyOf(it)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := parseCondition(expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation{t: t, table: *table, key: k, cond: cond, old: t[k]}, nil
+}
+
+func (db *DynamoDB) put(table *string, it item, expr *string, names map[string]string, values map[string]types.AttributeValue) (*operation, error) {
+	op, err := db.operation(table, it, expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	op.new = project(it, nil)
+	if err := checkSize(op.new); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (db *DynamoDB) delete(table *string, k item, expr *string, names map[string]string, values map[string]types.AttributeValue) (*operation, error) {
+	return db.operation(table, k, expr, names, values)
+}
+
+func (db *DynamoDB) update(table *string, k item, update, expr *string, names map[string]string, values map[string]types.AttributeValue) (*operation, error) {
+	op, err := db.operation(table, k, expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parseUpdate(update, names, values, db.hashKey, db.sortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	op.new = db.itemOf(op.key)
+	if op.old != nil {
+		op.new = project(op.old, nil)
+	}
+
+	if err := f(op.new); err != nil {
+		return nil, err
+	}
+
+	if err := checkSize(op.new); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (db *DynamoDB) conditionCheck(table *string, k item, expr *string, names map[string]string, values map[string]types.AttributeValue) (*operation, error) {
+	op, err := db.operation(table, k, expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	op.noop = true
+	return op, nil
+}
+
+// checkSize of item, it is the length of attribute names and values
+func checkSize(it item) error {
+	size := 0
+	for name, val := range it {
+		size += len(name) + sizeOfValue(val)
+	}
+
+	if size > maxItemSize {
+		return validation("Item size has exceeded the maximum allowed size")
+	}
+	return nil
+}
+
+func sizeOfValue(a types.AttributeValue) int {
+	size := 0
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		size = len(x.Value)
+	case *types.AttributeValueMemberN:
+		size = len(x.Value)
+	case *types.AttributeValueMemberB:
+		size = len(x.Value)
+	case *types.AttributeValueMemberSS:
+		for _, v := range x.Value {
+			size += len(v)
+		}
+	case *types.AttributeValueMemberNS:
+		for _, v := range x.Value {
+			size += len(v)
+		}
+	case *types.AttributeValueMemberBS:
+		for _, v := range x.Value {
+			size += len(v)
+		}
+	case *types.AttributeValueMemberL:
+		for _, v := range x.Value {
+			size += sizeOfValue(v)
+		}
+	case *types.AttributeValueMemberM:
+		for k, v := range x.Value {
+			size += len(k) + sizeOfValue(v)
+		}
+	default:
+		size = 1
+	}
+	return size
+}
+
+func validation(format string, args ...any) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+func conflict() error {
+	return &types.TransactionConflictException{
+		Message: aws.String("Transaction is ongoing for the item"),
+	}
+}
+
+func incorrectOperand(op string) error {
+	return validation("Invalid UpdateExpression: Incorrect operand type for operator or function; operator: %s", op)
+}
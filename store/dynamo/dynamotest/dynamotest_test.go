@@ -0,0 +1,283 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamotest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock/store/dynamo/dynamotest"
+)
+
+func key(hash, sort string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"prefix": &types.AttributeValueMemberS{Value: hash},
+		"suffix": &types.AttributeValueMemberS{Value: sort},
+	}
+}
+
+func add(sort string, vals ...string) *dynamodb.UpdateItemInput {
+	return &dynamodb.UpdateItemInput{
+		TableName:                aws.String("t"),
+		Key:                      key("h", sort),
+		UpdateExpression:         aws.String("ADD #a :v"),
+		ExpressionAttributeNames: map[string]string{"#a": "s"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":v": &types.AttributeValueMemberSS{Value: vals},
+		},
+	}
+}
+
+func get(db *dynamotest.DynamoDB, sort string) map[string]types.AttributeValue {
+	val, _ := db.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("t"),
+		Key:       key("h", sort),
+	})
+	return val.Item
+}
+
+func set(item map[string]types.AttributeValue) []string {
+	if ss, ok := item["s"].(*types.AttributeValueMemberSS); ok {
+		return ss.Value
+	}
+	return nil
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := dynamotest.New()
+
+	t.Run("Union", func(t *testing.T) {
+		_, erra := db.UpdateItem(ctx, add("a", "x", "y"))
+		_, errb := db.UpdateItem(ctx, add("a", "y", "z"))
+		it.Then(t).Should(
+			it.Nil(erra),
+			it.Nil(errb),
+			it.Seq(set(get(db, "a"))).Equal("x", "y", "z"),
+		)
+	})
+
+	t.Run("Minus", func(t *testing.T) {
+		req := add("a", "x", "y", "z")
+		req.UpdateExpression = aws.String("DELETE #a :v")
+		_, err := db.UpdateItem(ctx, req)
+		item := get(db, "a")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(item), 2),
+			it.Equal(len(set(item)), 0),
+		)
+	})
+
+	t.Run("Condition", func(t *testing.T) {
+		req := add("b", "x")
+		req.ConditionExpression = aws.String("attribute_not_exists(#a) OR size(#a) < :n")
+		req.ExpressionAttributeValues[":n"] = &types.AttributeValueMemberN{Value: "1"}
+
+		_, erra := db.UpdateItem(ctx, req)
+		_, errb := db.UpdateItem(ctx, req)
+
+		var failed *types.ConditionalCheckFailedException
+		it.Then(t).Should(
+			it.Nil(erra),
+			it.True(errors.As(errb, &failed)),
+		)
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		req := add("c", "x")
+		req.ExpressionAttributeNames["#a"] = "suffix"
+		_, err := db.UpdateItem(ctx, req)
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		)
+	})
+}
+
+func TestItemSize(t *testing.T) {
+	ctx := context.Background()
+	db := dynamotest.New()
+
+	v := strings.Repeat("x", 1024)
+	vals := make([]string, 0, 400)
+	for i := 0; i < 400; i++ {
+		vals = append(vals, fmt.Sprintf("%s%d", v, i))
+	}
+
+	_, err := db.UpdateItem(ctx, add("a", vals...))
+	it.Then(t).ShouldNot(
+		it.Nil(err),
+	).Should(
+		it.Equal(len(get(db, "a")), 0),
+	)
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := dynamotest.New()
+
+	update := func(req *dynamodb.UpdateItemInput) types.TransactWriteItem {
+		return types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                 req.TableName,
+				Key:                       req.Key,
+				UpdateExpression:          req.UpdateExpression,
+				ConditionExpression:       req.ConditionExpression,
+				ExpressionAttributeNames:  req.ExpressionAttributeNames,
+				ExpressionAttributeValues: req.ExpressionAttributeValues,
+			},
+		}
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		_, err := db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{update(add("a", "x")), update(add("b", "x"))},
+		})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(set(get(db, "a"))).Equal("x"),
+			it.Seq(set(get(db, "b"))).Equal("x"),
+		)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		req := add("b", "y")
+		req.ConditionExpression = aws.String("attribute_not_exists(#a)")
+
+		_, err := db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{update(add("c", "x")), update(req)},
+		})
+
+		var canceled *types.TransactionCanceledException
+		it.Then(t).Should(
+			it.True(errors.As(err, &canceled)),
+			it.Equal(*canceled.CancellationReasons[0].Code, "None"),
+			it.Equal(*canceled.CancellationReasons[1].Code, "ConditionalCheckFailed"),
+			it.Equal(len(get(db, "c")), 0),
+		)
+	})
+
+	t.Run("SameItem", func(t *testing.T) {
+		_, err := db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{update(add("d", "x")), update(add("d", "y"))},
+		})
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		db := dynamotest.New(dynamotest.WithLatency(50 * time.Millisecond))
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, v := range []string{"x", "y"} {
+			wg.Add(1)
+			go func(i int, v string) {
+				defer wg.Done()
+				_, errs[i] = db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+					TransactItems: []types.TransactWriteItem{update(add("e", v))},
+				})
+			}(i, v)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		_, errp := db.UpdateItem(ctx, add("e", "z"))
+		wg.Wait()
+
+		var canceled *types.TransactionCanceledException
+		var conflict *types.TransactionConflictException
+		it.Then(t).Should(
+			it.Nil(errs[0]),
+			it.True(errors.As(errs[1], &canceled)),
+			it.Equal(*canceled.CancellationReasons[0].Code, "TransactionConflict"),
+			it.True(errors.As(errp, &conflict)),
+			it.Seq(set(get(db, "e"))).Equal("x"),
+		)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	db := dynamotest.New()
+	for _, sort := range []string{"a|1", "a|2", "a|3", "b|1"} {
+		db.UpdateItem(ctx, add(sort, "x"))
+	}
+
+	query := func(expr string, values map[string]types.AttributeValue, limit int32, start map[string]types.AttributeValue) ([]string, map[string]types.AttributeValue) {
+		values[":p"] = &types.AttributeValueMemberS{Value: "h"}
+		val, err := db.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String("t"),
+			KeyConditionExpression:    aws.String(expr),
+			ExpressionAttributeNames:  map[string]string{"#p": "prefix", "#s": "suffix"},
+			ExpressionAttributeValues: values,
+			ProjectionExpression:      aws.String("#s"),
+			Limit:                     aws.Int32(limit),
+			ExclusiveStartKey:         start,
+		})
+		it.Then(t).Should(it.Nil(err))
+
+		seq := []string{}
+		for _, item := range val.Items {
+			seq = append(seq, item["suffix"].(*types.AttributeValueMemberS).Value)
+		}
+		return seq, val.LastEvaluatedKey
+	}
+
+	t.Run("BeginsWith", func(t *testing.T) {
+		seq, _ := query("#p = :p AND begins_with(#s, :s)",
+			map[string]types.AttributeValue{":s": &types.AttributeValueMemberS{Value: "a|"}}, 10, nil)
+		it.Then(t).Should(
+			it.Seq(seq).Equal("a|1", "a|2", "a|3"),
+		)
+	})
+
+	t.Run("Between", func(t *testing.T) {
+		seq, _ := query("#p = :p AND #s BETWEEN :lo AND :hi",
+			map[string]types.AttributeValue{
+				":lo": &types.AttributeValueMemberS{Value: "a|2"},
+				":hi": &types.AttributeValueMemberS{Value: "b|1"},
+			}, 10, nil)
+		it.Then(t).Should(
+			it.Seq(seq).Equal("a|2", "a|3", "b|1"),
+		)
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		values := map[string]types.AttributeValue{":s": &types.AttributeValueMemberS{Value: "a|"}}
+		a, cursor := query("#p = :p AND begins_with(#s, :s)", values, 2, nil)
+		b, last := query("#p = :p AND begins_with(#s, :s)", values, 2, cursor)
+		it.Then(t).Should(
+			it.Seq(a).Equal("a|1", "a|2"),
+			it.Seq(b).Equal("a|3"),
+			it.Equal(len(last), 0),
+		)
+	})
+}
@@ -0,0 +1,918 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamotest
+
+//
+// The file define evaluator of DynamoDB expressions: key conditions,
+// conditions, filters, updates and projections. Attribute paths are top
+// level attributes, nested paths (a.b, a[0]) are not supported.
+//
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// item is the set of attributes
+type item = map[string]types.AttributeValue
+
+// condition evaluates the item, the item is nil if it does not exist
+type condition func(item) bool
+
+// operand evaluates to attribute value, nil if it does not exist
+type operand func(item) types.AttributeValue
+
+// action of update expression mutates the item
+type action func(item) error
+
+//------------------------------------------------------------------------------
+//
+// lexer
+//
+//------------------------------------------------------------------------------
+
+const (
+	tkEOF = iota
+	tkName
+	tkValue
+	tkPunct
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(expr string) ([]token, error) {
+	seq := []token{}
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == ':' || c == '#' || isNameChar(c):
+			j := i + 1
+			for j < len(expr) && isNameChar(rune(expr[j])) {
+				j++
+			}
+			kind := tkName
+			if c == ':' {
+				kind = tkValue
+			}
+			seq = append(seq, token{kind: kind, text: expr[i:j]})
+			i = j
+		case c == '<' || c == '>':
+			if i+1 < len(expr) && (expr[i+1] == '=' || (c == '<' && expr[i+1] == '>')) {
+				seq = append(seq, token{kind: tkPunct, text: expr[i : i+2]})
+				i += 2
+			} else {
+				seq = append(seq, token{kind: tkPunct, text: expr[i : i+1]})
+				i++
+			}
+		case strings.ContainsRune("()=,+-", c):
+			seq = append(seq, token{kind: tkPunct, text: expr[i : i+1]})
+			i++
+		default:
+			return nil, validation("Invalid expression: syntax error at %q", expr[i:])
+		}
+	}
+
+	return append(seq, token{kind: tkEOF}), nil
+}
+
+func isNameChar(c rune) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+//------------------------------------------------------------------------------
+//
+// parser
+//
+//------------------------------------------------------------------------------
+
+type parser struct {
+	expr   string
+	seq    []token
+	at     int
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+func newParser(expr string, names map[string]string, values map[string]types.AttributeValue) (*parser, error) {
+	seq, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{expr: expr, seq: seq, names: names, values: values}, nil
+}
+
+func (p *parser) peek() token { return p.seq[p.at] }
+
+func (p *parser) next() token {
+	t := p.seq[p.at]
+	if t.kind != tkEOF {
+		p.at++
+	}
+	return t
+}
+
+// keyword consumes the keyword if it is next token
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tkName && strings.EqualFold(t.text, kw) {
+		p.at++
+		return true
+	}
+	return false
+}
+
+// punct consumes the punctuation if it is next token
+func (p *parser) punct(s string) bool {
+	if t := p.peek(); t.kind == tkPunct && t.text == s {
+		p.at++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.punct(s) {
+		return p.syntax()
+	}
+	return nil
+}
+
+func (p *parser) syntax() error {
+	return validation("Invalid expression: syntax error at token %q in %q", p.peek().text, p.expr)
+}
+
+// function consumes name of the function if it is followed by (
+func (p *parser) function(names ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tkName || p.seq[p.at+1].text != "(" {
+		return "", false
+	}
+
+	for _, name := range names {
+		if strings.EqualFold(t.text, name) {
+			p.at += 2
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// path to attribute, placeholders are resolved by names
+func (p *parser) path() (string, error) {
+	t := p.next()
+	if t.kind != tkName {
+		return "", p.syntax()
+	}
+
+	if strings.HasPrefix(t.text, "#") {
+		name, has := p.names[t.text]
+		if !has {
+			return "", validation("An expression attribute name used in the document path is not defined; attribute name: %s", t.text)
+		}
+		return name, nil
+	}
+
+	return t.text, nil
+}
+
+func (p *parser) value() (types.AttributeValue, error) {
+	t := p.next()
+	if t.kind != tkValue {
+		return nil, p.syntax()
+	}
+
+	val, has := p.values[t.text]
+	if !has {
+		return nil, validation("An expression attribute value used in expression is not defined; attribute value: %s", t.text)
+	}
+	return val, nil
+}
+
+func (p *parser) operand() (operand, error) {
+	if t := p.peek(); t.kind == tkValue {
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return func(item) types.AttributeValue { return val }, nil
+	}
+
+	if _, ok := p.function("size"); ok {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(it item) types.AttributeValue { return sizeOf(it[path]) }, nil
+	}
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return func(it item) types.AttributeValue { return it[path] }, nil
+}
+
+func (p *parser) eof() error {
+	if p.peek().kind != tkEOF {
+		return p.syntax()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+//
+// conditions
+//
+//------------------------------------------------------------------------------
+
+// parseCondition parses key condition, condition or filter expression
+func parseCondition(expr *string, names map[string]string, values map[string]types.AttributeValue) (condition, error) {
+	if expr == nil || *expr == "" {
+		return func(item) bool { return true }, nil
+	}
+
+	p, err := newParser(*expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, p.eof()
+}
+
+func (p *parser) or() (condition, error) {
+	a, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		b, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := a
+		a = func(it item) bool { return l(it) || b(it) }
+	}
+
+	return a, nil
+}
+
+func (p *parser) and() (condition, error) {
+	a, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		b, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := a
+		a = func(it item) bool { return l(it) && b(it) }
+	}
+
+	return a, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.keyword("NOT") {
+		c, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(it item) bool { return !c(it) }, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.punct("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+
+	if f, ok := p.function("attribute_exists", "attribute_not_exists"); ok {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		exists := f == "attribute_exists"
+		return func(it item) bool { _, has := it[path]; return has == exists }, p.expect(")")
+	}
+
+	if f, ok := p.function("begins_with", "contains"); ok {
+		a, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		b, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if f == "begins_with" {
+			return func(it item) bool { return beginsWith(a(it), b(it)) }, p.expect(")")
+		}
+		return func(it item) bool { return contains(a(it), b(it)) }, p.expect(")")
+	}
+
+	a, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.keyword("BETWEEN"):
+		lo, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.syntax()
+		}
+		hi, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(it item) bool {
+			x, y, z := a(it), lo(it), hi(it)
+			l, okl := compare(y, x)
+			h, okh := compare(x, z)
+			return okl && okh && l <= 0 && h <= 0
+		}, nil
+
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		seq := []operand{}
+		for {
+			b, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, b)
+			if !p.punct(",") {
+				break
+			}
+		}
+		return func(it item) bool {
+			x := a(it)
+			for _, b := range seq {
+				if equal(x, b(it)) {
+					return true
+				}
+			}
+			return false
+		}, p.expect(")")
+	}
+
+	t := p.next()
+	if t.kind != tkPunct {
+		return nil, p.syntax()
+	}
+
+	b, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.text {
+	case "=":
+		return func(it item) bool { return equal(a(it), b(it)) }, nil
+	case "<>":
+		return func(it item) bool { x := a(it); return x != nil && !equal(x, b(it)) }, nil
+	case "<":
+		return ordered(a, b, func(x int) bool { return x < 0 }), nil
+	case "<=":
+		return ordered(a, b, func(x int) bool { return x <= 0 }), nil
+	case ">":
+		return ordered(a, b, func(x int) bool { return x > 0 }), nil
+	case ">=":
+		return ordered(a, b, func(x int) bool { return x >= 0 }), nil
+	default:
+		return nil, p.syntax()
+	}
+}
+
+func ordered(a, b operand, f func(int) bool) condition {
+	return func(it item) bool {
+		x, ok := compare(a(it), b(it))
+		return ok && f(x)
+	}
+}
+
+//------------------------------------------------------------------------------
+//
+// updates
+//
+//------------------------------------------------------------------------------
+
+// parseUpdate parses update expression, keys are not updatable
+func parseUpdate(expr *string, names map[string]string, values map[string]types.AttributeValue, keys ...string) (action, error) {
+	if expr == nil || *expr == "" {
+		return func(item) error { return nil }, nil
+	}
+
+	p, err := newParser(*expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := []action{}
+	for p.peek().kind != tkEOF {
+		var clause func() (string, action, error)
+		switch {
+		case p.keyword("SET"):
+			clause = p.set
+		case p.keyword("REMOVE"):
+			clause = p.remove
+		case p.keyword("ADD"):
+			clause = p.add
+		case p.keyword("DELETE"):
+			clause = p.delete
+		default:
+			return nil, p.syntax()
+		}
+
+		for {
+			path, f, err := clause()
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range keys {
+				if path == key {
+					return nil, validation("Cannot update attribute %s. This attribute is part of the key", path)
+				}
+			}
+			seq = append(seq, f)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+
+	return func(it item) error {
+		for _, f := range seq {
+			if err := f(it); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func (p *parser) set() (string, action, error) {
+	path, err := p.path()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := p.expect("="); err != nil {
+		return "", nil, err
+	}
+
+	a, err := p.term()
+	if err != nil {
+		return "", nil, err
+	}
+
+	val := a
+	switch {
+	case p.punct("+"):
+		b, err := p.term()
+		if err != nil {
+			return "", nil, err
+		}
+		val = arithmetic(a, b, (*big.Float).Add)
+	case p.punct("-"):
+		b, err := p.term()
+		if err != nil {
+			return "", nil, err
+		}
+		val = arithmetic(a, b, (*big.Float).Sub)
+	}
+
+	return path, func(it item) error {
+		x := val(it)
+		if x == nil {
+			return validation("The provided expression refers to an attribute that does not exist in the item")
+		}
+		it[path] = clone(x)
+		return nil
+	}, nil
+}
+
+// term of SET action: operand or if_not_exists(path, operand)
+func (p *parser) term() (operand, error) {
+	if _, ok := p.function("if_not_exists"); ok {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		b, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(it item) types.AttributeValue {
+			if x, has := it[path]; has {
+				return x
+			}
+			return b(it)
+		}, p.expect(")")
+	}
+
+	return p.operand()
+}
+
+func arithmetic(a, b operand, f func(z, x, y *big.Float) *big.Float) operand {
+	return func(it item) types.AttributeValue {
+		x, okx := a(it).(*types.AttributeValueMemberN)
+		y, oky := b(it).(*types.AttributeValueMemberN)
+		if !okx || !oky {
+			return nil
+		}
+		return &types.AttributeValueMemberN{Value: number(x.Value, y.Value, f)}
+	}
+}
+
+func (p *parser) remove() (string, action, error) {
+	path, err := p.path()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return path, func(it item) error { delete(it, path); return nil }, nil
+}
+
+func (p *parser) add() (string, action, error) {
+	path, err := p.path()
+	if err != nil {
+		return "", nil, err
+	}
+
+	val, err := p.value()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return path, func(it item) error {
+		x, has := it[path]
+		if !has {
+			switch val.(type) {
+			case *types.AttributeValueMemberN, *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+				it[path] = clone(val)
+				return nil
+			}
+			return incorrectOperand("ADD")
+		}
+
+		switch a := x.(type) {
+		case *types.AttributeValueMemberN:
+			if b, ok := val.(*types.AttributeValueMemberN); ok {
+				it[path] = &types.AttributeValueMemberN{Value: number(a.Value, b.Value, (*big.Float).Add)}
+				return nil
+			}
+		case *types.AttributeValueMemberSS:
+			if b, ok := val.(*types.AttributeValueMemberSS); ok {
+				it[path] = &types.AttributeValueMemberSS{Value: union(a.Value, b.Value)}
+				return nil
+			}
+		case *types.AttributeValueMemberNS:
+			if b, ok := val.(*types.AttributeValueMemberNS); ok {
+				it[path] = &types.AttributeValueMemberNS{Value: union(a.Value, b.Value)}
+				return nil
+			}
+		}
+
+		return incorrectOperand("ADD")
+	}, nil
+}
+
+func (p *parser) delete() (string, action, error) {
+	path, err := p.path()
+	if err != nil {
+		return "", nil, err
+	}
+
+	val, err := p.value()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return path, func(it item) error {
+		x, has := it[path]
+		if !has {
+			return nil
+		}
+
+		var seq []string
+		switch a := x.(type) {
+		case *types.AttributeValueMemberSS:
+			b, ok := val.(*types.AttributeValueMemberSS)
+			if !ok {
+				return incorrectOperand("DELETE")
+			}
+			seq = minus(a.Value, b.Value)
+			it[path] = &types.AttributeValueMemberSS{Value: seq}
+		case *types.AttributeValueMemberNS:
+			b, ok := val.(*types.AttributeValueMemberNS)
+			if !ok {
+				return incorrectOperand("DELETE")
+			}
+			seq = minus(a.Value, b.Value)
+			it[path] = &types.AttributeValueMemberNS{Value: seq}
+		default:
+			return incorrectOperand("DELETE")
+		}
+
+		// sets are never empty, the attribute is removed instead
+		if len(seq) == 0 {
+			delete(it, path)
+		}
+		return nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+//
+// projections
+//
+//------------------------------------------------------------------------------
+
+// parseProjection parses projection expression into list of attributes
+func parseProjection(expr *string, names map[string]string) ([]string, error) {
+	if expr == nil || *expr == "" {
+		return nil, nil
+	}
+
+	p, err := newParser(*expr, names, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := []string{}
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, path)
+		if !p.punct(",") {
+			break
+		}
+	}
+
+	return seq, p.eof()
+}
+
+// project the item to attributes, the item is copied
+func project(it item, attrs []string) item {
+	val := item{}
+	if attrs == nil {
+		for k, v := range it {
+			val[k] = clone(v)
+		}
+		return val
+	}
+
+	for _, k := range attrs {
+		if v, has := it[k]; has {
+			val[k] = clone(v)
+		}
+	}
+	return val
+}
+
+//------------------------------------------------------------------------------
+//
+// values
+//
+//------------------------------------------------------------------------------
+
+// compare scalar values of same type
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(x.Value, y.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if y, ok := b.(*types.AttributeValueMemberN); ok {
+			fx, _, errx := big.ParseFloat(x.Value, 10, 128, big.ToNearestEven)
+			fy, _, erry := big.ParseFloat(y.Value, 10, 128, big.ToNearestEven)
+			if errx != nil || erry != nil {
+				return 0, false
+			}
+			return fx.Cmp(fy), true
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(x.Value, y.Value), true
+		}
+	}
+	return 0, false
+}
+
+func equal(a, b types.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	if x, ok := compare(a, b); ok {
+		return x == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func beginsWith(a, b types.AttributeValue) bool {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.HasPrefix(x.Value, y.Value)
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.HasPrefix(x.Value, y.Value)
+		}
+	}
+	return false
+}
+
+func contains(a, b types.AttributeValue) bool {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Contains(x.Value, y.Value)
+		}
+	case *types.AttributeValueMemberSS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return indexOf(x.Value, y.Value) != -1
+		}
+	case *types.AttributeValueMemberNS:
+		if y, ok := b.(*types.AttributeValueMemberN); ok {
+			for _, v := range x.Value {
+				if equal(&types.AttributeValueMemberN{Value: v}, y) {
+					return true
+				}
+			}
+		}
+	case *types.AttributeValueMemberL:
+		for _, v := range x.Value {
+			if equal(v, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sizeOf(a types.AttributeValue) types.AttributeValue {
+	n := -1
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberB:
+		n = len(x.Value)
+	case *types.AttributeValueMemberSS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberNS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberBS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberL:
+		n = len(x.Value)
+	case *types.AttributeValueMemberM:
+		n = len(x.Value)
+	}
+
+	if n == -1 {
+		return nil
+	}
+	return &types.AttributeValueMemberN{Value: fmt.Sprint(n)}
+}
+
+func number(a, b string, f func(z, x, y *big.Float) *big.Float) string {
+	x, _, _ := big.ParseFloat(a, 10, 128, big.ToNearestEven)
+	y, _, _ := big.ParseFloat(b, 10, 128, big.ToNearestEven)
+	if x == nil || y == nil {
+		return "0"
+	}
+	return f(new(big.Float).SetPrec(128), x, y).Text('f', -1)
+}
+
+func indexOf(seq []string, x string) int {
+	for i, v := range seq {
+		if v == x {
+			return i
+		}
+	}
+	return -1
+}
+
+func union(a, b []string) []string {
+	seq := append([]string{}, a...)
+	for _, x := range b {
+		if indexOf(seq, x) == -1 {
+			seq = append(seq, x)
+		}
+	}
+	return seq
+}
+
+func minus(a, b []string) []string {
+	seq := []string{}
+	for _, x := range a {
+		if indexOf(b, x) == -1 {
+			seq = append(seq, x)
+		}
+	}
+	return seq
+}
+
+// clone deep copies the value
+func clone(a types.AttributeValue) types.AttributeValue {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: x.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: x.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, x.Value...)}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, x.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, x.Value...)}
+	case *types.AttributeValueMemberBS:
+		seq := make([][]byte, len(x.Value))
+		for i, v := range x.Value {
+			seq[i] = append([]byte{}, v...)
+		}
+		return &types.AttributeValueMemberBS{Value: seq}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: x.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: x.Value}
+	case *types.AttributeValueMemberL:
+		seq := make([]types.AttributeValue, len(x.Value))
+		for i, v := range x.Value {
+			seq[i] = clone(v)
+		}
+		return &types.AttributeValueMemberL{Value: seq}
+	case *types.AttributeValueMemberM:
+		val := make(map[string]types.AttributeValue, len(x.Value))
+		for k, v := range x.Value {
+			val[k] = clone(v)
+		}
+		return &types.AttributeValueMemberM{Value: val}
+	default:
+		return a
+	}
+}
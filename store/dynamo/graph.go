@@ -0,0 +1,455 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define lifecycle of graphs: enumeration, statistics, copy and
+// drop. The graph is registered in the table before the first write to it
+// by the process, the registry item is not a part of transactions, which
+// would make it a hot item of concurrent writes. The registry enumerates
+// graphs written since it was introduced, graphs written by earlier
+// releases are registered by next write or by RegisterGraph. The process
+// does not know about graphs dropped by other processes, writing the graph
+// again requires RegisterGraph. Operations scan the graph page by page, they
+// report progress with continuation token and are resumable from it.
+//
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v2"
+	"github.com/fogfish/dynamo/v2/service/ddb"
+	"github.com/kshard/spock"
+)
+
+// partition of registry, the item per graph
+const registry = curie.IRI("graphs|")
+
+// Progress of graph operation
+type Progress struct {
+	Items      int    // number of processed items of all index permutations
+	Statements int    // number of processed statements
+	Cursor     string // continuation token, the operation resumes after processed items
+}
+
+// GraphStat is the size of the graph
+type GraphStat struct {
+	Graph      curie.IRI
+	Statements int // number of statements, counted in ⟨s, p, o⟩ permutation
+	Items      int // number of items in all index permutations
+}
+
+// GraphOption configures graph operations
+type GraphOption func(*lifecycle)
+
+type lifecycle struct {
+	progress func(Progress) error
+	token    string
+	at       checkpoint
+	load     []LoadOption
+}
+
+// WithProgress reports progress of the operation after each page. The
+// operation is aborted if the function fails, the last reported cursor
+// resumes it.
+func WithProgress(f func(Progress) error) GraphOption {
+	return func(l *lifecycle) {
+		l.progress = f
+	}
+}
+
+// WithResume resumes the operation from continuation token, which is
+// obtained from the progress of same operation.
+func WithResume(token string) GraphOption {
+	return func(l *lifecycle) {
+		l.token = token
+	}
+}
+
+// WithLoader configures bulk loader used by operations that write
+// statements (CopyGraph, Migrate).
+func WithLoader(opts ...LoadOption) GraphOption {
+	return func(l *lifecycle) {
+		l.load = append(l.load, opts...)
+	}
+}
+
+func newLifecycle(opts []GraphOption) (*lifecycle, error) {
+	l := &lifecycle{progress: func(Progress) error { return nil }}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.token != "" {
+		at, err := decodeCheckpoint(l.token)
+		if err != nil {
+			return nil, err
+		}
+		l.at = at
+	}
+
+	return l, nil
+}
+
+func (l *lifecycle) report(at checkpoint) error {
+	return l.progress(Progress{Items: at.N, Statements: at.M, Cursor: at.String()})
+}
+
+// checkpoint of graph operation: index permutation, position within it
+// and counters of processed items and statements.
+type checkpoint struct {
+	I int    `json:"i,omitempty"`
+	C string `json:"c,omitempty"`
+	N int    `json:"n,omitempty"`
+	M int    `json:"m,omitempty"`
+}
+
+func (at checkpoint) String() string {
+	b, _ := json.Marshal(at)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCheckpoint(token string) (checkpoint, error) {
+	var at checkpoint
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return at, &invalidCursor{token: token, err: err}
+	}
+
+	if err := json.Unmarshal(b, &at); err != nil {
+		return at, &invalidCursor{token: token, err: err}
+	}
+
+	if at.I < 0 || at.I >= len(permutations) || at.N < 0 || at.M < 0 {
+		return at, &invalidCursor{token: token}
+	}
+
+	return at, nil
+}
+
+// register writes the registry item of the graph once per process. The write
+// is conditional, the existing item is not written again.
+func (t *table) register(ctx context.Context, graph curie.IRI) error {
+	if _, has := t.graphs.Load(graph); has {
+		return nil
+	}
+
+	_, err := t.service.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(t.name),
+		Item:                     t.key(registry, graph),
+		ConditionExpression:      aws.String("attribute_not_exists(#p)"),
+		ExpressionAttributeNames: map[string]string{"#p": t.prefix},
+	})
+
+	var exists *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &exists) {
+		return err
+	}
+
+	t.graphs.Store(graph, struct{}{})
+	return nil
+}
+
+// RegisterGraph registers the graph written by earlier releases, which did
+// not maintain the registry. Writes register the graph automatically.
+func RegisterGraph(ctx context.Context, store *Store, graph curie.IRI) error {
+	_, err := store.table.service.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.table.name),
+		Item:      store.table.key(registry, graph),
+	})
+	if err != nil {
+		return err
+	}
+
+	store.table.graphs.Store(graph, struct{}{})
+	return nil
+}
+
+// Graphs returns graphs registered in the store. Graphs written by earlier
+// releases are not listed until they are written or registered again (see
+// RegisterGraph).
+func Graphs(ctx context.Context, store *Store, opts ...GraphOption) ([]curie.IRI, error) {
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	at := l.at
+	seq := make([]curie.IRI, 0)
+
+	var cursor map[string]types.AttributeValue
+	if at.C != "" {
+		pos, err := decodePosition(at.C)
+		if err != nil {
+			return nil, err
+		}
+		cursor = store.table.key(registry, pos.S)
+	}
+
+	for {
+		val, err := store.table.service.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(store.table.name),
+			KeyConditionExpression: aws.String("#p = :p"),
+			ExpressionAttributeNames: map[string]string{
+				"#p": store.table.prefix,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":p": &types.AttributeValueMemberS{Value: string(registry)},
+			},
+			ExclusiveStartKey: cursor,
+			Limit:             aws.Int32(maxTransactItems),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range val.Items {
+			if sk, ok := item[store.table.suffix].(*types.AttributeValueMemberS); ok {
+				seq = append(seq, curie.IRI(sk.Value))
+				at.C = position{H: registry, S: curie.IRI(sk.Value)}.String()
+				at.N++
+			}
+		}
+
+		if len(val.Items) > 0 {
+			if err := l.report(at); err != nil {
+				return seq, err
+			}
+		}
+
+		if len(val.LastEvaluatedKey) == 0 {
+			return seq, nil
+		}
+		cursor = val.LastEvaluatedKey
+	}
+}
+
+// Stats scans all index permutations of the graph and counts its items
+// and statements.
+func Stats(ctx context.Context, store *Store, graph curie.IRI, opts ...GraphOption) (GraphStat, error) {
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return GraphStat{}, err
+	}
+
+	at, err := store.scan(ctx, graph, l, func(int, []Writer) error { return nil })
+	return GraphStat{Graph: graph, Statements: at.M, Items: at.N}, err
+}
+
+// DropGraph deletes all items of the graph from index permutations, the
+// graph is unregistered once it is empty. It returns the number of deleted
+// statements. Writes to the graph are not isolated from the drop, stop them
+// before.
+func DropGraph(ctx context.Context, store *Store, graph curie.IRI, opts ...GraphOption) (int, error) {
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	at, err := store.scan(ctx, graph, l, func(_ int, page []Writer) error {
+		return store.table.drop(ctx, page)
+	})
+	if err != nil {
+		return at.M, err
+	}
+
+	_, err = store.table.service.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.table.name),
+		Key:       store.table.key(registry, graph),
+	})
+	store.table.graphs.Delete(graph)
+
+	return at.M, err
+}
+
+// CopyGraph copies statements of the source graph to the target one, the
+// target graph is merged with copied statements. Statements are read from
+// the ⟨s, p, o⟩ permutation and loaded into all of them. It returns the
+// number of copied statements. Copy is idempotent, rename of the graph is
+// the copy followed by drop of the source.
+func CopyGraph(ctx context.Context, store *Store, source, target curie.IRI, opts ...GraphOption) (int, error) {
+	if source == target {
+		return 0, fmt.Errorf("cannot copy graph %s to itself", source)
+	}
+
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return copyGraph(ctx, store, store, source, target, l)
+}
+
+// copyGraph copies statements of the graph between stores page by page
+func copyGraph(ctx context.Context, from, to *Store, source, target curie.IRI, l *lifecycle) (int, error) {
+	at := l.at
+	err := pages(ctx, from.spo, spo{}, from.layout.partitions(source), at.C,
+		func(page []Writer, cursor string) error {
+			bag := spock.Bag{}
+			for _, w := range page {
+				bag = append(bag, w.(spo).ToSPOCK()...)
+			}
+
+			if _, err := Load(ctx, to, target, bag, l.load...); err != nil {
+				return err
+			}
+
+			at.C = cursor
+			at.N += len(page)
+			at.M += len(bag)
+			return l.report(at)
+		},
+	)
+
+	return at.M, err
+}
+
+// scan applies f to pages of index permutations of the graph one after
+// another, statements are counted in ⟨s, p, o⟩ permutation.
+func (store *Store) scan(ctx context.Context, graph curie.IRI, l *lifecycle, f func(int, []Writer) error) (checkpoint, error) {
+	at := l.at
+	partitions := store.layout.partitions(graph)
+
+	for i := l.at.I; i < len(permutations); i++ {
+		from := ""
+		if i == l.at.I {
+			from = l.at.C
+		}
+
+		g := func(page []Writer, cursor string) error {
+			if err := f(i, page); err != nil {
+				return err
+			}
+
+			at.I, at.C = i, cursor
+			at.N += len(page)
+			if i == 0 {
+				for _, w := range page {
+					_, vals := w.SetOf()
+					at.M += len(vals)
+				}
+			}
+			return l.report(at)
+		}
+
+		var err error
+		switch i {
+		case 0:
+			err = pages(ctx, store.spo, spo{}, partitions, from, g)
+		case 1:
+			err = pages(ctx, store.sop, sop{}, partitions, from, g)
+		case 2:
+			err = pages(ctx, store.pos, pos{}, partitions, from, g)
+		case 3:
+			err = pages(ctx, store.pso, pso{}, partitions, from, g)
+		case 4:
+			err = pages(ctx, store.osp, osp{}, partitions, from, g)
+		case 5:
+			err = pages(ctx, store.ops, ops{}, partitions, from, g)
+		}
+		if err != nil {
+			return at, err
+		}
+	}
+
+	return at, nil
+}
+
+// pages applies f to pages of entries of all partitions of the index
+// permutation, the scan starts after the position given by cursor. The
+// function receives the cursor of position after the page.
+func pages[T interface {
+	partitioned[T]
+	Writer
+}](ctx context.Context, storage *ddb.Storage[T], key T, partitions []curie.IRI, cursor string, f func([]Writer, string) error) error {
+	m, err := newMatcher([]MatchOption{WithCursor(cursor), WithPageSize(maxTransactItems)})
+	if err != nil {
+		return err
+	}
+
+	hash := make([]curie.IRI, len(partitions))
+	for i, g := range partitions {
+		hash[i] = key.inGraph(g).HashKey()
+	}
+
+	at, pos, err := m.start(hash)
+	if err != nil {
+		return err
+	}
+
+	for p := at; p < len(partitions); p++ {
+		var next dynamo.MatchOpt = none("")
+		if p == at && pos.S != "" {
+			next = dynamo.Cursor(entryKey{hash: hash[p], sort: pos.S})
+		}
+
+		for next != nil {
+			page, cursor, err := storage.Match(ctx, key.inGraph(partitions[p]), next, dynamo.Limit(m.pageSize))
+			if err != nil {
+				return err
+			}
+			next = cursor
+
+			if len(page) == 0 {
+				continue
+			}
+
+			seq := make([]Writer, len(page))
+			for i, x := range page {
+				seq[i] = x
+			}
+
+			last := position{P: p, H: hash[p], S: page[len(page)-1].SortKey()}
+			if err := f(seq, last.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// drop deletes items by single transaction
+func (t *table) drop(ctx context.Context, seq []Writer) error {
+	items := make([]types.TransactWriteItem, len(seq))
+	for i, w := range seq {
+		items[i] = types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName: aws.String(t.name),
+				Key:       t.key(w.HashKey(), w.SortKey()),
+			},
+		}
+	}
+
+	_, err := t.service.TransactWriteItems(ctx,
+		&dynamodb.TransactWriteItemsInput{TransactItems: items},
+	)
+	return err
+}
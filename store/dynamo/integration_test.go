@@ -1,5 +1,3 @@
-//go:build it
-
 /*
 
   Knowledge Graph: SPOCK
@@ -21,22 +19,34 @@
 */
 
 //
-// go test -tags=it
+// The suite runs against in-memory stand-in of DynamoDB, use the real
+// table with
+//
+//	CONFIG_IT_SPOCK_DYNAMO=ddb:///table go test
 //
 
 package dynamo_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/fogfish/curie"
+	ddb "github.com/fogfish/dynamo/v2"
 	"github.com/fogfish/it/v2"
 	"github.com/kshard/spock"
 	"github.com/kshard/spock/store/dynamo"
+	"github.com/kshard/spock/store/dynamo/dynamotest"
+	"github.com/kshard/xsd"
 )
 
 const (
@@ -68,8 +78,19 @@ func datasetSocialGraph() spock.Bag {
 	}
 }
 
+// in-memory service is shared by stores of the suite as the real table
+var service = dynamotest.New()
+
+func connect(opts ...ddb.Option) (*dynamo.Store, error) {
+	if connector := os.Getenv("CONFIG_IT_SPOCK_DYNAMO"); connector != "" {
+		return dynamo.New(connector, opts...)
+	}
+
+	return dynamo.New("ddb:///spock", append(opts, ddb.WithService(service))...)
+}
+
 func setup(bag spock.Bag) *dynamo.Store {
-	store, err := dynamo.New(os.Getenv("CONFIG_IT_SPOCK_DYNAMO"))
+	store, err := connect()
 	if err != nil {
 		panic(err)
 	}
@@ -256,27 +277,27 @@ func TestSocialGraph(t *testing.T) {
 
 	t.Run("#6: (s)º ⇒ p", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(s)º ⇒ p",
+			Seq(t, "(s)º ⇒ p",
 				spock.Query(spock.IRI.Equal(D), nil, spock.Gt("a")),
 			).Equal(
-			// spock.From(D, "status", "d"),
+				spock.From(D, "status", "d"),
 			),
 		)
 	})
 
 	t.Run("#6: (s)º ⇒ p", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(s)º ⇒ p",
+			Seq(t, "(s)º ⇒ p",
 				spock.Query(spock.IRI.Equal(D), nil, spock.Lt("x")),
 			).Equal(
-			// spock.From(D, "status", "d"),
+				spock.From(D, "status", "d"),
 			),
 		)
 	})
 
 	t.Run("#6: (s)º ⇒ p", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(s)º ⇒ p",
+			Seq(t, "(s)º ⇒ p",
 				spock.Query(spock.IRI.Equal(D), nil, spock.Gt("x")),
 			).Equal(),
 		)
@@ -284,7 +305,7 @@ func TestSocialGraph(t *testing.T) {
 
 	t.Run("#6: (s)º ⇒ p", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(s)º ⇒ p",
+			Seq(t, "(s)º ⇒ p",
 				spock.Query(spock.IRI.Equal(D), nil, spock.Lt("a")),
 			).Equal(),
 		)
@@ -581,42 +602,42 @@ func TestSocialGraph(t *testing.T) {
 
 	t.Run("#13: (p)º ⇒ s", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(p)º ⇒ s",
+			Seq(t, "(p)º ⇒ s",
 				spock.Query(nil, spock.IRI.Equal("status"), spock.Gt("a")),
 			).Equal(
-			// spock.From(B, "status", "b"),
-			// spock.From(D, "status", "d"),
-			// spock.From(G, "status", "g"),
+				spock.From(B, "status", "b"),
+				spock.From(D, "status", "d"),
+				spock.From(G, "status", "g"),
 			),
 		)
 	})
 
 	t.Run("#13: (p)º ⇒ s", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(p)º ⇒ s",
+			Seq(t, "(p)º ⇒ s",
 				spock.Query(nil, spock.IRI.Equal("status"), spock.Lt("x")),
 			).Equal(
-			// spock.From(B, "status", "b"),
-			// spock.From(D, "status", "d"),
-			// spock.From(G, "status", "g"),
+				spock.From(B, "status", "b"),
+				spock.From(D, "status", "d"),
+				spock.From(G, "status", "g"),
 			),
 		)
 	})
 
 	t.Run("#13: (p)º ⇒ s", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(p)º ⇒ s",
+			Seq(t, "(p)º ⇒ s",
 				spock.Query(nil, spock.IRI.Equal("status"), spock.In("d", "g")),
 			).Equal(
-			// spock.From(D, "status", "d"),
-			// spock.From(G, "status", "g"),
+				spock.From(D, "status", "d"),
+				spock.From(G, "status", "g"),
 			),
 		)
 	})
 
 	t.Run("#13: (p)º ⇒ s", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(p)º ⇒ s",
+			Seq(t, "(p)º ⇒ s",
 				spock.Query(nil, spock.IRI.Equal("status"), spock.Gt("x")),
 			).Equal(),
 		)
@@ -624,7 +645,7 @@ func TestSocialGraph(t *testing.T) {
 
 	t.Run("#13: (p)º ⇒ s", func(t *testing.T) {
 		it.Then(t).Should(
-			NotSupported(t, "(p)º ⇒ s",
+			Seq(t, "(p)º ⇒ s",
 				spock.Query(nil, spock.IRI.Equal("none"), spock.Gt("a")),
 			).Equal(),
 		)
@@ -1146,3 +1167,869 @@ func TestSocialGraph(t *testing.T) {
 	})
 
 }
+
+// gYear is xsd type that is not supported by the store
+type gYear int
+
+func (gYear) XSDType() xsd.Symbol { return xsd.ToSymbol("xsd:gYear") }
+
+func TestNotSupported(t *testing.T) {
+	ctx := context.Background()
+	rds, err := connect()
+	it.Then(t).Should(it.Nil(err))
+
+	x := spock.SPOCK{S: xsd.ToAnyURI(A), P: xsd.ToAnyURI("age"), O: gYear(2023)}
+	var notSupported interface{ NotSupported() }
+
+	t.Run("Put", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Fail(func() error { return dynamo.Put(ctx, rds, "it:xsd", x) }).With(&notSupported),
+		)
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		bag := spock.Bag{spock.From(A, "status", "a"), x}
+		seq, err := dynamo.Load(ctx, rds, "it:xsd", bag)
+		it.Then(t).Should(
+			it.Fail(func() error { return err }).With(&notSupported),
+			it.Seq(seq).Equal(bag...),
+		)
+	})
+
+	t.Run("Cut", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Fail(func() error { return dynamo.Cut(ctx, rds, "it:xsd", x) }).With(&notSupported),
+		)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		q := spock.Query(nil, spock.IRI.Equal("age"), &spock.Predicate[xsd.Value]{Clause: spock.EQ, Value: gYear(2023)})
+		it.Then(t).Should(
+			it.Error(dynamo.Match(ctx, rds, "it:xsd", q)).With(&notSupported),
+		)
+	})
+
+	t.Run("Nothing", func(t *testing.T) {
+		stat, err := dynamo.Stats(ctx, rds, "it:xsd")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(stat.Items, 0),
+		)
+	})
+}
+
+func TestTypedValues(t *testing.T) {
+	ctx := context.Background()
+	rds, err := connect()
+	it.Then(t).Should(it.Nil(err))
+
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+	bag := spock.Bag{
+		spock.From(A, "n", -100),
+		spock.From(B, "n", -9),
+		spock.From(C, "n", 0),
+		spock.From(D, "n", 9),
+		spock.From(E, "n", 100),
+		spock.From(A, "x", -1e10),
+		spock.From(B, "x", -0.5),
+		spock.From(C, "x", 0.25),
+		spock.From(D, "x", 1e10),
+		spock.From(A, "b", false),
+		spock.From(B, "b", true),
+		spock.From(A, "t", day(9)),
+		spock.From(B, "t", day(10)),
+		spock.From(C, "t", day(31)),
+		spock.From(A, "s", "90"),
+	}
+
+	_, err = dynamo.Add(ctx, rds, "it:typed", bag)
+	it.Then(t).Should(it.Nil(err))
+
+	Seq := func(t *testing.T, q spock.Pattern) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		found := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, "it:typed", q)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(found.Join)),
+		)
+
+		return it.Seq(found)
+	}
+
+	t.Run("Eq", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Eq("n"), spock.Eq(-9))).Equal(bag[1]),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("x"), spock.Eq(0.25))).Equal(bag[7]),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("b"), spock.Eq(true))).Equal(bag[10]),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("t"), spock.Eq(day(10)))).Equal(bag[12]),
+		)
+	})
+
+	t.Run("Gt", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Eq("n"), spock.Gt(-9))).Equal(bag[2:5]...),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("x"), spock.Gt(-0.5))).Equal(bag[7:9]...),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("t"), spock.Gt(day(9)))).Equal(bag[12:14]...),
+		)
+	})
+
+	t.Run("Lt", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Eq("n"), spock.Lt(9))).Equal(bag[0:3]...),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("x"), spock.Lt(0.25))).Equal(bag[5:7]...),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("b"), spock.Lt(true))).Equal(bag[9]),
+		)
+	})
+
+	t.Run("In", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Eq("n"), spock.In(-9, 9))).Equal(bag[1:4]...),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("t"), spock.In(day(1), day(10)))).Equal(bag[11:13]...),
+		)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, x := range bag {
+			q := spock.Query(spock.IRI.Eq(curie.IRI(x.S.String())), spock.IRI.Eq(curie.IRI(x.P.String())), nil)
+			it.Then(t).Should(
+				Seq(t, q).Equal(x),
+			)
+		}
+	})
+
+	t.Run("DateTimeRange", func(t *testing.T) {
+		x := spock.From(A, "t", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC))
+		var notSupported interface{ NotSupported() }
+		it.Then(t).Should(
+			it.Fail(func() error { return dynamo.Put(ctx, rds, "it:typed", x) }).With(&notSupported),
+		)
+	})
+}
+
+func TestConsistency(t *testing.T) {
+	rds := setup(datasetSocialGraph())
+
+	seq, err := dynamo.Check(context.Background(), rds, "it")
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(len(seq), 0),
+	)
+
+	seq, err = dynamo.Repair(context.Background(), rds, "it")
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(len(seq), 0),
+	)
+
+	t.Run("Divergence", func(t *testing.T) {
+		if os.Getenv("CONFIG_IT_SPOCK_DYNAMO") != "" {
+			t.Skip("divergence is injected into in-memory service only")
+		}
+
+		ctx := context.Background()
+		bag := datasetSocialGraph()
+		_, err := dynamo.Load(ctx, rds, "it:check", bag)
+		it.Then(t).Should(it.Nil(err))
+
+		// removes value from the set of entry
+		lose := func(hash, sort, attr, value string) {
+			_, err := service.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName: aws.String("spock"),
+				Key: map[string]types.AttributeValue{
+					"prefix": &types.AttributeValueMemberS{Value: hash},
+					"suffix": &types.AttributeValueMemberS{Value: sort},
+				},
+				UpdateExpression:         aws.String("DELETE #a :v"),
+				ExpressionAttributeNames: map[string]string{"#a": attr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":v": &types.AttributeValueMemberSS{Value: []string{value}},
+				},
+			})
+			it.Then(t).Should(it.Nil(err))
+		}
+
+		// ⟨s:C follows u:B⟩ is lost by ⟨s, p, o⟩ and ⟨p, o, s⟩ permutations
+		lose("sp|it:check", "s:C|follows", "o", "ᴵu:B")
+		lose("po|it:check", "follows|ᴵu:B", "s", "s:C")
+
+		seq, err := dynamo.Check(ctx, rds, "it:check")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 1),
+			it.Equal(seq[0].SPOCK, spock.From(C, "follows", B)),
+			it.Seq(seq[0].Missing).Equal("spo", "pos"),
+		)
+
+		seq, err = dynamo.Repair(ctx, rds, "it:check")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 1),
+		)
+
+		seq, err = dynamo.Check(ctx, rds, "it:check")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 0),
+		)
+	})
+}
+
+func TestRemove(t *testing.T) {
+	rds, err := connect()
+	it.Then(t).Should(it.Nil(err))
+
+	ctx := context.Background()
+	bag := spock.Bag{
+		spock.From(A, "follows", B),
+		spock.From(A, "follows", C),
+		spock.From(A, "status", "a"),
+		spock.From(B, "status", "b"),
+	}
+	_, err = dynamo.Add(ctx, rds, "it:remove", bag)
+	it.Then(t).Should(it.Nil(err))
+
+	Seq := func(t *testing.T, req spock.Pattern) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		seq := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, "it:remove", req)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(seq.Join)),
+		)
+		return it.Seq(seq)
+	}
+
+	t.Run("Cut", func(t *testing.T) {
+		err := dynamo.Cut(ctx, rds, "it:remove", spock.From(A, "follows", B))
+		it.Then(t).Should(
+			it.Nil(err),
+			Seq(t, spock.Query(nil, nil, spock.Eq(B))).Equal(),
+			Seq(t, spock.Query(spock.IRI.Eq(A), spock.IRI.Eq("follows"), nil)).Equal(
+				spock.From(A, "follows", C),
+			),
+		)
+	})
+
+	t.Run("RemoveSubject", func(t *testing.T) {
+		err := dynamo.RemoveSubject(ctx, rds, "it:remove", A)
+		it.Then(t).Should(
+			it.Nil(err),
+			Seq(t, spock.Query(spock.IRI.Eq(A), nil, nil)).Equal(),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("status"), nil)).Equal(
+				spock.From(B, "status", "b"),
+			),
+		)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		seq, err := dynamo.Remove(ctx, rds, "it:remove", bag)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 0),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("status"), nil)).Equal(),
+		)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	rds, err := connect()
+	it.Then(t).Should(it.Nil(err))
+
+	ctx := context.Background()
+	bag := datasetSocialGraph()
+	for i := 0; i < 250; i++ {
+		bag = append(bag, spock.From(N, "follows", curie.IRI(fmt.Sprintf("u:%d", i))))
+	}
+
+	seq, err := dynamo.Load(ctx, rds, "it:load", bag, dynamo.WithConcurrency(2))
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(len(seq), 0),
+	)
+
+	for _, x := range bag {
+		q := spock.Query(
+			spock.IRI.Eq(curie.IRI(x.S.String())),
+			spock.IRI.Eq(curie.IRI(x.P.String())),
+			nil,
+		)
+		found := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, "it:load", q)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(found.Join)),
+			it.Seq(found).Contain(x),
+		)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	// transactions of in-memory service conflict if they overlap in time
+	rds, err := dynamo.New("ddb:///spock",
+		ddb.WithService(dynamotest.New(dynamotest.WithLatency(10*time.Millisecond))),
+	)
+	it.Then(t).Should(it.Nil(err))
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			x := spock.From(curie.IRI(fmt.Sprintf("u:%d", i)), "status", "a")
+			errs[i] = dynamo.Put(ctx, rds, "it:concurrent", x)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		it.Then(t).Should(it.Nil(err))
+	}
+
+	seq, err := dynamo.Graphs(ctx, rds)
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Seq(seq).Equal("it:concurrent"),
+	)
+}
+
+func TestShardedLayout(t *testing.T) {
+	ctx := context.Background()
+	source := setup(datasetSocialGraph())
+	target, err := connect(dynamo.WithShards(4))
+	it.Then(t).Should(it.Nil(err))
+
+	n, err := dynamo.Migrate(ctx, source, target, "it")
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(n, len(datasetSocialGraph())),
+	)
+
+	Seq := func(t *testing.T, req spock.Pattern) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		bag := spock.Bag{}
+		seq, err := dynamo.Match(ctx, target, "it", req)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(seq.FMap(bag.Join)),
+		)
+		return it.Seq(bag)
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(spock.IRI.Equal(C), nil, nil)).Equal(
+				spock.From(C, "follows", B),
+				spock.From(C, "follows", E),
+				spock.From(C, "relates", D),
+			),
+		)
+	})
+
+	t.Run("FanOut", func(t *testing.T) {
+		bag := Seq(t, spock.Query(spock.IRI.HasPrefix("s:"), nil, nil))
+		it.Then(t).Should(
+			it.Equal(len(bag), 5),
+			bag.Contain().AllOf(
+				spock.From(C, "follows", B),
+				spock.From(C, "follows", E),
+				spock.From(C, "relates", D),
+				spock.From(F, "follows", G),
+				spock.From(G, "status", "g"),
+			),
+		)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Equal("status"), spock.In("d", "g"))).Equal(
+				spock.From(D, "status", "d"),
+				spock.From(G, "status", "g"),
+			),
+		)
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		seq, err := dynamo.Check(ctx, target, "it")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(len(seq), 0),
+		)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		// the migration is aborted after the first page
+		cursor := ""
+		abort := func(p dynamo.Progress) error {
+			cursor = p.Cursor
+			return fmt.Errorf("abort")
+		}
+
+		_, err := dynamo.Migrate(ctx, source, target, "it", dynamo.WithProgress(abort))
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		).ShouldNot(
+			it.Equal(cursor, ""),
+		)
+
+		n, err := dynamo.Migrate(ctx, source, target, "it",
+			dynamo.WithResume(cursor),
+			dynamo.WithLoader(dynamo.WithConcurrency(1)),
+		)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(n, len(datasetSocialGraph())),
+		)
+	})
+}
+
+func TestCursor(t *testing.T) {
+	ctx := context.Background()
+	rds := setup(datasetSocialGraph())
+
+	// reads n statements from the query, n < 0 reads all of them
+	Page := func(t *testing.T, req spock.Pattern, n int, opts ...dynamo.MatchOption) (spock.Bag, string) {
+		t.Helper()
+		bag := spock.Bag{}
+		seq, err := dynamo.Match(ctx, rds, "it", req, opts...)
+		it.Then(t).Should(it.Nil(err))
+
+		for n != 0 && seq.Next() {
+			bag = append(bag, seq.Head())
+			n--
+		}
+		return bag, seq.Cursor()
+	}
+
+	Resume := func(t *testing.T, req spock.Pattern) {
+		t.Helper()
+		all, cursor := Page(t, req, -1)
+		it.Then(t).Should(it.Equal(cursor, ""))
+
+		bag := spock.Bag{}
+		for {
+			page, next := Page(t, req, 2, dynamo.WithPageSize(1), dynamo.WithCursor(cursor))
+			bag = append(bag, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		it.Then(t).Should(
+			it.Equal(len(bag), len(all)),
+			it.Seq(bag).Equal(all...),
+		)
+	}
+
+	t.Run("Resume", func(t *testing.T) {
+		Resume(t, spock.Query(spock.IRI.Equal(C), nil, nil))
+	})
+
+	t.Run("ResumeEntry", func(t *testing.T) {
+		Resume(t, spock.Query(nil, spock.IRI.Equal("follows"), spock.Eq(B)))
+	})
+
+	t.Run("ResumeFilter", func(t *testing.T) {
+		Resume(t, spock.Query(spock.IRI.Equal(C), spock.IRI.Equal("follows"), spock.HasPrefix(curie.IRI("u:"))))
+	})
+
+	t.Run("ResumeRange", func(t *testing.T) {
+		Resume(t, spock.Query(nil, spock.IRI.Equal("status"), spock.In("b", "g")))
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		for _, req := range []spock.Pattern{
+			spock.Query(spock.IRI.Equal(C), nil, nil),
+			spock.Query(spock.IRI.Equal(C), spock.IRI.Equal("follows"), spock.HasPrefix(curie.IRI("u:"))),
+			spock.Query(nil, spock.IRI.Equal("status"), spock.In("b", "g")),
+		} {
+			seq, err := dynamo.Match(ctx, rds, "it", req, dynamo.WithPageSize(1))
+			it.Then(t).Should(
+				it.Nil(err),
+				it.True(seq.Next()),
+				it.Nil(spock.Close(seq)),
+			).ShouldNot(
+				it.True(seq.Next()),
+			)
+		}
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		var err interface{ InvalidCursor() }
+
+		it.Then(t).Should(
+			it.Error(
+				dynamo.Match(ctx, rds, "it", spock.Query(spock.IRI.Equal(C), nil, nil), dynamo.WithCursor("@")),
+			).With(&err),
+		)
+	})
+}
+
+// faulty service fails queries on demand
+type faulty struct {
+	dynamo.DynamoDB
+	err error
+}
+
+func (f *faulty) Query(ctx context.Context, in *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.DynamoDB.Query(ctx, in, opts...)
+}
+
+func TestStreamError(t *testing.T) {
+	if os.Getenv("CONFIG_IT_SPOCK_DYNAMO") != "" {
+		t.Skip("faults are injected into in-memory service only")
+	}
+
+	ctx := context.Background()
+	setup(datasetSocialGraph())
+
+	fault := &faulty{DynamoDB: service}
+	rds, err := dynamo.New("ddb:///spock", ddb.WithService(fault))
+	it.Then(t).Should(it.Nil(err))
+
+	Fail := func(t *testing.T, req spock.Pattern) {
+		t.Helper()
+		fault.err = nil
+		all := spock.Bag{}
+		seq, err := dynamo.Match(ctx, rds, "it", req)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(seq.FMap(all.Join)),
+		)
+
+		seq, err = dynamo.Match(ctx, rds, "it", req, dynamo.WithPageSize(1))
+		it.Then(t).Should(it.Nil(err), it.True(seq.Next()))
+		bag := spock.Bag{seq.Head()}
+
+		// statements of the buffered page are consumed before failure
+		fault.err = fmt.Errorf("unavailable")
+		for seq.Next() {
+			bag = append(bag, seq.Head())
+		}
+		it.Then(t).Should(
+			it.True(errors.Is(seq.Err(), fault.err)),
+			it.True(seq.Cursor() != ""),
+		)
+
+		seq, err = dynamo.Match(ctx, rds, "it", req, dynamo.WithCursor(seq.Cursor()))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.True(errors.Is(seq.FMap(bag.Join), fault.err)),
+		)
+
+		fault.err = nil
+		seq, err = dynamo.Match(ctx, rds, "it", req, dynamo.WithCursor(seq.Cursor()))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(seq.FMap(bag.Join)),
+			it.Seq(bag).Equal(all...),
+		)
+	}
+
+	t.Run("Iterator", func(t *testing.T) {
+		Fail(t, spock.Query(spock.IRI.Equal(C), nil, nil))
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		Fail(t, spock.Query(spock.IRI.Equal(C), spock.IRI.Equal("follows"), spock.HasPrefix(curie.IRI("u:"))))
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		Fail(t, spock.Query(nil, spock.IRI.Equal("status"), spock.In("b", "g")))
+	})
+}
+
+func TestOverflow(t *testing.T) {
+	ctx := context.Background()
+
+	// the last reported size of ⟨p, o⟩ entries
+	var mu sync.Mutex
+	stats := map[curie.IRI]dynamo.SetStat{}
+	hook := dynamo.WithOverflowHook(func(s dynamo.SetStat) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(string(s.HashKey), "po|") {
+			stats[s.HashKey] = s
+		}
+	})
+
+	// values are IRIs of 3 bytes, the item holds 2 of them
+	rds, err := connect(dynamo.WithSetSize(6), hook)
+	it.Then(t).Should(it.Nil(err))
+
+	bag := spock.Bag{
+		spock.From(A, "follows", N),
+		spock.From(B, "follows", N),
+		spock.From(C, "follows", N),
+		spock.From(D, "follows", N),
+		spock.From(E, "follows", N),
+	}
+	_, err = dynamo.Add(ctx, rds, "it:overflow", bag)
+	it.Then(t).Should(it.Nil(err))
+
+	Seq := func(t *testing.T, req spock.Pattern) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		seq := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, "it:overflow", req)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(seq.Join)),
+		)
+		return it.Seq(seq)
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		seq := Seq(t, spock.Query(nil, spock.IRI.Eq("follows"), spock.Eq(N)))
+		it.Then(t).Should(
+			it.Equal(len(seq), 5),
+			seq.Contain().AllOf(bag...),
+		)
+	})
+
+	t.Run("OverflowHook", func(t *testing.T) {
+		s := stats["po|it:overflow"]
+		it.Then(t).Should(
+			it.Equal(s.Values, 5),
+			it.Equal(s.Size, 15),
+			it.Equal(s.Items, 3),
+		)
+	})
+
+	t.Run("Cut", func(t *testing.T) {
+		_, err := dynamo.Remove(ctx, rds, "it:overflow", bag)
+		it.Then(t).Should(
+			it.Nil(err),
+			Seq(t, spock.Query(nil, spock.IRI.Eq("follows"), spock.Eq(N))).Equal(),
+		)
+	})
+
+	t.Run("Idempotent", func(t *testing.T) {
+		_, err := dynamo.Add(ctx, rds, "it:overflow", bag[:3])
+		it.Then(t).Should(it.Nil(err))
+
+		_, err = dynamo.Add(ctx, rds, "it:overflow", bag[:2])
+		it.Then(t).Should(it.Nil(err))
+
+		_, err = dynamo.Load(ctx, rds, "it:overflow", bag[:3])
+		it.Then(t).Should(it.Nil(err))
+
+		seq := Seq(t, spock.Query(nil, spock.IRI.Eq("follows"), spock.Eq(N)))
+		it.Then(t).Should(
+			it.Equal(len(seq), 3),
+			seq.Contain().AllOf(bag[:3]...),
+		)
+
+		_, err = dynamo.Remove(ctx, rds, "it:overflow", bag[:3])
+		it.Then(t).Should(it.Nil(err))
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		rds, err := connect(dynamo.WithSetSize(9), hook)
+		it.Then(t).Should(it.Nil(err))
+
+		_, err = dynamo.Add(ctx, rds, "it:overflow:capacity", bag[:2])
+		it.Then(t).Should(it.Nil(err))
+
+		_, err = dynamo.Load(ctx, rds, "it:overflow:capacity", bag[2:])
+		it.Then(t).Should(it.Nil(err))
+
+		s := stats["po|it:overflow:capacity"]
+		it.Then(t).Should(
+			it.Equal(s.Values, 5),
+			it.Equal(s.Items, 2),
+		)
+	})
+
+	t.Run("ItemSize", func(t *testing.T) {
+		rds, err := connect()
+		it.Then(t).Should(it.Nil(err))
+
+		// values of the entry exceed the item size of DynamoDB (400 KB)
+		bag := spock.Bag{}
+		for i := 0; i < 600; i++ {
+			s := curie.IRI(fmt.Sprintf("u:%s%d", strings.Repeat("x", 800), i))
+			bag = append(bag, spock.From(s, "follows", N))
+		}
+
+		_, err = dynamo.Load(ctx, rds, "it:overflow:size", bag[:500])
+		it.Then(t).Should(it.Nil(err))
+
+		_, err = dynamo.Add(ctx, rds, "it:overflow:size", bag[500:])
+		it.Then(t).Should(it.Nil(err))
+
+		seq := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, "it:overflow:size",
+			spock.Query(nil, spock.IRI.Eq("follows"), spock.Eq(N)),
+		)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(seq.Join)),
+			it.Equal(len(seq), len(bag)),
+		)
+	})
+
+	t.Run("Reserved", func(t *testing.T) {
+		var err interface{ NotSupported() }
+
+		it.Then(t).Should(
+			it.Fail(func() error {
+				return dynamo.Put(ctx, rds, "it:overflow", spock.From(A, "status", "x\x1f12"))
+			}).With(&err),
+			it.Fail(func() error {
+				_, err := dynamo.Load(ctx, rds, "it:overflow", spock.Bag{spock.From(A, "status\x1f1", "x")})
+				return err
+			}).With(&err),
+		)
+	})
+}
+
+func TestGraphLifecycle(t *testing.T) {
+	ctx := context.Background()
+	rds, err := connect()
+	it.Then(t).Should(it.Nil(err))
+
+	bag := datasetSocialGraph()
+	_, err = dynamo.Load(ctx, rds, "it:lifecycle:a", bag)
+	it.Then(t).Should(it.Nil(err))
+
+	Seq := func(t *testing.T, graph curie.IRI) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		seq := spock.Bag{}
+		stream, err := dynamo.Match(ctx, rds, graph, spock.Query(spock.IRI.Equal(C), nil, nil))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(stream.FMap(seq.Join)),
+		)
+		return it.Seq(seq)
+	}
+
+	t.Run("Graphs", func(t *testing.T) {
+		seq, err := dynamo.Graphs(ctx, rds)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(seq).Contain("it:lifecycle:a"),
+		)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stat, err := dynamo.Stats(ctx, rds, "it:lifecycle:a")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(stat.Statements, len(bag)),
+			it.True(stat.Items > 0),
+		)
+	})
+
+	t.Run("CopyGraph", func(t *testing.T) {
+		n, err := dynamo.CopyGraph(ctx, rds, "it:lifecycle:a", "it:lifecycle:b")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(n, len(bag)),
+			Seq(t, "it:lifecycle:b").Equal(
+				spock.From(C, "follows", B),
+				spock.From(C, "follows", E),
+				spock.From(C, "relates", D),
+			),
+		)
+
+		a, _ := dynamo.Stats(ctx, rds, "it:lifecycle:a")
+		b, _ := dynamo.Stats(ctx, rds, "it:lifecycle:b")
+		it.Then(t).Should(
+			it.Equal(a.Statements, b.Statements),
+			it.Equal(a.Items, b.Items),
+		)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		// the operation is aborted after the first page
+		cursor := ""
+		abort := func(p dynamo.Progress) error {
+			cursor = p.Cursor
+			return fmt.Errorf("abort")
+		}
+
+		_, err := dynamo.DropGraph(ctx, rds, "it:lifecycle:b", dynamo.WithProgress(abort))
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		).ShouldNot(
+			it.Equal(cursor, ""),
+		)
+
+		n, err := dynamo.DropGraph(ctx, rds, "it:lifecycle:b", dynamo.WithResume(cursor))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(n, len(bag)),
+			Seq(t, "it:lifecycle:b").Equal(),
+		)
+
+		stat, err := dynamo.Stats(ctx, rds, "it:lifecycle:b")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(stat.Items, 0),
+		)
+	})
+
+	t.Run("DropGraph", func(t *testing.T) {
+		_, err := dynamo.DropGraph(ctx, rds, "it:lifecycle:a")
+		it.Then(t).Should(it.Nil(err))
+
+		seq, err := dynamo.Graphs(ctx, rds)
+		it.Then(t).Should(it.Nil(err))
+		for _, g := range seq {
+			it.Then(t).ShouldNot(
+				it.Equal(g, "it:lifecycle:a"),
+				it.Equal(g, "it:lifecycle:b"),
+			)
+		}
+	})
+
+	t.Run("Instances", func(t *testing.T) {
+		other, err := connect()
+		it.Then(t).Should(it.Nil(err))
+
+		it.Then(t).Should(
+			it.Nil(dynamo.Put(ctx, rds, "it:lifecycle:c", bag[0])),
+		)
+
+		_, err = dynamo.DropGraph(ctx, other, "it:lifecycle:c")
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Nil(dynamo.RegisterGraph(ctx, rds, "it:lifecycle:c")),
+			it.Nil(dynamo.Put(ctx, rds, "it:lifecycle:c", bag[0])),
+		)
+
+		seq, err := dynamo.Graphs(ctx, other)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(seq).Contain("it:lifecycle:c"),
+		)
+	})
+
+	t.Run("RegisterGraph", func(t *testing.T) {
+		it.Then(t).Should(
+			it.Nil(dynamo.RegisterGraph(ctx, rds, "it:lifecycle:d")),
+		)
+
+		seq, err := dynamo.Graphs(ctx, rds)
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(seq).Contain("it:lifecycle:d"),
+		)
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		var err interface{ InvalidCursor() }
+
+		it.Then(t).Should(
+			it.Error(
+				dynamo.Stats(ctx, rds, "it:lifecycle:a", dynamo.WithResume("@")),
+			).With(&err),
+		)
+	})
+}
@@ -22,7 +22,9 @@ package dynamo
 
 import (
 	"context"
+	"io"
 
+	"github.com/fogfish/curie"
 	"github.com/fogfish/dynamo/v2"
 	"github.com/fogfish/dynamo/v2/service/ddb"
 	"github.com/kshard/spock"
@@ -35,21 +37,29 @@ func (none) MatchOpt() {}
 type Seq[T dynamo.Thing] interface {
 	Head() T
 	Next() bool
+	Err() error
 }
 
 func NewIterator[T dynamo.Thing](store *ddb.Storage[T], query T) Seq[T] {
+	return newIterator(store, query, 100, none(""))
+}
+
+func newIterator[T dynamo.Thing](store *ddb.Storage[T], query T, limit int32, cursor dynamo.MatchOpt) *Iterator[T] {
 	return &Iterator[T]{
 		store:  store,
 		query:  query,
-		cursor: none(""),
+		limit:  limit,
+		cursor: cursor,
 	}
 }
 
 type Iterator[T dynamo.Thing] struct {
 	store  *ddb.Storage[T]
 	query  T
+	limit  int32
 	cursor dynamo.MatchOpt
 	seq    []T
+	err    error
 }
 
 func (iter *Iterator[T]) Head() T {
@@ -68,9 +78,10 @@ func (iter *Iterator[T]) Next() bool {
 
 	var err error
 	iter.seq, iter.cursor, err = iter.store.Match(context.TODO(),
-		iter.query, iter.cursor, dynamo.Limit(2),
+		iter.query, iter.cursor, dynamo.Limit(iter.limit),
 	)
 	if err != nil {
+		iter.seq, iter.cursor, iter.err = nil, nil, err
 		return false
 	}
 
@@ -81,9 +92,26 @@ func (iter *Iterator[T]) Next() bool {
 	return true
 }
 
+// Err returns the error that terminated the sequence
+func (iter *Iterator[T]) Err() error {
+	return iter.err
+}
+
+// Close discards the cursor and buffered page, the sequence is exhausted afterwards
+func (iter *Iterator[T]) Close() error {
+	iter.seq = nil
+	iter.cursor = nil
+	return nil
+}
+
+// Unfold entries into statements, it tracks position of the stream
 type Unfold[T dynamo.Thing] struct {
-	seq Seq[T]
-	bag []spock.SPOCK
+	seq  Seq[T]
+	bag  []spock.SPOCK
+	head curie.IRI // sort key of current entry
+	last curie.IRI // sort key of last consumed entry
+	n    int       // statements consumed from current entry
+	skip int       // statements to skip from first entry on resume
 }
 
 func (unfold *Unfold[T]) Head() spock.SPOCK {
@@ -93,26 +121,75 @@ func (unfold *Unfold[T]) Head() spock.SPOCK {
 func (unfold *Unfold[T]) Next() bool {
 	if unfold.bag != nil && len(unfold.bag) > 1 {
 		unfold.bag = unfold.bag[1:]
+		unfold.n++
 		return true
 	}
 
-	if !unfold.seq.Next() {
-		return false
-	}
+	// entries with empty set are skipped, they are not statements
+	for {
+		if unfold.head != "" {
+			unfold.last, unfold.n = unfold.head, 0
+		}
+
+		if !unfold.seq.Next() {
+			unfold.bag = nil
+			unfold.head = ""
+			return false
+		}
+
+		entry := unfold.seq.Head()
+		unfold.head = entry.SortKey()
+		unfold.bag = nil
 
-	switch vv := any(unfold.seq.Head()).(type) {
-	case interface{ ToSPOCK() []spock.SPOCK }:
-		unfold.bag = vv.ToSPOCK()
+		switch vv := any(entry).(type) {
+		case interface{ ToSPOCK() []spock.SPOCK }:
+			unfold.bag = vv.ToSPOCK()
+		}
+		sortBag(unfold.bag)
+
+		if unfold.skip > 0 {
+			k := unfold.skip
+			if k > len(unfold.bag) {
+				k = len(unfold.bag)
+			}
+			unfold.bag, unfold.n, unfold.skip = unfold.bag[k:], k, 0
+		}
+
+		if len(unfold.bag) > 0 {
+			unfold.n++
+			return true
+		}
 	}
+}
 
-	return true
+// position of the stream within the partition
+func (unfold *Unfold[T]) position() (curie.IRI, int) {
+	if unfold.head == "" {
+		// nothing is consumed from next entry, including the resume
+		return unfold.last, unfold.skip
+	}
+	return unfold.last, unfold.n
 }
 
 func (unfold *Unfold[T]) FMap(f func(spock.SPOCK) error) error {
 	for unfold.Next() {
 		if err := f(unfold.Head()); err != nil {
+			unfold.Close()
 			return err
 		}
 	}
+	return unfold.Err()
+}
+
+// Err returns the error that terminated the stream
+func (unfold *Unfold[T]) Err() error {
+	return unfold.seq.Err()
+}
+
+func (unfold *Unfold[T]) Close() error {
+	unfold.bag = nil
+	if closer, ok := unfold.seq.(io.Closer); ok {
+		return closer.Close()
+	}
 	return nil
 }
@@ -0,0 +1,47 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define migration of graph between stores of different layout.
+//
+
+import (
+	"context"
+
+	"github.com/fogfish/curie"
+)
+
+// Migrate copies statements of the graph from the source store to the target
+// one, e.g. from the default to the sharded layout. Statements are read from
+// the ⟨s, p, o⟩ permutation of the source page by page and loaded into the
+// target (see WithLoader). It returns the number of copied statements.
+// Migration is idempotent, it reports progress and is resumable from the
+// last reported cursor (see WithProgress, WithResume). The source is not
+// modified, remove the graph from it once the target is verified by Check.
+func Migrate(ctx context.Context, source, target *Store, graph curie.IRI, opts ...GraphOption) (int, error) {
+	l, err := newLifecycle(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return copyGraph(ctx, source, target, graph, graph, l)
+}
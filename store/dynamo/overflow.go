@@ -0,0 +1,500 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define overflow of large sets. Each entry of index permutation
+// stores values as string set, which is bounded by the item size limit of
+// DynamoDB (400 KB). The item records the size of its values in bytes, once
+// the limit is reached values are written into continuation items.
+// Continuation item has the sort key of the entry followed by `\x1f` and
+// sequence number, it is stored next to the entry and is decoded as the
+// same entry.
+//
+// The recorded size is upper bound, values written again or removed are
+// not subtracted. The write that does not fit the item reads all items of
+// the entry and records their exact size.
+//
+// The entry that has overflowed records the number of its last continuation
+// item, the record is never removed. Writes to such entry read all its items
+// and add only values that are absent, re-writing the statement is no-op.
+// Concurrent writers of the same value into overflowed entry might store it
+// by two items, readers would see the statement twice.
+//
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v2"
+)
+
+const (
+	// separator of continuation number in the sort key
+	continuation = "\x1f"
+
+	// attribute of the entry that holds number of its last continuation item
+	tailAttr = "tail"
+
+	// attribute of the item that holds size of values in bytes
+	sizeAttr = "size"
+
+	// attempts to resolve overflow of contended entries
+	maxAttempts = 16
+
+	// limit of backoff of conflicting transactions
+	maxBackoff = 100 * time.Millisecond
+
+	// default limit of values in bytes stored by single item, the rest of
+	// the item size (400 KB) is reserved for keys and attributes
+	defaultSetSize = 384 * 1024
+
+	// limit of continuation items per entry
+	maxContinuations = 1 << 16
+)
+
+// WithSetSize configures the size of values in bytes stored by single item
+// of index permutation, values above the limit overflow to continuation
+// items. Default is 384 KB, the item of DynamoDB is limited by 400 KB
+// including keys and names of attributes.
+func WithSetSize(n int) dynamo.Option {
+	return func(c interface{ Config() }) {
+		if t, ok := c.(*table); ok && n > 0 {
+			t.size = n
+		}
+	}
+}
+
+// SetStat is the size of the set stored by entry of index permutation
+type SetStat struct {
+	HashKey curie.IRI
+	SortKey curie.IRI
+	Size    int // size of values in bytes in all items of the entry
+	Values  int // number of values in all items of the entry
+	Items   int // number of items, the entry and its continuations
+}
+
+// WithOverflowHook reports the size of entry at write time, each time the
+// write does not fit the item and values overflow. The entry reported
+// repeatedly is the hotspot of the graph. The function is called by
+// concurrent writers.
+func WithOverflowHook(f func(SetStat)) dynamo.Option {
+	return func(c interface{ Config() }) {
+		if t, ok := c.(*table); ok {
+			t.hook = f
+		}
+	}
+}
+
+// sizeOf values in bytes
+func sizeOf(vals []string) int {
+	size := 0
+	for _, v := range vals {
+		size += len(v)
+	}
+	return size
+}
+
+// continuationOf the sort key, the zero continuation is the key itself
+func continuationOf(sk curie.IRI, n int) curie.IRI {
+	if n == 0 {
+		return sk
+	}
+	return sk + continuation + curie.IRI(strconv.Itoa(n))
+}
+
+// trimContinuation removes continuation number from the sort key
+func trimContinuation(sk string) string {
+	i := strings.LastIndex(sk, continuation)
+	if i == -1 {
+		return sk
+	}
+
+	if _, err := strconv.Atoi(sk[i+1:]); err != nil {
+		return sk
+	}
+	return sk[:i]
+}
+
+// continuationNumber of the item of entry, it returns false if the sort
+// key belongs to other entry
+func continuationNumber(entry, sk string) (int, bool) {
+	if sk == entry {
+		return 0, true
+	}
+
+	if !strings.HasPrefix(sk, entry+continuation) {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(sk[len(entry)+1:])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// slot is an item of the entry, either the entry itself or continuation
+type slot struct {
+	n    int
+	vals []string
+}
+
+// slots reads all items of the entry, it returns them together with the
+// number of last continuation item.
+func (t *table) slots(ctx context.Context, w Writer) ([]slot, int, error) {
+	attr, _ := w.SetOf()
+	sk := string(w.SortKey())
+	seq := make([]slot, 0)
+	tail := 0
+
+	var cursor map[string]types.AttributeValue
+	for {
+		val, err := t.service.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(t.name),
+			KeyConditionExpression: aws.String("#p = :p AND #s BETWEEN :lo AND :hi"),
+			ExpressionAttributeNames: map[string]string{
+				"#p": t.prefix,
+				"#s": t.suffix,
+				"#a": attr,
+				"#t": tailAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":p":  &types.AttributeValueMemberS{Value: string(w.HashKey())},
+				":lo": &types.AttributeValueMemberS{Value: sk},
+				":hi": &types.AttributeValueMemberS{Value: sk + continuation + "\x7f"},
+			},
+			ProjectionExpression: aws.String("#s, #a, #t"),
+			ExclusiveStartKey:    cursor,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, item := range val.Items {
+			key, ok := item[t.suffix].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+
+			n, ok := continuationNumber(sk, key.Value)
+			if !ok {
+				continue
+			}
+
+			s := slot{n: n}
+			if set, ok := item[attr].(*types.AttributeValueMemberSS); ok {
+				s.vals = set.Value
+			}
+			if num, ok := item[tailAttr].(*types.AttributeValueMemberN); ok {
+				if x, err := strconv.Atoi(num.Value); err == nil && x > tail {
+					tail = x
+				}
+			}
+			if n > tail {
+				tail = n
+			}
+			seq = append(seq, s)
+		}
+
+		if len(val.LastEvaluatedKey) == 0 {
+			return seq, tail, nil
+		}
+		cursor = val.LastEvaluatedKey
+	}
+}
+
+// overflow is the write of values into the item of overflowed entry
+type overflow struct {
+	vals  []string // values absent in all items of the entry
+	slot  int      // continuation number of the item that has room for values
+	tail  int      // number of last continuation item
+	count int      // number of values in the item, as it has been read
+	size  int      // size of values in the item after the write
+}
+
+// overflowOf reads the entry and plans the write of absent values
+func (t *table) overflowOf(ctx context.Context, w Writer) (*overflow, error) {
+	seq, tail, err := t.slots(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	size := map[int]int{}
+	count := map[int]int{}
+	known := map[string]struct{}{}
+	stat := SetStat{HashKey: w.HashKey(), SortKey: w.SortKey(), Items: tail + 1}
+	for _, s := range seq {
+		size[s.n] = sizeOf(s.vals)
+		count[s.n] = len(s.vals)
+		stat.Size += size[s.n]
+		stat.Values += len(s.vals)
+		for _, v := range s.vals {
+			known[v] = struct{}{}
+		}
+	}
+
+	_, vals := w.SetOf()
+	plan := &overflow{vals: make([]string, 0, len(vals)), tail: tail, slot: tail + 1}
+	for _, v := range vals {
+		if _, has := known[v]; !has {
+			plan.vals = append(plan.vals, v)
+		}
+	}
+
+	for n := 0; n <= tail; n++ {
+		if size[n]+sizeOf(plan.vals) <= t.size {
+			plan.slot = n
+			break
+		}
+	}
+
+	if plan.slot >= maxContinuations {
+		return nil, fmt.Errorf("dynamo set overflow exceeds %d items", maxContinuations)
+	}
+
+	plan.count = count[plan.slot]
+	plan.size = size[plan.slot] + sizeOf(plan.vals)
+
+	if t.hook != nil {
+		stat.Size += sizeOf(plan.vals)
+		stat.Values += len(plan.vals)
+		if plan.slot > tail {
+			stat.Items = plan.slot + 1
+		}
+		t.hook(stat)
+	}
+
+	return plan, nil
+}
+
+// put adds values to sets of all entries of the graph by single transaction.
+// The entry that has no continuation items is written directly if it has
+// room for values. Otherwise, the condition fails, all items of the entry
+// are read and absent values are written to the first item that has room
+// for them, the transaction is retried. The transaction conflicting with
+// concurrent one is retried with backoff.
+//
+// The transaction is limited by 100 items, the overflow might require more
+// of them for the chunk of bulk loader. The chunk is written by halves then,
+// it is not atomic anymore: the failure leaves the first half written.
+func (t *table) put(ctx context.Context, graph curie.IRI, seq []Writer) error {
+	plan := make([]*overflow, len(seq))
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		items := []types.TransactWriteItem{}
+		owner := []int{}
+		for i, w := range seq {
+			for _, item := range t.putItems(w, plan[i]) {
+				items = append(items, item)
+				owner = append(owner, i)
+			}
+		}
+
+		if len(items) > maxTransactItems && len(seq) > 1 {
+			// chunk of bulk loader exceeds the transaction due to the overflow,
+			// it is written by halves, the atomicity of the chunk is lost.
+			if err := t.put(ctx, graph, seq[:len(seq)/2]); err != nil {
+				return err
+			}
+			return t.put(ctx, graph, seq[len(seq)/2:])
+		}
+
+		_, err := t.service.TransactWriteItems(ctx,
+			&dynamodb.TransactWriteItemsInput{TransactItems: items},
+		)
+		if err == nil {
+			return nil
+		}
+
+		var canceled *types.TransactionCanceledException
+		if !errors.As(err, &canceled) {
+			return err
+		}
+
+		failed := map[int]struct{}{}
+		for i, reason := range canceled.CancellationReasons {
+			if i < len(owner) && reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+				failed[owner[i]] = struct{}{}
+			}
+		}
+		if len(failed) == 0 {
+			if !isThrottled(err) {
+				return err
+			}
+
+			// concurrent transaction holds the entry, the write is retried
+			backoff := time.Duration(1<<attempt) * time.Millisecond
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			continue
+		}
+
+		for i := range failed {
+			p, err := t.overflowOf(ctx, seq[i])
+			if err != nil {
+				return err
+			}
+			plan[i] = p
+		}
+	}
+
+	return fmt.Errorf("dynamo write is not resolved after %d attempts", maxAttempts)
+}
+
+// putItems writes values of the entry. Without the plan, values are added
+// to the entry if it has no continuation items and it has room for values
+// or the value is already known (single value writes only), the size of
+// values is added to the size of item. With the plan, values are added to
+// the planned item if it is not changed since it has been read, the exact
+// size of item is recorded. The number of last continuation is recorded by
+// the entry if a new continuation item is created.
+func (t *table) putItems(w Writer, plan *overflow) []types.TransactWriteItem {
+	attr, vals := w.SetOf()
+	sk := w.SortKey()
+
+	if plan == nil {
+		cond := "attribute_not_exists(#a) OR #z <= :n"
+		values := map[string]types.AttributeValue{
+			":v": &types.AttributeValueMemberSS{Value: vals},
+			":z": &types.AttributeValueMemberN{Value: strconv.Itoa(sizeOf(vals))},
+			":n": &types.AttributeValueMemberN{Value: strconv.Itoa(t.size - sizeOf(vals))},
+		}
+		if len(vals) == 1 {
+			cond += " OR contains(#a, :x)"
+			values[":x"] = &types.AttributeValueMemberS{Value: vals[0]}
+		}
+
+		return []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName:                 aws.String(t.name),
+					Key:                       t.key(w.HashKey(), sk),
+					UpdateExpression:          aws.String("ADD #a :v, #z :z"),
+					ConditionExpression:       aws.String("(" + cond + ") AND attribute_not_exists(#t)"),
+					ExpressionAttributeNames:  map[string]string{"#a": attr, "#t": tailAttr, "#z": sizeAttr},
+					ExpressionAttributeValues: values,
+				},
+			},
+		}
+	}
+
+	if len(plan.vals) == 0 {
+		return nil
+	}
+
+	cond := "size(#a) = :n"
+	if plan.count == 0 {
+		cond = "attribute_not_exists(#a)"
+	}
+
+	seq := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName:                aws.String(t.name),
+				Key:                      t.key(w.HashKey(), continuationOf(sk, plan.slot)),
+				UpdateExpression:         aws.String("ADD #a :v SET #z = :z"),
+				ConditionExpression:      aws.String(cond),
+				ExpressionAttributeNames: map[string]string{"#a": attr, "#z": sizeAttr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":v": &types.AttributeValueMemberSS{Value: plan.vals},
+					":z": &types.AttributeValueMemberN{Value: strconv.Itoa(plan.size)},
+				},
+			},
+		},
+	}
+	if plan.count > 0 {
+		seq[0].Update.ExpressionAttributeValues[":n"] = &types.AttributeValueMemberN{Value: strconv.Itoa(plan.count)}
+	}
+
+	if plan.slot > plan.tail {
+		seq = append(seq, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                aws.String(t.name),
+				Key:                      t.key(w.HashKey(), sk),
+				UpdateExpression:         aws.String("SET #t = :t"),
+				ConditionExpression:      aws.String("attribute_not_exists(#t) OR #t < :t"),
+				ExpressionAttributeNames: map[string]string{"#t": tailAttr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":t": &types.AttributeValueMemberN{Value: strconv.Itoa(plan.slot)},
+				},
+			},
+		})
+	}
+
+	return seq
+}
+
+// cutOverflow removes values from continuation items of entries, the
+// emptied items are deleted.
+func (t *table) cutOverflow(ctx context.Context, seq []Writer) error {
+	for _, w := range seq {
+		slots, _, err := t.slots(ctx, w)
+		if err != nil {
+			return err
+		}
+
+		attr, vals := w.SetOf()
+		for _, s := range slots {
+			if s.n == 0 {
+				continue
+			}
+
+			sk := continuationOf(w.SortKey(), s.n)
+			_, err := t.service.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                aws.String(t.name),
+				Key:                      t.key(w.HashKey(), sk),
+				UpdateExpression:         aws.String("DELETE #a :v"),
+				ConditionExpression:      aws.String("attribute_exists(#a)"),
+				ExpressionAttributeNames: map[string]string{"#a": attr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":v": &types.AttributeValueMemberSS{Value: vals},
+				},
+			})
+			if err != nil {
+				var empty *types.ConditionalCheckFailedException
+				if !errors.As(err, &empty) {
+					return err
+				}
+			}
+
+			if err := t.prune(ctx, []Writer{&entry{hash: w.HashKey(), sort: sk, attr: attr}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define layout of partition keys. By default, each index
+// permutation of the graph is a single partition (e.g. `sp|graph`). The
+// sharded layout spreads items of the graph over N partitions by hash of
+// the leading component of permutation (e.g. `sp|graph|7`). Queries that
+// match the leading component address single partition, prefix queries
+// fan-out to all partitions of the graph.
+//
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v2"
+	"github.com/fogfish/dynamo/v2/service/ddb"
+	"github.com/kshard/spock"
+)
+
+// separator of shard number in the partition key. It is not legal in IRIs,
+// so that sharded partitions never collide with other graphs.
+const shard = "|"
+
+// layout of partition keys
+type layout struct {
+	shards int
+}
+
+// Config() makes layout configurable by dynamo.Option
+func (*layout) Config() {}
+
+// WithShards configures sharded layout of partition keys, the graph is
+// spread over n partitions per index permutation. Stores of different
+// layout are not compatible, use Migrate to move the graph.
+func WithShards(n int) dynamo.Option {
+	return func(c interface{ Config() }) {
+		if l, ok := c.(*layout); ok {
+			l.shards = n
+		}
+	}
+}
+
+func newLayout(opts []dynamo.Option) layout {
+	l := layout{}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	return l
+}
+
+// partition of the graph for the leading component
+func (l layout) partition(graph curie.IRI, leading string) curie.IRI {
+	if l.shards <= 1 {
+		return graph
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(leading))
+	return graph + shard + curie.IRI(strconv.Itoa(int(h.Sum32()%uint32(l.shards))))
+}
+
+// partitions of the graph
+func (l layout) partitions(graph curie.IRI) []curie.IRI {
+	if l.shards <= 1 {
+		return []curie.IRI{graph}
+	}
+
+	seq := make([]curie.IRI, l.shards)
+	for i := 0; i < l.shards; i++ {
+		seq[i] = graph + shard + curie.IRI(strconv.Itoa(i))
+	}
+	return seq
+}
+
+// partitions of the graph to query, the leading component is either
+// matched or the query fan-out to all partitions.
+func (l layout) query(graph curie.IRI, hint spock.Hint, leading string) []curie.IRI {
+	if hint == spock.HINT_MATCH {
+		return []curie.IRI{l.partition(graph, leading)}
+	}
+	return l.partitions(graph)
+}
+
+// writers of statement to six index permutations, in order of permutations
+func (store *Store) writers(graph curie.IRI, spock spock.SPOCK) []Writer {
+	s := store.layout.partition(graph, spock.S.String())
+	p := store.layout.partition(graph, spock.P.String())
+	o := store.layout.partition(graph, encodeValue(spock.O))
+
+	return []Writer{
+		encodeSPO(s, spock),
+		encodeSOP(s, spock),
+		encodePOS(p, spock),
+		encodePSO(p, spock),
+		encodeOSP(o, spock),
+		encodeOPS(o, spock),
+	}
+}
+
+// index permutation that is addressable by partition of graph
+type partitioned[T any] interface {
+	dynamo.Thing
+	inGraph(curie.IRI) T
+}
+
+// fanOut iterates the key over partitions one after another
+func fanOut[T partitioned[T]](storage *ddb.Storage[T], key T, partitions []curie.IRI, m *matcher) (*Chain[T], error) {
+	hash := make([]curie.IRI, len(partitions))
+	for i, g := range partitions {
+		hash[i] = key.inGraph(g).HashKey()
+	}
+
+	at, pos, err := m.start(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &Chain[T]{hash: hash[at:], at: at}
+	for i, g := range partitions[at:] {
+		var cursor dynamo.MatchOpt = none("")
+		unfold := &Unfold[T]{}
+
+		if i == 0 {
+			// resume after last consumed entry of the partition
+			unfold.last, unfold.skip = pos.S, pos.N
+			if pos.S != "" {
+				cursor = dynamo.Cursor(entryKey{hash: pos.H, sort: pos.S})
+			}
+		}
+
+		unfold.seq = newIterator(storage, key.inGraph(g), m.pageSize, cursor)
+		chain.seq = append(chain.seq, unfold)
+	}
+
+	return chain, nil
+}
+
+// key of the entry, it is used as exclusive start key of the query
+type entryKey struct{ hash, sort curie.IRI }
+
+func (k entryKey) HashKey() curie.IRI { return k.hash }
+func (k entryKey) SortKey() curie.IRI { return k.sort }
+
+// Chain of streams over partitions of the graph
+type Chain[T dynamo.Thing] struct {
+	seq  []*Unfold[T]
+	hash []curie.IRI
+	at   int
+}
+
+func (chain *Chain[T]) Head() spock.SPOCK {
+	return chain.seq[0].Head()
+}
+
+func (chain *Chain[T]) Next() bool {
+	for len(chain.seq) > 0 {
+		if chain.seq[0].Next() {
+			return true
+		}
+		if chain.seq[0].Err() != nil {
+			// the failed partition is kept, the cursor resumes it
+			return false
+		}
+		chain.seq, chain.hash, chain.at = chain.seq[1:], chain.hash[1:], chain.at+1
+	}
+	return false
+}
+
+func (chain *Chain[T]) FMap(f func(spock.SPOCK) error) error {
+	for chain.Next() {
+		if err := f(chain.Head()); err != nil {
+			chain.Close()
+			return err
+		}
+	}
+	return chain.Err()
+}
+
+// Err returns the error that terminated the stream
+func (chain *Chain[T]) Err() error {
+	if len(chain.seq) == 0 {
+		return nil
+	}
+	return chain.seq[0].Err()
+}
+
+// Cursor is continuation token of the stream
+func (chain *Chain[T]) Cursor() string {
+	if len(chain.seq) == 0 {
+		return ""
+	}
+
+	s, n := chain.seq[0].position()
+	return position{P: chain.at, H: chain.hash[0], S: s, N: n}.String()
+}
+
+// Close discards all streams of the chain
+func (chain *Chain[T]) Close() error {
+	for _, seq := range chain.seq {
+		if err := seq.Close(); err != nil {
+			return err
+		}
+	}
+	chain.seq, chain.hash = nil, nil
+	return nil
+}
+
+// entries applies f to entries of all partitions of the index permutation
+func entries[T interface {
+	partitioned[T]
+	Writer
+}](ctx context.Context, storage *ddb.Storage[T], key T, partitions []curie.IRI, f func(Writer) error) error {
+	for _, g := range partitions {
+		var cursor dynamo.MatchOpt = none("")
+
+		for cursor != nil {
+			page, next, err := storage.Match(ctx, key.inGraph(g), cursor)
+			if err != nil {
+				return err
+			}
+			cursor = next
+
+			for _, entry := range page {
+				if err := f(entry); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define range queries over sort keys. Range predicates over
+// objects (LT, GT, IN) are translated into `BETWEEN` key condition and
+// evaluated by DynamoDB as a single query.
+//
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+// keyRange is the range of sort keys [lo, hi] within the partition
+type keyRange struct {
+	hash   curie.IRI
+	lo, hi string
+}
+
+// rangeIV builds range of sort keys encoded with encodeIV, the range
+// is inclusive, the exclusive bounds of LT and GT are filtered by stream.
+func rangeIV(hash, a curie.IRI, q *spock.Predicate[xsd.Value]) keyRange {
+	switch q.Clause {
+	case spock.LT:
+		return keyRange{hash: hash, lo: string(a) + "|" + encodeValueMin(q.Value), hi: encodeIV(a, q.Value)}
+	case spock.GT:
+		return keyRange{hash: hash, lo: encodeIV(a, q.Value), hi: string(a) + "|" + encodeValueMax(q.Value)}
+	default:
+		// upper bound includes continuation items of the entry
+		return keyRange{hash: hash, lo: encodeIV(a, q.Value), hi: encodeIV(a, q.Other) + continuation + "\x7f"}
+	}
+}
+
+// NewRangeIterator creates iterator over range of sort keys
+func NewRangeIterator[T dynamo.Thing](ctx context.Context, t *table, r keyRange) Seq[T] {
+	return newRangeIterator[T](ctx, t, r, 100, "")
+}
+
+func newRangeIterator[T dynamo.Thing](ctx context.Context, t *table, r keyRange, limit int32, after curie.IRI) *RangeIterator[T] {
+	iter := &RangeIterator[T]{ctx: ctx, table: t, keys: r, limit: limit}
+	if after != "" {
+		iter.cursor = t.key(r.hash, after)
+	}
+	if r.lo > r.hi {
+		// the range is empty, DynamoDB rejects BETWEEN with lo > hi
+		iter.done = true
+	}
+	return iter
+}
+
+// rangeOf streams range of sort keys, the range is single partition
+func rangeOf[T dynamo.Thing](ctx context.Context, t *table, r keyRange, m *matcher) (*Chain[T], error) {
+	_, pos, err := m.start([]curie.IRI{r.hash})
+	if err != nil {
+		return nil, err
+	}
+
+	unfold := &Unfold[T]{
+		seq:  newRangeIterator[T](ctx, t, r, m.pageSize, pos.S),
+		last: pos.S,
+		skip: pos.N,
+	}
+
+	return &Chain[T]{seq: []*Unfold[T]{unfold}, hash: []curie.IRI{r.hash}}, nil
+}
+
+// RangeIterator queries range of sort keys page by page
+type RangeIterator[T dynamo.Thing] struct {
+	ctx    context.Context
+	table  *table
+	keys   keyRange
+	limit  int32
+	cursor map[string]types.AttributeValue
+	done   bool
+	seq    []T
+	err    error
+}
+
+func (iter *RangeIterator[T]) Head() T {
+	return iter.seq[0]
+}
+
+func (iter *RangeIterator[T]) Next() bool {
+	if len(iter.seq) > 1 {
+		iter.seq = iter.seq[1:]
+		return true
+	}
+
+	for !iter.done {
+		if err := iter.query(); err != nil {
+			iter.seq, iter.cursor, iter.done, iter.err = nil, nil, true, err
+			return false
+		}
+
+		if len(iter.seq) > 0 {
+			return true
+		}
+	}
+
+	iter.seq = nil
+	return false
+}
+
+func (iter *RangeIterator[T]) query() error {
+	val, err := iter.table.service.Query(iter.ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(iter.table.name),
+		IndexName:              iter.table.index,
+		KeyConditionExpression: aws.String("#p = :p AND #s BETWEEN :lo AND :hi"),
+		ExpressionAttributeNames: map[string]string{
+			"#p": iter.table.prefix,
+			"#s": iter.table.suffix,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":p":  &types.AttributeValueMemberS{Value: string(iter.keys.hash)},
+			":lo": &types.AttributeValueMemberS{Value: iter.keys.lo},
+			":hi": &types.AttributeValueMemberS{Value: iter.keys.hi},
+		},
+		ExclusiveStartKey: iter.cursor,
+		Limit:             aws.Int32(iter.limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	iter.seq = make([]T, len(val.Items))
+	for i, item := range val.Items {
+		if err := attributevalue.UnmarshalMap(item, &iter.seq[i]); err != nil {
+			return err
+		}
+	}
+
+	iter.cursor = val.LastEvaluatedKey
+	iter.done = len(val.LastEvaluatedKey) == 0
+	return nil
+}
+
+// Err returns the error that terminated the sequence
+func (iter *RangeIterator[T]) Err() error {
+	return iter.err
+}
+
+// Close discards the cursor and buffered page, the sequence is exhausted afterwards
+func (iter *RangeIterator[T]) Close() error {
+	iter.seq = nil
+	iter.cursor = nil
+	iter.done = true
+	return nil
+}
@@ -21,16 +21,17 @@
 package dynamo
 
 import (
-	"context"
-
 	"github.com/fogfish/curie"
-	"github.com/fogfish/dynamo/v2/service/ddb"
 	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
 )
 
+// Writer is an entry of permutation index, the entry is a set of values
+// stored under the composite key. Entries are written by transaction.
 type Writer interface {
-	Put(ctx context.Context, store *Store) error
-	Cut(ctx context.Context, store *Store) error
+	HashKey() curie.IRI
+	SortKey() curie.IRI
+	SetOf() (string, []string)
 }
 
 //
@@ -43,32 +44,16 @@ type spo struct {
 	O  []string  `dynamodbav:"o,stringset"`
 }
 
-func (spo spo) HashKey() curie.IRI     { return spo.G }
-func (spo spo) SortKey() curie.IRI     { return curie.IRI(spo.SP) }
-func (spo spo) ToSPOCK() []spock.SPOCK { return decodeSPO(spo) }
-
-func (spo spo) Put(ctx context.Context, store *Store) error {
-	_, err := store.spo.UpdateWith(ctx,
-		ddb.Updater(spo, _spo.Union(spo.O)),
-	)
-	return err
-}
-
-func (spo spo) Cut(ctx context.Context, store *Store) error {
-	_, err := store.spo.UpdateWith(ctx,
-		ddb.Updater(spo, _spo.Minus(spo.O)),
-	)
-	return err
-}
-
-var (
-	_spo = ddb.UpdateFor[spo, []string]()
-)
+func (spo spo) HashKey() curie.IRI        { return spo.G }
+func (spo spo) SortKey() curie.IRI        { return curie.IRI(spo.SP) }
+func (spo spo) ToSPOCK() []spock.SPOCK    { return decodeSPO(spo) }
+func (spo spo) inGraph(g curie.IRI) spo   { spo.G = "sp|" + g; return spo }
+func (spo spo) SetOf() (string, []string) { return "o", spo.O }
 
 func encodeSPO(g curie.IRI, spock spock.SPOCK) spo {
 	return spo{
 		G:  "sp|" + g,
-		SP: encodeII(spock.S, spock.P),
+		SP: encodeII(iriOf(spock.S), iriOf(spock.P)),
 		O:  []string{encodeValue(spock.O)},
 	}
 }
@@ -78,7 +63,7 @@ func decodeSPO(spo spo) []spock.SPOCK {
 	s, p := decodeII(spo.SP)
 
 	for i, o := range spo.O {
-		seq[i].S, seq[i].P, seq[i].O = s, p, decodeValue(o)
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), decodeValue(o)
 	}
 
 	return seq
@@ -98,29 +83,20 @@ func (sop sop) HashKey() curie.IRI     { return sop.G }
 func (sop sop) SortKey() curie.IRI     { return curie.IRI(sop.SO) }
 func (sop sop) ToSPOCK() []spock.SPOCK { return decodeSOP(sop) }
 
-func (sop sop) Put(ctx context.Context, store *Store) error {
-	_, err := store.sop.UpdateWith(ctx,
-		ddb.Updater(sop, _sop.Union(sop.P)),
-	)
-	return err
-}
-
-func (sop sop) Cut(ctx context.Context, store *Store) error {
-	_, err := store.sop.UpdateWith(ctx,
-		ddb.Updater(sop, _sop.Minus(sop.P)),
-	)
-	return err
+func (sop sop) inGraph(g curie.IRI) sop { sop.G = "so|" + g; return sop }
+func (sop sop) SetOf() (string, []string) {
+	seq := make([]string, len(sop.P))
+	for i, x := range sop.P {
+		seq[i] = string(x)
+	}
+	return "p", seq
 }
 
-var (
-	_sop = ddb.UpdateFor[sop, []curie.IRI]()
-)
-
 func encodeSOP(g curie.IRI, spock spock.SPOCK) sop {
 	return sop{
 		G:  "so|" + g,
-		SO: encodeIV(spock.S, spock.O),
-		P:  []curie.IRI{spock.P},
+		SO: encodeIV(iriOf(spock.S), spock.O),
+		P:  []curie.IRI{iriOf(spock.P)},
 	}
 }
 
@@ -129,7 +105,7 @@ func decodeSOP(sop sop) []spock.SPOCK {
 	s, o := decodeIV(sop.SO)
 
 	for i, p := range sop.P {
-		seq[i].S, seq[i].P, seq[i].O = s, p, o
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), o
 	}
 
 	return seq
@@ -149,29 +125,20 @@ func (pos pos) HashKey() curie.IRI     { return pos.G }
 func (pos pos) SortKey() curie.IRI     { return curie.IRI(pos.PO) }
 func (pos pos) ToSPOCK() []spock.SPOCK { return decodePOS(pos) }
 
-func (pos pos) Put(ctx context.Context, store *Store) error {
-	_, err := store.pos.UpdateWith(ctx,
-		ddb.Updater(pos, _pos.Union(pos.S)),
-	)
-	return err
-}
-
-func (pos pos) Cut(ctx context.Context, store *Store) error {
-	_, err := store.pos.UpdateWith(ctx,
-		ddb.Updater(pos, _pos.Minus(pos.S)),
-	)
-	return err
+func (pos pos) inGraph(g curie.IRI) pos { pos.G = "po|" + g; return pos }
+func (pos pos) SetOf() (string, []string) {
+	seq := make([]string, len(pos.S))
+	for i, x := range pos.S {
+		seq[i] = string(x)
+	}
+	return "s", seq
 }
 
-var (
-	_pos = ddb.UpdateFor[pos, []curie.IRI]()
-)
-
 func encodePOS(g curie.IRI, spock spock.SPOCK) pos {
 	return pos{
 		G:  "po|" + g,
-		PO: encodeIV(spock.P, spock.O),
-		S:  []curie.IRI{spock.S},
+		PO: encodeIV(iriOf(spock.P), spock.O),
+		S:  []curie.IRI{iriOf(spock.S)},
 	}
 }
 
@@ -180,7 +147,7 @@ func decodePOS(pos pos) []spock.SPOCK {
 	p, o := decodeIV(pos.PO)
 
 	for i, s := range pos.S {
-		seq[i].S, seq[i].P, seq[i].O = s, p, o
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), o
 	}
 
 	return seq
@@ -196,32 +163,16 @@ type pso struct {
 	O  []string  `dynamodbav:"o,stringset"`
 }
 
-func (pso pso) HashKey() curie.IRI     { return pso.G }
-func (pso pso) SortKey() curie.IRI     { return curie.IRI(pso.PS) }
-func (pso pso) ToSPOCK() []spock.SPOCK { return decodePSO(pso) }
-
-func (pso pso) Put(ctx context.Context, store *Store) error {
-	_, err := store.pso.UpdateWith(ctx,
-		ddb.Updater(pso, _pso.Union(pso.O)),
-	)
-	return err
-}
-
-func (pso pso) Cut(ctx context.Context, store *Store) error {
-	_, err := store.pso.UpdateWith(ctx,
-		ddb.Updater(pso, _pso.Minus(pso.O)),
-	)
-	return err
-}
-
-var (
-	_pso = ddb.UpdateFor[pso, []string]()
-)
+func (pso pso) HashKey() curie.IRI        { return pso.G }
+func (pso pso) SortKey() curie.IRI        { return curie.IRI(pso.PS) }
+func (pso pso) ToSPOCK() []spock.SPOCK    { return decodePSO(pso) }
+func (pso pso) inGraph(g curie.IRI) pso   { pso.G = "ps|" + g; return pso }
+func (pso pso) SetOf() (string, []string) { return "o", pso.O }
 
 func encodePSO(g curie.IRI, spock spock.SPOCK) pso {
 	return pso{
 		G:  "ps|" + g,
-		PS: encodeII(spock.P, spock.S),
+		PS: encodeII(iriOf(spock.P), iriOf(spock.S)),
 		O:  []string{encodeValue(spock.O)},
 	}
 }
@@ -231,7 +182,7 @@ func decodePSO(pso pso) []spock.SPOCK {
 	p, s := decodeII(pso.PS)
 
 	for i, o := range pso.O {
-		seq[i].S, seq[i].P, seq[i].O = s, p, decodeValue(o)
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), decodeValue(o)
 	}
 
 	return seq
@@ -251,29 +202,20 @@ func (osp osp) HashKey() curie.IRI     { return osp.G }
 func (osp osp) SortKey() curie.IRI     { return curie.IRI(osp.OS) }
 func (osp osp) ToSPOCK() []spock.SPOCK { return decodeOSP(osp) }
 
-func (osp osp) Put(ctx context.Context, store *Store) error {
-	_, err := store.osp.UpdateWith(ctx,
-		ddb.Updater(osp, _osp.Union(osp.P)),
-	)
-	return err
-}
-
-func (osp osp) Cut(ctx context.Context, store *Store) error {
-	_, err := store.osp.UpdateWith(ctx,
-		ddb.Updater(osp, _osp.Minus(osp.P)),
-	)
-	return err
+func (osp osp) inGraph(g curie.IRI) osp { osp.G = "os|" + g; return osp }
+func (osp osp) SetOf() (string, []string) {
+	seq := make([]string, len(osp.P))
+	for i, x := range osp.P {
+		seq[i] = string(x)
+	}
+	return "p", seq
 }
 
-var (
-	_osp = ddb.UpdateFor[osp, []curie.IRI]()
-)
-
 func encodeOSP(g curie.IRI, spock spock.SPOCK) osp {
 	return osp{
 		G:  "os|" + g,
-		OS: encodeVI(spock.O, spock.S),
-		P:  []curie.IRI{spock.P},
+		OS: encodeVI(spock.O, iriOf(spock.S)),
+		P:  []curie.IRI{iriOf(spock.P)},
 	}
 }
 
@@ -282,7 +224,7 @@ func decodeOSP(osp osp) []spock.SPOCK {
 	o, s := decodeVI(osp.OS)
 
 	for i, p := range osp.P {
-		seq[i].S, seq[i].P, seq[i].O = s, p, o
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), o
 	}
 
 	return seq
@@ -302,29 +244,20 @@ func (ops ops) HashKey() curie.IRI     { return ops.G }
 func (ops ops) SortKey() curie.IRI     { return curie.IRI(ops.OP) }
 func (ops ops) ToSPOCK() []spock.SPOCK { return decodeOPS(ops) }
 
-func (ops ops) Put(ctx context.Context, store *Store) error {
-	_, err := store.ops.UpdateWith(ctx,
-		ddb.Updater(ops, _ops.Union(ops.S)),
-	)
-	return err
-}
-
-func (ops ops) Cut(ctx context.Context, store *Store) error {
-	_, err := store.ops.UpdateWith(ctx,
-		ddb.Updater(ops, _ops.Minus(ops.S)),
-	)
-	return err
+func (ops ops) inGraph(g curie.IRI) ops { ops.G = "op|" + g; return ops }
+func (ops ops) SetOf() (string, []string) {
+	seq := make([]string, len(ops.S))
+	for i, x := range ops.S {
+		seq[i] = string(x)
+	}
+	return "s", seq
 }
 
-var (
-	_ops = ddb.UpdateFor[ops, []curie.IRI]()
-)
-
 func encodeOPS(g curie.IRI, spock spock.SPOCK) ops {
 	return ops{
 		G:  "op|" + g,
-		OP: encodeVI(spock.O, spock.P),
-		S:  []curie.IRI{spock.S},
+		OP: encodeVI(spock.O, iriOf(spock.P)),
+		S:  []curie.IRI{iriOf(spock.S)},
 	}
 }
 
@@ -333,7 +266,7 @@ func decodeOPS(ops ops) []spock.SPOCK {
 	o, p := decodeVI(ops.OP)
 
 	for i, s := range ops.S {
-		seq[i].S, seq[i].P, seq[i].O = s, p, o
+		seq[i].S, seq[i].P, seq[i].O = xsd.ToAnyURI(s), xsd.ToAnyURI(p), o
 	}
 
 	return seq
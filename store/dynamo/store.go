@@ -31,15 +31,25 @@ import (
 )
 
 type Store struct {
-	spo *ddb.Storage[spo]
-	sop *ddb.Storage[sop]
-	pso *ddb.Storage[pso]
-	pos *ddb.Storage[pos]
-	osp *ddb.Storage[osp]
-	ops *ddb.Storage[ops]
+	table  *table
+	layout layout
+	spo    *ddb.Storage[spo]
+	sop    *ddb.Storage[sop]
+	pso    *ddb.Storage[pso]
+	pos    *ddb.Storage[pos]
+	osp    *ddb.Storage[osp]
+	ops    *ddb.Storage[ops]
 }
 
 func New(connector string, opts ...dynamo.Option) (*Store, error) {
+	table, err := newTable(connector, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// storages share the service with range queries
+	opts = append(opts, dynamo.WithService(table.service))
+
 	spo, err := ddb.New[spo](connector, opts...)
 	if err != nil {
 		return nil, err
@@ -71,15 +81,19 @@ func New(connector string, opts ...dynamo.Option) (*Store, error) {
 	}
 
 	return &Store{
-		spo: spo,
-		sop: sop,
-		pso: pso,
-		pos: pos,
-		osp: osp,
-		ops: ops,
+		table:  table,
+		layout: newLayout(opts),
+		spo:    spo,
+		sop:    sop,
+		pso:    pso,
+		pos:    pos,
+		osp:    osp,
+		ops:    ops,
 	}, nil
 }
 
+// Add statements into the graph, each statement is written atomically.
+// It returns statements that are not written on failure.
 func Add(ctx context.Context, store *Store, graph curie.IRI, bag spock.Bag) (spock.Bag, error) {
 	for i, spock := range bag {
 		if err := Put(ctx, store, graph, spock); err != nil {
@@ -90,44 +104,100 @@ func Add(ctx context.Context, store *Store, graph curie.IRI, bag spock.Bag) (spo
 	return nil, nil
 }
 
+// Put statement into the graph. The statement is written into six index
+// permutations by single transaction, it is visible either in all of them
+// or in none.
 func Put(ctx context.Context, store *Store, graph curie.IRI, spock spock.SPOCK) error {
-	seq := []Writer{
-		encodeSPO(graph, spock),
-		encodeSOP(graph, spock),
-		encodePOS(graph, spock),
-		encodePSO(graph, spock),
-		encodeOPS(graph, spock),
-		encodeOSP(graph, spock),
-	}
-
-	for i := 0; i < len(seq); i++ {
-		if err := seq[i].Put(ctx, store); err != nil {
-			for k := 0; k < i; k++ {
-				if err := seq[k].Cut(ctx, store); err != nil {
-					// TODO: log error
-				}
-			}
-			return err
+	if err := checkStatement(spock); err != nil {
+		return err
+	}
+
+	return store.table.transact(ctx, "ADD", graph, store.writers(graph, spock))
+}
+
+// Remove statements from the graph, each statement is removed atomically.
+// It returns statements that are not removed on failure.
+func Remove(ctx context.Context, store *Store, graph curie.IRI, bag spock.Bag) (spock.Bag, error) {
+	for i, spock := range bag {
+		if err := Cut(ctx, store, graph, spock); err != nil {
+			return bag[i:], err
 		}
 	}
 
-	return nil
+	return nil, nil
+}
+
+// Cut statement from the graph. The statement is removed from six index
+// permutations by single transaction, entries that become empty are
+// deleted afterwards.
+func Cut(ctx context.Context, store *Store, graph curie.IRI, spock spock.SPOCK) error {
+	if err := checkStatement(spock); err != nil {
+		return err
+	}
+
+	seq := store.writers(graph, spock)
+
+	if err := store.table.transact(ctx, "DELETE", graph, seq); err != nil {
+		return err
+	}
+
+	if err := store.table.cutOverflow(ctx, seq); err != nil {
+		return err
+	}
+
+	return store.table.prune(ctx, seq)
+}
+
+// RemoveSubject removes all statements of the subject from the graph.
+// Statements that refer the subject as object are not removed.
+func RemoveSubject(ctx context.Context, store *Store, graph curie.IRI, s curie.IRI) error {
+	stream, err := Match(ctx, store, graph, spock.Query(spock.IRI.Eq(s), nil, nil))
+	if err != nil {
+		return err
+	}
+
+	bag := spock.Bag{}
+	if err := stream.FMap(bag.Join); err != nil {
+		return err
+	}
+
+	_, err = Remove(ctx, store, graph, bag)
+	return err
 }
 
-func Match(ctx context.Context, store *Store, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
+// Match statements of the graph to the pattern. The stream is paged, each
+// page is fetched by single request (see WithPageSize). The stream is
+// resumable from continuation token (see WithCursor).
+func Match(ctx context.Context, store *Store, graph curie.IRI, q spock.Pattern, opts ...MatchOption) (Stream, error) {
+	m, err := newMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.O != nil {
+		if err := checkValue(q.O.Value); err != nil {
+			return nil, err
+		}
+		if q.O.Clause == spock.IN {
+			if err := checkValue(q.O.Other); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	switch q.Strategy {
 	case spock.STRATEGY_SPO:
-		return store.streamSPO(ctx, graph, q)
+		return store.streamSPO(ctx, graph, q, m)
 	case spock.STRATEGY_SOP:
-		return store.streamSOP(ctx, graph, q)
+		return store.streamSOP(ctx, graph, q, m)
 	case spock.STRATEGY_PSO:
-		return store.streamPSO(ctx, graph, q)
+		return store.streamPSO(ctx, graph, q, m)
 	case spock.STRATEGY_POS:
-		return store.streamPOS(ctx, graph, q)
+		return store.streamPOS(ctx, graph, q, m)
 	case spock.STRATEGY_OSP:
-		return store.streamOSP(ctx, graph, q)
+		return store.streamOSP(ctx, graph, q, m)
 	case spock.STRATEGY_OPS:
-		return store.streamOPS(ctx, graph, q)
+		return store.streamOPS(ctx, graph, q, m)
 	default:
 		panic(fmt.Errorf("unknown strategy"))
 	}
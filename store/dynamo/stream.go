@@ -33,164 +33,218 @@ type notSupported struct{ spock.Pattern }
 func (err notSupported) Error() string { return fmt.Sprintf("not supported %s", err.Pattern.Dump()) }
 func (notSupported) NotSupported()     {}
 
-func (store *Store) streamSPO(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := spo{G: "sp|" + graph}
+func (store *Store) streamSPO(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := spo{}
 
 	switch {
 	case q.HintForS == spock.HINT_MATCH && q.HintForP == spock.HINT_NONE:
-		key.SP = encodeII(q.S.Value, "")
+		key.SP = encodeII(iriOf(q.S.Value), "")
 	case q.HintForS == spock.HINT_MATCH && q.HintForP == spock.HINT_MATCH:
-		key.SP = encodeII(q.S.Value, q.P.Value)
+		key.SP = encodeII(iriOf(q.S.Value), iriOf(q.P.Value))
 	case q.HintForS == spock.HINT_MATCH && q.HintForP == spock.HINT_FILTER_PREFIX:
-		key.SP = encodeII(q.S.Value, q.P.Value)
+		key.SP = encodeII(iriOf(q.S.Value), iriOf(q.P.Value))
 	case q.HintForS == spock.HINT_FILTER_PREFIX && q.HintForP == spock.HINT_NONE:
-		key.SP = encodeI(q.S.Value)
+		key.SP = encodeI(iriOf(q.S.Value))
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[spo]{
-		seq: NewIterator(store.spo, key),
+	chain, err := fanOut(store.spo, key, store.layout.query(graph, q.HintForS, q.S.Value.String()), m)
+	if err != nil {
+		return nil, err
 	}
 
+	var stream spock.Stream = chain
+
 	if q.O != nil {
 		stream = spock.NewFilterO(q.HintForO, q.O, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
 
-func (store *Store) streamSOP(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := sop{G: "so|" + graph}
+func (store *Store) streamSOP(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := sop{}
 
 	switch {
 	case q.HintForS == spock.HINT_MATCH && q.HintForO == spock.HINT_NONE:
-		key.SO = encodeII(q.S.Value, "")
+		key.SO = encodeII(iriOf(q.S.Value), "")
 	case q.HintForS == spock.HINT_MATCH && q.HintForO == spock.HINT_MATCH:
-		key.SO = encodeIV(q.S.Value, q.O.Value)
+		key.SO = encodeIV(iriOf(q.S.Value), q.O.Value)
 	case q.HintForS == spock.HINT_MATCH && q.HintForO == spock.HINT_FILTER_PREFIX:
-		key.SO = encodeIV(q.S.Value, q.O.Value)
+		key.SO = encodeIV(iriOf(q.S.Value), q.O.Value)
 	case q.HintForS == spock.HINT_FILTER_PREFIX && q.HintForO == spock.HINT_NONE:
-		key.SO = encodeI(q.S.Value)
+		key.SO = encodeI(iriOf(q.S.Value))
+	case q.HintForS == spock.HINT_MATCH && q.HintForO == spock.HINT_FILTER:
+		return store.rangeSOP(ctx, "so|"+store.layout.partition(graph, q.S.Value.String()), q, m)
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[sop]{
-		seq: NewIterator(store.sop, key),
+	chain, err := fanOut(store.sop, key, store.layout.query(graph, q.HintForS, q.S.Value.String()), m)
+	if err != nil {
+		return nil, err
 	}
 
+	var stream spock.Stream = chain
+
 	if q.P != nil {
 		stream = spock.NewFilterP(q.HintForP, q.P, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
 
-func (store *Store) streamPSO(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := pso{G: "ps|" + graph}
+func (store *Store) streamPSO(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := pso{}
 
 	switch {
 	case q.HintForP == spock.HINT_MATCH && q.HintForS == spock.HINT_NONE:
-		key.PS = encodeII(q.P.Value, "")
+		key.PS = encodeII(iriOf(q.P.Value), "")
 	case q.HintForP == spock.HINT_MATCH && q.HintForS == spock.HINT_MATCH:
-		key.PS = encodeII(q.P.Value, q.S.Value)
+		key.PS = encodeII(iriOf(q.P.Value), iriOf(q.S.Value))
 	case q.HintForP == spock.HINT_MATCH && q.HintForS == spock.HINT_FILTER_PREFIX:
-		key.PS = encodeII(q.P.Value, q.S.Value)
+		key.PS = encodeII(iriOf(q.P.Value), iriOf(q.S.Value))
 	case q.HintForP == spock.HINT_FILTER_PREFIX && q.HintForS == spock.HINT_NONE:
-		key.PS = encodeI(q.P.Value)
+		key.PS = encodeI(iriOf(q.P.Value))
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[pso]{
-		seq: NewIterator(store.pso, key),
+	chain, err := fanOut(store.pso, key, store.layout.query(graph, q.HintForP, q.P.Value.String()), m)
+	if err != nil {
+		return nil, err
 	}
 
+	var stream spock.Stream = chain
+
 	if q.O != nil {
 		stream = spock.NewFilterO(q.HintForO, q.O, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
 
-func (store *Store) streamPOS(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := pos{G: "po|" + graph}
+func (store *Store) streamPOS(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := pos{}
 
 	switch {
 	case q.HintForP == spock.HINT_MATCH && q.HintForO == spock.HINT_NONE:
-		key.PO = encodeII(q.P.Value, "")
+		key.PO = encodeII(iriOf(q.P.Value), "")
 	case q.HintForP == spock.HINT_MATCH && q.HintForO == spock.HINT_MATCH:
-		key.PO = encodeIV(q.P.Value, q.O.Value)
+		key.PO = encodeIV(iriOf(q.P.Value), q.O.Value)
 	case q.HintForP == spock.HINT_MATCH && q.HintForO == spock.HINT_FILTER_PREFIX:
-		key.PO = encodeIV(q.P.Value, q.O.Value)
+		key.PO = encodeIV(iriOf(q.P.Value), q.O.Value)
 	case q.HintForP == spock.HINT_FILTER_PREFIX && q.HintForO == spock.HINT_NONE:
-		key.PO = encodeI(q.P.Value)
+		key.PO = encodeI(iriOf(q.P.Value))
+	case q.HintForP == spock.HINT_MATCH && q.HintForO == spock.HINT_FILTER:
+		return store.rangePOS(ctx, "po|"+store.layout.partition(graph, q.P.Value.String()), q, m)
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[pos]{
-		seq: NewIterator(store.pos, key),
+	chain, err := fanOut(store.pos, key, store.layout.query(graph, q.HintForP, q.P.Value.String()), m)
+	if err != nil {
+		return nil, err
 	}
 
+	var stream spock.Stream = chain
+
 	if q.S != nil {
 		stream = spock.NewFilterS(q.HintForS, q.S, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
 
-func (store *Store) streamOSP(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := osp{G: "os|" + graph}
+func (store *Store) streamOSP(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := osp{}
 
 	switch {
 	case q.HintForO == spock.HINT_MATCH && q.HintForS == spock.HINT_NONE:
 		key.OS = encodeVI(q.O.Value, "")
 	case q.HintForO == spock.HINT_MATCH && q.HintForS == spock.HINT_MATCH:
-		key.OS = encodeVI(q.O.Value, q.S.Value)
+		key.OS = encodeVI(q.O.Value, iriOf(q.S.Value))
 	case q.HintForO == spock.HINT_MATCH && q.HintForS == spock.HINT_FILTER_PREFIX:
-		key.OS = encodeVI(q.O.Value, q.S.Value)
+		key.OS = encodeVI(q.O.Value, iriOf(q.S.Value))
 	case q.HintForO == spock.HINT_FILTER_PREFIX && q.HintForS == spock.HINT_NONE:
 		key.OS = encodeValue(q.O.Value)
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[osp]{
-		seq: NewIterator(store.osp, key),
+	chain, err := fanOut(store.osp, key, store.layout.query(graph, q.HintForO, encodeValue(q.O.Value)), m)
+	if err != nil {
+		return nil, err
 	}
 
+	var stream spock.Stream = chain
+
 	if q.P != nil {
 		stream = spock.NewFilterP(q.HintForP, q.P, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
 
-func (store *Store) streamOPS(ctx context.Context, graph curie.IRI, q spock.Pattern) (spock.Stream, error) {
-	key := ops{G: "op|" + graph}
+func (store *Store) streamOPS(ctx context.Context, graph curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	key := ops{}
 
 	switch {
 	case q.HintForO == spock.HINT_MATCH && q.HintForP == spock.HINT_NONE:
 		key.OP = encodeVI(q.O.Value, "")
 	case q.HintForO == spock.HINT_MATCH && q.HintForP == spock.HINT_MATCH:
-		key.OP = encodeVI(q.O.Value, q.P.Value)
+		key.OP = encodeVI(q.O.Value, iriOf(q.P.Value))
 	case q.HintForO == spock.HINT_MATCH && q.HintForP == spock.HINT_FILTER_PREFIX:
-		key.OP = encodeVI(q.O.Value, q.P.Value)
+		key.OP = encodeVI(q.O.Value, iriOf(q.P.Value))
 	case q.HintForO == spock.HINT_FILTER_PREFIX && q.HintForP == spock.HINT_NONE:
 		key.OP = encodeValue(q.O.Value)
 	default:
 		return nil, &notSupported{q}
 	}
 
-	var stream spock.Stream = &Unfold[ops]{
-		seq: NewIterator(store.ops, key),
+	chain, err := fanOut(store.ops, key, store.layout.query(graph, q.HintForO, encodeValue(q.O.Value)), m)
+	if err != nil {
+		return nil, err
+	}
+
+	var stream spock.Stream = chain
+
+	if q.S != nil {
+		stream = spock.NewFilterS(q.HintForS, q.S, stream)
+	}
+
+	return resumable(chain, stream), nil
+}
+
+// (s)º ⇒ p, the range of objects is pushed down to sort key
+func (store *Store) rangeSOP(ctx context.Context, hash curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	chain, err := rangeOf[sop](ctx, store.table, rangeIV(hash, iriOf(q.S.Value), q.O), m)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := spock.NewFilterO(q.HintForO, q.O, chain)
+
+	if q.P != nil {
+		stream = spock.NewFilterP(q.HintForP, q.P, stream)
+	}
+
+	return resumable(chain, stream), nil
+}
+
+// (p)º ⇒ s, the range of objects is pushed down to sort key
+func (store *Store) rangePOS(ctx context.Context, hash curie.IRI, q spock.Pattern, m *matcher) (Stream, error) {
+	chain, err := rangeOf[pos](ctx, store.table, rangeIV(hash, iriOf(q.P.Value), q.O), m)
+	if err != nil {
+		return nil, err
 	}
 
+	stream := spock.NewFilterO(q.HintForO, q.O, chain)
+
 	if q.S != nil {
 		stream = spock.NewFilterS(q.HintForS, q.S, stream)
 	}
 
-	return stream, nil
+	return resumable(chain, stream), nil
 }
@@ -0,0 +1,185 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package dynamo
+
+//
+// The file define DynamoDB table used by store directly, bypassing storage
+// abstraction for range queries and transactional writes.
+//
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/fogfish/curie"
+	"github.com/fogfish/dynamo/v2"
+	"github.com/fogfish/dynamo/v2/service/ddb"
+)
+
+// DynamoDB declares interface of AWS DynamoDB API used by the store.
+// The service given with dynamo.WithService option must implement it.
+type DynamoDB interface {
+	ddb.DynamoDB
+	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// table is DynamoDB table used by store
+type table struct {
+	service DynamoDB
+	name    string
+	index   *string
+	prefix  string
+	suffix  string
+
+	// limit of values in bytes stored by single item
+	size int
+
+	// reports size of overflowed entries
+	hook func(SetStat)
+
+	// graphs registered by the process
+	graphs sync.Map
+}
+
+// Config() makes table configurable by dynamo.Option
+func (*table) Config() {}
+
+func newTable(connector string, opts []dynamo.Option) (*table, error) {
+	uri, err := url.Parse(connector)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := strings.Split(strings.Trim(uri.Path, "/"), "/")
+	if seq[0] == "" {
+		return nil, fmt.Errorf("invalid connector %s", connector)
+	}
+
+	t := &table{
+		name:   seq[0],
+		prefix: "prefix",
+		suffix: "suffix",
+		size:   defaultSetSize,
+	}
+	if len(seq) > 1 {
+		t.index = &seq[1]
+	}
+	if prefix := uri.Query().Get("prefix"); prefix != "" {
+		t.prefix = prefix
+	}
+	if suffix := uri.Query().Get("suffix"); suffix != "" {
+		t.suffix = suffix
+	}
+
+	conf := dynamo.NewConfig()
+	for _, opt := range opts {
+		opt(&conf)
+		opt(t)
+	}
+
+	if conf.Service != nil {
+		service, ok := conf.Service.(DynamoDB)
+		if !ok {
+			return nil, fmt.Errorf("dynamo service %T do not support transactions", conf.Service)
+		}
+		t.service = service
+		return t, nil
+	}
+
+	aws, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	t.service = dynamodb.NewFromConfig(aws)
+
+	return t, nil
+}
+
+// transact applies update action (ADD or DELETE) to sets of all entries
+// of the graph by single transaction. Values overflow to continuation items
+// on ADD, the graph is registered before the transaction.
+func (t *table) transact(ctx context.Context, action string, graph curie.IRI, seq []Writer) error {
+	if action == "ADD" {
+		if err := t.register(ctx, graph); err != nil {
+			return err
+		}
+		return t.put(ctx, graph, seq)
+	}
+
+	items := make([]types.TransactWriteItem, len(seq))
+	for i, w := range seq {
+		attr, vals := w.SetOf()
+		items[i] = types.TransactWriteItem{
+			Update: &types.Update{
+				TableName:                aws.String(t.name),
+				Key:                      t.key(w.HashKey(), w.SortKey()),
+				UpdateExpression:         aws.String(action + " #a :v"),
+				ExpressionAttributeNames: map[string]string{"#a": attr},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":v": &types.AttributeValueMemberSS{Value: vals},
+				},
+			},
+		}
+	}
+
+	_, err := t.service.TransactWriteItems(ctx,
+		&dynamodb.TransactWriteItemsInput{TransactItems: items},
+	)
+	return err
+}
+
+// key of item
+func (t *table) key(hash, sort curie.IRI) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		t.prefix: &types.AttributeValueMemberS{Value: string(hash)},
+		t.suffix: &types.AttributeValueMemberS{Value: string(sort)},
+	}
+}
+
+// prune deletes entries with empty set, the overflowed entry is kept
+func (t *table) prune(ctx context.Context, seq []Writer) error {
+	for _, w := range seq {
+		attr, _ := w.SetOf()
+		_, err := t.service.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:                aws.String(t.name),
+			Key:                      t.key(w.HashKey(), w.SortKey()),
+			ConditionExpression:      aws.String("attribute_not_exists(#a) AND attribute_not_exists(#t)"),
+			ExpressionAttributeNames: map[string]string{"#a": attr, "#t": tailAttr},
+		})
+		if err != nil {
+			var notEmpty *types.ConditionalCheckFailedException
+			if errors.As(err, &notEmpty) {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
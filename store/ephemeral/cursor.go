@@ -0,0 +1,171 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package ephemeral
+
+//
+// The file define continuation tokens of queries. The token is number of
+// statements consumed from the stream, the query skips them on resume.
+// The token is opaque for clients, it is serialised as base64url of JSON.
+//
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kshard/spock"
+)
+
+// Stream of statements that is resumable by continuation token. Streams
+// returned by Match implement it. Cursor returns token of the position
+// after the last consumed statement, the token is empty when the stream
+// is exhausted.
+type Stream interface {
+	spock.Stream
+	Cursor() string
+}
+
+// MatchOption configures Match
+type MatchOption func(*matcher)
+
+type matcher struct {
+	token string
+}
+
+// WithCursor resumes the query from continuation token, which is obtained
+// from the stream of same query.
+func WithCursor(token string) MatchOption {
+	return func(m *matcher) {
+		m.token = token
+	}
+}
+
+// invalid continuation token
+type invalidCursor struct {
+	token string
+	err   error
+}
+
+func (err *invalidCursor) Error() string {
+	if err.err != nil {
+		return fmt.Sprintf("invalid cursor %q: %s", err.token, err.err)
+	}
+	return fmt.Sprintf("invalid cursor %q", err.token)
+}
+
+func (err *invalidCursor) Unwrap() error { return err.err }
+func (*invalidCursor) InvalidCursor()    {}
+
+// position of the stream
+type position struct {
+	N int `json:"n,omitempty"`
+}
+
+func (pos position) String() string {
+	b, _ := json.Marshal(pos)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePosition(token string) (position, error) {
+	var pos position
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pos, &invalidCursor{token: token, err: err}
+	}
+
+	if err := json.Unmarshal(b, &pos); err != nil {
+		return pos, &invalidCursor{token: token, err: err}
+	}
+
+	if pos.N < 0 {
+		return pos, &invalidCursor{token: token}
+	}
+
+	return pos, nil
+}
+
+// resumable stream counts consumed statements
+type resumable struct {
+	stream spock.Stream
+	n      int
+	done   bool
+}
+
+func resume(stream spock.Stream, opts []MatchOption) (*resumable, error) {
+	m := &matcher{}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	r := &resumable{stream: stream}
+	if m.token == "" {
+		return r, nil
+	}
+
+	pos, err := decodePosition(m.token)
+	if err != nil {
+		return nil, err
+	}
+
+	for r.n < pos.N && r.Next() {
+	}
+
+	return r, nil
+}
+
+func (r *resumable) Head() spock.SPOCK {
+	return r.stream.Head()
+}
+
+func (r *resumable) Next() bool {
+	if r.done || !r.stream.Next() {
+		r.done = true
+		return false
+	}
+
+	r.n++
+	return true
+}
+
+func (r *resumable) FMap(f func(spock.SPOCK) error) error {
+	for r.Next() {
+		if err := f(r.Head()); err != nil {
+			r.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// Cursor is continuation token of the stream
+func (r *resumable) Cursor() string {
+	if r.done {
+		return ""
+	}
+	return position{N: r.n}.String()
+}
+
+// Close releases the stream, it is exhausted afterwards
+func (r *resumable) Close() error {
+	r.done = true
+	return spock.Close(r.stream)
+}
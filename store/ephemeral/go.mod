@@ -10,3 +10,5 @@ require (
 	github.com/kshard/spock v0.1.0
 	github.com/kshard/xsd v0.1.0
 )
+
+replace github.com/kshard/spock => ../..
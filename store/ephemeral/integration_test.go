@@ -1134,3 +1134,148 @@ func TestSocialGraph(t *testing.T) {
 	})
 
 }
+
+func TestStreamClose(t *testing.T) {
+	rds := setup(datasetSocialGraph())
+
+	t.Run("Close", func(t *testing.T) {
+		seq, err := ephemeral.Match(rds, spock.Query(spock.IRI.Equal(C), nil, nil))
+		it.Then(t).Should(
+			it.Nil(err),
+			it.True(seq.Next()),
+			it.Nil(spock.Close(seq)),
+			it.True(!seq.Next()),
+		)
+	})
+
+	t.Run("CloseFilter", func(t *testing.T) {
+		seq, err := ephemeral.Match(rds, spock.Query(spock.IRI.Equal(C), nil, nil))
+		it.Then(t).Should(it.Nil(err))
+
+		seq = spock.NewFilter(func(spock.SPOCK) bool { return true }, seq)
+		it.Then(t).Should(
+			it.True(seq.Next()),
+			it.Nil(spock.Close(seq)),
+			it.True(!seq.Next()),
+		)
+	})
+
+	t.Run("CloseOnError", func(t *testing.T) {
+		seq, err := ephemeral.Match(rds, spock.Query(spock.IRI.Equal(C), nil, nil))
+		it.Then(t).Should(it.Nil(err))
+
+		err = seq.FMap(func(spock.SPOCK) error { return fmt.Errorf("fail") })
+		it.Then(t).ShouldNot(
+			it.Nil(err),
+		).Should(
+			it.True(!seq.Next()),
+		)
+	})
+}
+
+func TestCursor(t *testing.T) {
+	rds := setup(datasetSocialGraph())
+
+	// reads n statements from the query, n < 0 reads all of them
+	Page := func(t *testing.T, req spock.Pattern, n int, opts ...ephemeral.MatchOption) (spock.Bag, string) {
+		t.Helper()
+		bag := spock.Bag{}
+		seq, err := ephemeral.Match(rds, req, opts...)
+		it.Then(t).Should(it.Nil(err))
+
+		for n != 0 && seq.Next() {
+			bag = append(bag, seq.Head())
+			n--
+		}
+		return bag, seq.(ephemeral.Stream).Cursor()
+	}
+
+	t.Run("Resume", func(t *testing.T) {
+		req := spock.Query(nil, spock.IRI.Equal("follows"), nil)
+		all, cursor := Page(t, req, -1)
+		it.Then(t).Should(it.Equal(cursor, ""))
+
+		bag := spock.Bag{}
+		for {
+			page, next := Page(t, req, 2, ephemeral.WithCursor(cursor))
+			bag = append(bag, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		it.Then(t).Should(
+			it.Equal(len(bag), len(all)),
+			it.Seq(bag).Equal(all...),
+		)
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		var err interface{ InvalidCursor() }
+
+		it.Then(t).Should(
+			it.Error(
+				ephemeral.Match(rds, spock.Query(spock.IRI.Equal(C), nil, nil), ephemeral.WithCursor("@")),
+			).With(&err),
+		)
+	})
+}
+
+func TestTypedRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	rds := setup(spock.Bag{
+		spock.From(A, "age", 9),
+		spock.From(B, "age", 10),
+		spock.From(C, "age", 100),
+		spock.From(D, "age", "90"),
+		spock.From(A, "born", day(9)),
+		spock.From(B, "born", day(10)),
+	})
+
+	Seq := func(t *testing.T, req spock.Pattern) it.SeqOf[spock.SPOCK] {
+		t.Helper()
+		bag := spock.Bag{}
+		seq, err := ephemeral.Match(rds, req)
+		it.Then(t).Should(it.Nil(err))
+		it.Then(t).Should(it.Nil(seq.FMap(bag.Join)))
+
+		return it.Seq(bag)
+	}
+
+	t.Run("Gt", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Equal("age"), spock.Gt(9))).Equal(
+				spock.From(B, "age", 10),
+				spock.From(C, "age", 100),
+			),
+		)
+	})
+
+	t.Run("Lt", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Equal("age"), spock.Lt(100))).Equal(
+				spock.From(A, "age", 9),
+				spock.From(B, "age", 10),
+			),
+		)
+	})
+
+	t.Run("In", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Equal("age"), spock.In(10, 1000))).Equal(
+				spock.From(B, "age", 10),
+				spock.From(C, "age", 100),
+			),
+		)
+	})
+
+	t.Run("DateTime", func(t *testing.T) {
+		it.Then(t).Should(
+			Seq(t, spock.Query(nil, spock.IRI.Equal("born"), spock.Gt(day(9)))).Equal(
+				spock.From(B, "born", day(10)),
+			),
+		)
+	})
+}
@@ -91,8 +91,17 @@ func (iter *iterator[A, B, C]) Next() bool {
 func (iter *iterator[A, B, C]) FMap(f func(spock.SPOCK) error) error {
 	for iter.Next() {
 		if err := f(iter.Head()); err != nil {
+			iter.Close()
 			return err
 		}
 	}
 	return nil
 }
+
+// Close releases references to indexes, the stream is exhausted afterwards
+func (iter *iterator[A, B, C]) Close() error {
+	iter.abc = nil
+	iter._bc = nil
+	iter.__c = nil
+	return nil
+}
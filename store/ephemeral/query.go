@@ -92,7 +92,12 @@ func queryXSD[A, B any](
 		if after == nil {
 			return nil
 		}
-		return NewTakeWhileType[B](pred.Value.XSDType(), after).(Seq[A, B])
+		// the split is inclusive, the value itself is not greater than value
+		gt := NewDropWhile[xsd.Value, B](
+			func(x xsd.Value) bool { return spock.Compare(x, pred.Value) == 0 },
+			after,
+		)
+		return NewTakeWhileType[B](pred.Value.XSDType(), gt).(Seq[A, B])
 	}
 
 	if seq == nil {
@@ -151,6 +156,32 @@ func (seq *takeWhile[A, B]) Next() bool {
 	return true
 }
 
+type dropWhile[A, B any] struct {
+	Seq[A, B]
+	f func(A) bool
+}
+
+func NewDropWhile[A, B any](f func(A) bool, seq Seq[A, B]) Seq[A, B] {
+	return &dropWhile[A, B]{Seq: seq, f: f}
+}
+
+func (seq *dropWhile[A, B]) Next() bool {
+	for {
+		if !seq.Seq.Next() {
+			return false
+		}
+
+		if seq.f == nil {
+			return true
+		}
+
+		if key, _ := seq.Seq.Head(); !seq.f(key) {
+			seq.f = nil
+			return true
+		}
+	}
+}
+
 // take sequence elements while xsd.Value belongs to same category (type)
 type takeWhileType[T any] struct {
 	Seq[xsd.Value, T]
@@ -160,7 +160,9 @@ func putS(store *Store, _os _os, _ps _ps, spock spock.SPOCK) {
 	skiplist.Put(__s, spock.S, struct{}{}) // spock.K)
 }
 
-func Match(store *Store, q spock.Pattern) (spock.Stream, error) {
+// Match statements of the store to the pattern. The stream implements
+// Stream, it is resumable from continuation token (see WithCursor).
+func Match(store *Store, q spock.Pattern, opts ...MatchOption) (spock.Stream, error) {
 	if q.HintForS != spock.HINT_MATCH && q.HintForS != spock.HINT_NONE {
 		return nil, &notSupported{q}
 	}
@@ -173,6 +175,15 @@ func Match(store *Store, q spock.Pattern) (spock.Stream, error) {
 		return nil, &notSupported{q}
 	}
 
+	stream, err := store.stream(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return resume(stream, opts)
+}
+
+func (store *Store) stream(q spock.Pattern) (spock.Stream, error) {
 	switch q.Strategy {
 	case spock.STRATEGY_SPO:
 		return store.streamSPO(q)
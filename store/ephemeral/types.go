@@ -24,6 +24,7 @@ import (
 	"math/rand"
 
 	"github.com/fogfish/skiplist"
+	"github.com/kshard/spock"
 	"github.com/kshard/xsd"
 )
 
@@ -58,12 +59,12 @@ type ops = *skiplist.SkipList[o, _ps]
 // allocators for indexes
 func newS(rnd rand.Source) __s { return skiplist.New[s, k](xsd.OrdAnyURI, rnd) }
 func newP(rnd rand.Source) __p { return skiplist.New[p, k](xsd.OrdAnyURI, rnd) }
-func newO(rnd rand.Source) __o { return skiplist.New[o, k](xsd.OrdValue, rnd) }
+func newO(rnd rand.Source) __o { return skiplist.New[o, k](spock.OrdValue, rnd) }
 
 func newPO(rnd rand.Source) _po { return skiplist.New[p, __o](xsd.OrdAnyURI, rnd) }
-func newOP(rnd rand.Source) _op { return skiplist.New[o, __p](xsd.OrdValue, rnd) }
+func newOP(rnd rand.Source) _op { return skiplist.New[o, __p](spock.OrdValue, rnd) }
 func newSO(rnd rand.Source) _so { return skiplist.New[s, __o](xsd.OrdAnyURI, rnd) }
-func newOS(rnd rand.Source) _os { return skiplist.New[o, __s](xsd.OrdValue, rnd) }
+func newOS(rnd rand.Source) _os { return skiplist.New[o, __s](spock.OrdValue, rnd) }
 func newSP(rnd rand.Source) _sp { return skiplist.New[s, __p](xsd.OrdAnyURI, rnd) }
 func newPS(rnd rand.Source) _ps { return skiplist.New[p, __s](xsd.OrdAnyURI, rnd) }
 
@@ -71,5 +72,5 @@ func newSPO(rnd rand.Source) spo { return skiplist.New[s, _po](xsd.OrdAnyURI, rn
 func newSOP(rnd rand.Source) sop { return skiplist.New[s, _op](xsd.OrdAnyURI, rnd) }
 func newPSO(rnd rand.Source) pso { return skiplist.New[p, _so](xsd.OrdAnyURI, rnd) }
 func newPOS(rnd rand.Source) pos { return skiplist.New[p, _os](xsd.OrdAnyURI, rnd) }
-func newOSP(rnd rand.Source) osp { return skiplist.New[o, _sp](xsd.OrdValue, rnd) }
-func newOPS(rnd rand.Source) ops { return skiplist.New[o, _ps](xsd.OrdValue, rnd) }
+func newOSP(rnd rand.Source) osp { return skiplist.New[o, _sp](spock.OrdValue, rnd) }
+func newOPS(rnd rand.Source) ops { return skiplist.New[o, _ps](spock.OrdValue, rnd) }
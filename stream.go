@@ -25,6 +25,9 @@ package spock
 //
 
 import (
+	"io"
+	"strings"
+
 	"github.com/kshard/xsd"
 )
 
@@ -35,6 +38,47 @@ type Stream interface {
 	FMap(func(SPOCK) error) error
 }
 
+// Close releases resources (cursors, in-flight requests, snapshots) held by
+// the stream. Stream optionally implements io.Closer, the function is no-op
+// for streams that do not hold any resources.
+func Close(stream Stream) error {
+	if closer, ok := stream.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stream returns stream of statements of the bag
+func (bag Bag) Stream() Stream {
+	return &unfold{seq: bag}
+}
+
+type unfold struct {
+	seq Bag
+	at  int
+}
+
+func (unfold *unfold) Head() SPOCK {
+	return unfold.seq[unfold.at-1]
+}
+
+func (unfold *unfold) Next() bool {
+	if unfold.at >= len(unfold.seq) {
+		return false
+	}
+	unfold.at++
+	return true
+}
+
+func (unfold *unfold) FMap(f func(SPOCK) error) error {
+	for unfold.Next() {
+		if err := f(unfold.Head()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type filter struct {
 	pred   func(SPOCK) bool
 	stream Stream
@@ -59,12 +103,17 @@ func (filter *filter) Next() bool {
 func (filter *filter) FMap(f func(SPOCK) error) error {
 	for filter.Next() {
 		if err := f(filter.Head()); err != nil {
+			filter.Close()
 			return err
 		}
 	}
 	return nil
 }
 
+func (filter *filter) Close() error {
+	return Close(filter.stream)
+}
+
 func NewFilter(pred func(SPOCK) bool, stream Stream) Stream {
 	return &filter{pred: pred, stream: stream}
 }
@@ -73,30 +122,30 @@ func NewFilterO(hint Hint, q *Predicate[xsd.Value], stream Stream) Stream {
 	switch hint {
 	case HINT_MATCH:
 		return NewFilter(
-			func(spock SPOCK) bool { return xsd.Compare(spock.O, q.Value) == 0 },
+			func(spock SPOCK) bool { return Compare(spock.O, q.Value) == 0 },
 			stream,
 		)
 	case HINT_FILTER_PREFIX:
 		return NewFilter(
-			func(spock SPOCK) bool { return xsd.HasPrefix(spock.O, q.Value) },
+			func(spock SPOCK) bool { return hasPrefix(spock.O, q.Value) },
 			stream,
 		)
 	case HINT_FILTER:
 		switch q.Clause {
 		case LT:
 			return NewFilter(
-				func(spock SPOCK) bool { return xsd.Compare(spock.O, q.Value) == -1 },
+				func(spock SPOCK) bool { return Compare(spock.O, q.Value) == -1 },
 				stream,
 			)
 		case GT:
 			return NewFilter(
-				func(spock SPOCK) bool { return xsd.Compare(spock.O, q.Value) == 1 },
+				func(spock SPOCK) bool { return Compare(spock.O, q.Value) == 1 },
 				stream,
 			)
 		case IN:
 			return NewFilter(
 				func(spock SPOCK) bool {
-					return xsd.Compare(spock.O, q.Value) >= 0 && xsd.Compare(spock.O, q.Other) <= 0
+					return Compare(spock.O, q.Value) >= 0 && Compare(spock.O, q.Other) <= 0
 				},
 				stream,
 			)
@@ -106,6 +155,16 @@ func NewFilterO(hint Hint, q *Predicate[xsd.Value], stream Stream) Stream {
 	return stream
 }
 
+// hasPrefix extends xsd.HasPrefix with prefix of IRI
+func hasPrefix(a, b xsd.Value) bool {
+	if av, ok := a.(xsd.AnyURI); ok {
+		bv, ok := b.(xsd.AnyURI)
+		return ok && strings.HasPrefix(av.String(), bv.String())
+	}
+
+	return xsd.HasPrefix(a, b)
+}
+
 func NewFilterP(hint Hint, q *Predicate[xsd.AnyURI], stream Stream) Stream {
 	switch hint {
 	case HINT_MATCH:
@@ -113,11 +172,11 @@ func NewFilterP(hint Hint, q *Predicate[xsd.AnyURI], stream Stream) Stream {
 			func(spock SPOCK) bool { return spock.P == q.Value },
 			stream,
 		)
-		// case HINT_FILTER_PREFIX:
-		// 	return NewFilter(
-		// 		func(spock SPOCK) bool { return strings.HasPrefix(string(spock.P), string(q.Value)) },
-		// 		stream,
-		// 	)
+	case HINT_FILTER_PREFIX:
+		return NewFilter(
+			func(spock SPOCK) bool { return strings.HasPrefix(spock.P.String(), q.Value.String()) },
+			stream,
+		)
 	}
 
 	return stream
@@ -130,11 +189,11 @@ func NewFilterS(hint Hint, q *Predicate[xsd.AnyURI], stream Stream) Stream {
 			func(spock SPOCK) bool { return spock.S == q.Value },
 			stream,
 		)
-		// case HINT_FILTER_PREFIX:
-		// 	return NewFilter(
-		// 		func(spock SPOCK) bool { return strings.HasPrefix(string(spock.S), string(q.Value)) },
-		// 		stream,
-		// 	)
+	case HINT_FILTER_PREFIX:
+		return NewFilter(
+			func(spock SPOCK) bool { return strings.HasPrefix(spock.S.String(), q.Value.String()) },
+			stream,
+		)
 	}
 
 	return stream
@@ -0,0 +1,150 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package spock_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+)
+
+// closer is a stream that records release of its resources
+type closer struct {
+	spock.Stream
+	closed int
+}
+
+func (s *closer) Next() bool {
+	return s.closed == 0 && s.Stream.Next()
+}
+
+func (s *closer) FMap(f func(spock.SPOCK) error) error {
+	for s.Next() {
+		if err := f(s.Head()); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *closer) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	bag := spock.Bag{
+		spock.From("a", "p", "1"),
+		spock.From("b", "p", "2"),
+		spock.From("c", "p", "3"),
+	}
+
+	t.Run("Closer", func(t *testing.T) {
+		s := &closer{Stream: bag.Stream()}
+
+		it.Then(t).Should(
+			it.Nil(spock.Close(s)),
+			it.Equal(s.closed, 1),
+		).ShouldNot(
+			it.True(s.Next()),
+		)
+	})
+
+	t.Run("NotCloser", func(t *testing.T) {
+		s := bag.Stream()
+
+		it.Then(t).Should(
+			it.Nil(spock.Close(s)),
+			it.True(s.Next()),
+		)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		s := &closer{Stream: bag.Stream()}
+		f := spock.NewFilter(func(spock.SPOCK) bool { return true }, s)
+
+		it.Then(t).Should(
+			it.Nil(spock.Close(f)),
+			it.Equal(s.closed, 1),
+		)
+	})
+
+	t.Run("FilterO", func(t *testing.T) {
+		s := &closer{Stream: bag.Stream()}
+		q := spock.Query(nil, nil, spock.Gt("1"))
+		f := spock.NewFilterO(q.HintForO, q.O, s)
+
+		it.Then(t).Should(
+			it.Nil(spock.Close(f)),
+			it.Equal(s.closed, 1),
+		)
+	})
+
+	t.Run("FMapOnError", func(t *testing.T) {
+		s := &closer{Stream: bag.Stream()}
+		f := spock.NewFilter(func(spock.SPOCK) bool { return true }, s)
+		fail := errors.New("fail")
+
+		n := 0
+		err := f.FMap(func(spock.SPOCK) error {
+			if n++; n == 2 {
+				return fail
+			}
+			return nil
+		})
+
+		it.Then(t).Should(
+			it.Equal(err, fail),
+			it.Equal(n, 2),
+			it.Equal(s.closed, 1),
+		)
+	})
+
+	t.Run("FMapCompleted", func(t *testing.T) {
+		s := &closer{Stream: bag.Stream()}
+		f := spock.NewFilter(func(x spock.SPOCK) bool { return x.S.String() != "b" }, s)
+
+		seq := spock.Bag{}
+		it.Then(t).Should(
+			it.Nil(f.FMap(seq.Join)),
+			it.Seq(seq).Equal(bag[0], bag[2]),
+			it.Equal(s.closed, 0),
+		)
+	})
+}
+
+func TestBagStream(t *testing.T) {
+	bag := spock.Bag{
+		spock.From("a", "p", "1"),
+		spock.From("b", "p", "2"),
+	}
+
+	seq := spock.Bag{}
+	it.Then(t).Should(
+		it.Nil(bag.Stream().FMap(seq.Join)),
+		it.Seq(seq).Equal(bag...),
+	).ShouldNot(
+		it.True(spock.Bag{}.Stream().Next()),
+	)
+}
@@ -0,0 +1,210 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package spock
+
+//
+// The file extends xsd package with numeric, boolean and date-time data types
+//
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/kshard/xsd"
+)
+
+// Symbols of data types defined by the package
+var (
+	XSD_INTEGER  = xsd.ToSymbol("xsd:integer")
+	XSD_DOUBLE   = xsd.ToSymbol("xsd:double")
+	XSD_BOOLEAN  = xsd.ToSymbol("xsd:boolean")
+	XSD_DATETIME = xsd.ToSymbol("xsd:dateTime")
+)
+
+// The integer data type, values are 64-bit signed integers.
+type Integer int64
+
+func (v Integer) XSDType() xsd.Symbol { return XSD_INTEGER }
+func (v Integer) String() string      { return strconv.FormatInt(int64(v), 10) }
+
+// The double data type, IEEE 754 double-precision floating point.
+type Double float64
+
+func (v Double) XSDType() xsd.Symbol { return XSD_DOUBLE }
+func (v Double) String() string {
+	switch {
+	case math.IsInf(float64(v), 1):
+		return "INF"
+	case math.IsInf(float64(v), -1):
+		return "-INF"
+	case math.IsNaN(float64(v)):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64)
+	}
+}
+
+// The boolean data type.
+type Boolean bool
+
+func (v Boolean) XSDType() xsd.Symbol { return XSD_BOOLEAN }
+func (v Boolean) String() string      { return strconv.FormatBool(bool(v)) }
+
+// The date and time data type. Values are instants of time, the time zone
+// is not preserved, values are written in UTC. Dates are instants of the
+// midnight UTC.
+type DateTime time.Time
+
+func (v DateTime) XSDType() xsd.Symbol { return XSD_DATETIME }
+func (v DateTime) String() string      { return time.Time(v).UTC().Format(time.RFC3339Nano) }
+
+// DataType is the type constraint of values accepted by the package. It
+// extends xsd.DataType with time.Time.
+type DataType interface {
+	xsd.DataType | time.Time
+}
+
+// ValueOf converts Go value to xsd value. Integers, floats, booleans and
+// time are converted to Integer, Double, Boolean and DateTime.
+func ValueOf[T DataType](value T) xsd.Value {
+	switch v := any(value).(type) {
+	case Integer, Double, Boolean, DateTime:
+		return v.(xsd.Value)
+	case int:
+		return Integer(v)
+	case int8:
+		return Integer(v)
+	case int16:
+		return Integer(v)
+	case int32:
+		return Integer(v)
+	case int64:
+		return Integer(v)
+	case uint:
+		return unsigned(uint64(v))
+	case uint8:
+		return Integer(v)
+	case uint16:
+		return Integer(v)
+	case uint32:
+		return Integer(v)
+	case uint64:
+		return unsigned(v)
+	case float32:
+		return Double(v)
+	case float64:
+		return Double(v)
+	case bool:
+		return Boolean(v)
+	case time.Time:
+		return DateTime(v.UTC())
+	case curie.IRI:
+		return xsd.ToAnyURI(v)
+	case xsd.AnyURI:
+		return v
+	case string:
+		return xsd.String(v)
+	case xsd.String:
+		return v
+	default:
+		panic(fmt.Errorf("package spock does not support %T", value))
+	}
+}
+
+// unsigned integers above math.MaxInt64 are not integers of the package
+func unsigned(v uint64) xsd.Value {
+	if v > math.MaxInt64 {
+		panic(fmt.Errorf("package spock does not support uint64 %d, it overflows Integer", v))
+	}
+	return Integer(v)
+}
+
+// Compare extends xsd.Compare with data types defined by the package.
+// Values of same type are compared by value, values of distinct types are
+// ordered by type: Boolean < Integer < Double < DateTime < xsd.String <
+// xsd.AnyURI.
+func Compare(a, b xsd.Value) int {
+	ra, rb := rankOf(a), rankOf(b)
+	if ra != rb {
+		return compare(ra, rb)
+	}
+
+	switch av := a.(type) {
+	case Integer:
+		return compare(av, b.(Integer))
+	case Double:
+		return compare(av, b.(Double))
+	case Boolean:
+		return compare(boolToInt(bool(av)), boolToInt(bool(b.(Boolean))))
+	case DateTime:
+		return time.Time(av).Compare(time.Time(b.(DateTime)))
+	default:
+		return xsd.Compare(a, b)
+	}
+}
+
+// rank of data type, see Compare
+func rankOf(x xsd.Value) int {
+	switch x.(type) {
+	case Boolean:
+		return 1
+	case Integer:
+		return 2
+	case Double:
+		return 3
+	case DateTime:
+		return 4
+	case xsd.String:
+		return 5
+	case xsd.AnyURI:
+		return 6
+	default:
+		panic(fmt.Errorf("package spock does not support %T", x))
+	}
+}
+
+func boolToInt(x bool) int {
+	if x {
+		return 1
+	}
+	return 0
+}
+
+func compare[T ~int | ~int64 | ~float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// OrdValue is the ordering of xsd values defined by Compare
+const OrdValue = ordValue("ord.spock.value")
+
+type ordValue string
+
+func (ordValue) Compare(a, b xsd.Value) int { return Compare(a, b) }
@@ -0,0 +1,65 @@
+/*
+
+  Knowledge Graph: SPOCK
+  Copyright (C) 2016 - 2023 Dmitry Kolesnikov
+
+  This program is free software: you can redistribute it and/or modify
+  it under the terms of the GNU Affero General Public License as published
+  by the Free Software Foundation, either version 3 of the License, or
+  (at your option) any later version.
+
+  This program is distributed in the hope that it will be useful,
+  but WITHOUT ANY WARRANTY; without even the implied warranty of
+  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+  GNU Affero General Public License for more details.
+
+  You should have received a copy of the GNU Affero General Public License
+  along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+*/
+
+package spock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fogfish/curie"
+	"github.com/fogfish/it/v2"
+	"github.com/kshard/spock"
+	"github.com/kshard/xsd"
+)
+
+func TestValueOf(t *testing.T) {
+	at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.FixedZone("EET", 7200))
+
+	it.Then(t).Should(
+		it.Equal[xsd.Value](spock.ValueOf(42), spock.Integer(42)),
+		it.Equal[xsd.Value](spock.ValueOf(uint8(42)), spock.Integer(42)),
+		it.Equal[xsd.Value](spock.ValueOf(0.5), spock.Double(0.5)),
+		it.Equal[xsd.Value](spock.ValueOf(true), spock.Boolean(true)),
+		it.Equal[xsd.Value](spock.ValueOf(at), spock.DateTime(at.UTC())),
+		it.Equal[xsd.Value](spock.ValueOf("a"), xsd.String("a")),
+		it.Equal[xsd.Value](spock.ValueOf(curie.IRI("a")), xsd.ToAnyURI("a")),
+	)
+}
+
+func TestCompare(t *testing.T) {
+	day := func(d int) spock.DateTime { return spock.DateTime(time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC)) }
+
+	for _, seq := range [][]xsd.Value{
+		{spock.Integer(-10), spock.Integer(9), spock.Integer(10)},
+		{spock.Double(-0.5), spock.Double(0.25), spock.Double(1e10)},
+		{spock.Boolean(false), spock.Boolean(true)},
+		{day(9), day(10), day(31)},
+		{spock.Boolean(true), spock.Integer(0), spock.Double(0), day(1), xsd.String(""), xsd.ToAnyURI("a")},
+	} {
+		for i := 1; i < len(seq); i++ {
+			it.Then(t).Should(
+				it.Equal(spock.Compare(seq[i-1], seq[i]), -1),
+				it.Equal(spock.Compare(seq[i], seq[i-1]), 1),
+				it.Equal(spock.Compare(seq[i], seq[i]), 0),
+			)
+		}
+	}
+}